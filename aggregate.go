@@ -0,0 +1,149 @@
+package ministry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Number of categories fetched concurrently for aggregated headlines
+const aggregateFetchWorkers = 4
+
+// Parse a comma-separated category list, dropping duplicates
+func parseAggregateCategories(raw string, allowed []string) ([]string, error) {
+	var categories []string
+	seen := make(map[string]bool)
+
+	for _, category := range strings.Split(raw, ",") {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if category == "" || seen[category] {
+			continue
+		}
+		if err := checkCategory(category, allowed); err != nil {
+			return nil, err
+		}
+		seen[category] = true
+		categories = append(categories, category)
+	}
+
+	if len(categories) == 0 {
+		return nil, fmt.Errorf("Query parameter 'categories' is required")
+	}
+	return categories, nil
+}
+
+// Fetch headlines for each category concurrently and merge them, tagging
+// every article with its category and dropping duplicate URLs. The result is
+// stale if any category was served stale. The first failed fetch cancels the rest.
+func aggregateHeadlines(ctx context.Context, categories []string, pageSize int, fetch func(ctx context.Context, query HeadlinesQuery) (*NewsResponse, error)) (*NewsResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]*NewsResponse, len(categories))
+	jobs := make(chan int)
+
+	var firstErr error
+	var errOnce sync.Once
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < aggregateFetchWorkers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result, err := fetch(ctx, HeadlinesQuery{Country: headlinesCountry, Category: categories[i], PageSize: pageSize})
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("category %s: %w", categories[i], err)
+						cancel()
+					})
+					continue
+				}
+				results[i] = result
+			}
+		}()
+	}
+
+	for i := range categories {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	merged := &NewsResponse{Status: "ok", Articles: []Article{}}
+	seen := make(map[string]bool)
+	for i, result := range results {
+		merged.Stale = merged.Stale || result.Stale
+		for _, article := range result.Articles {
+			if article.URL != "" && seen[article.URL] {
+				continue
+			}
+			seen[article.URL] = true
+			article.Category = categories[i]
+			merged.Articles = append(merged.Articles, article)
+		}
+	}
+	merged.TotalResults = len(merged.Articles)
+
+	return merged, nil
+}
+
+// Get top headlines across several categories at once
+func getAggregateHeadlines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	format, err := responseFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fields, err := parseArticleFields(r.URL.Query().Get("fields"), format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := parseArticleSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	categories, err := parseAggregateCategories(r.URL.Query().Get("categories"), currentConfig().AllowedCategories)
+	if err != nil {
+		http.Error(w, err.Error(), queryErrorStatus(err))
+		return
+	}
+
+	// Page size per category, DEFAULT_PAGE_SIZE unless ?pageSize= says otherwise
+	pageSize, err := pageSizeParam(r.URL.Query(), currentConfig())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	newsResponse, err := aggregateHeadlines(r.Context(), categories, pageSize, currentNewsProvider().TopHeadlines)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error aggregating headlines", "error", err)
+		http.Error(w, fmt.Sprintf("Error fetching news: %v", err), newsErrorStatus(err))
+		return
+	}
+	truncateArticles(newsResponse, currentConfig().MaxArticles)
+
+	// ?inferCategory=true tags each article with a keyword-based category
+	if infer, _ := strconv.ParseBool(r.URL.Query().Get("inferCategory")); infer {
+		currentConfig().Categories.Tag(newsResponse.Articles)
+	}
+
+	sortArticles(newsResponse.Articles, order)
+	writeNewsResponse(w, r, format, fields, newsResponse)
+}
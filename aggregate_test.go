@@ -0,0 +1,142 @@
+package ministry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseAggregateCategories(t *testing.T) {
+	got, err := parseAggregateCategories("Business, technology,,business", nil)
+	if err != nil || !slices.Equal(got, []string{"business", "technology"}) {
+		t.Errorf("parseAggregateCategories = %q, %v", got, err)
+	}
+	for _, raw := range []string{"", " , ", "business,gossip"} {
+		if _, err := parseAggregateCategories(raw, nil); err == nil {
+			t.Errorf("parseAggregateCategories(%q) succeeded, want an error", raw)
+		}
+	}
+}
+
+func TestAggregateHeadlinesFetchesConcurrently(t *testing.T) {
+	categories := []string{"business", "technology", "health"}
+
+	// Every fetch waits until all of them have started
+	var started sync.WaitGroup
+	started.Add(len(categories))
+	allStarted := make(chan struct{})
+	go func() { started.Wait(); close(allStarted) }()
+
+	fetch := func(ctx context.Context, query HeadlinesQuery) (*NewsResponse, error) {
+		started.Done()
+		select {
+		case <-allStarted:
+		case <-time.After(2 * time.Second):
+			return nil, errors.New("fetches ran one at a time")
+		}
+		articles := []Article{{Title: query.Category + " news", URL: "https://example.com/" + query.Category}}
+		if query.Category != "business" {
+			// Shared with business, so dropped as a duplicate
+			articles = append(articles, Article{Title: "shared", URL: "https://example.com/business"})
+		}
+		return &NewsResponse{Status: "ok", Articles: articles}, nil
+	}
+
+	merged, err := aggregateHeadlines(context.Background(), categories, 20, fetch)
+	if err != nil {
+		t.Fatalf("aggregateHeadlines: %v", err)
+	}
+	if merged.TotalResults != 3 || len(merged.Articles) != 3 {
+		t.Fatalf("merged = %+v, want 3 deduplicated articles", merged)
+	}
+	for i, category := range categories {
+		if article := merged.Articles[i]; article.Category != category || article.Title != category+" news" {
+			t.Errorf("article %d = %+v, want %s tagged %s", i, article, category+" news", category)
+		}
+	}
+}
+
+func TestAggregateHeadlinesCarriesStale(t *testing.T) {
+	fetch := func(ctx context.Context, query HeadlinesQuery) (*NewsResponse, error) {
+		return &NewsResponse{Status: "ok", Stale: query.Category == "health"}, nil
+	}
+
+	merged, err := aggregateHeadlines(context.Background(), []string{"business", "health"}, 20, fetch)
+	if err != nil || !merged.Stale {
+		t.Errorf("merged = %+v, %v; want stale when one category was", merged, err)
+	}
+}
+
+func TestAggregateHeadlinesCancelsOnError(t *testing.T) {
+	upstreamDown := errors.New("upstream down")
+	fetch := func(ctx context.Context, query HeadlinesQuery) (*NewsResponse, error) {
+		if query.Category == "business" {
+			return nil, upstreamDown
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(2 * time.Second):
+			t.Error("remaining fetches weren't cancelled")
+			return &NewsResponse{}, nil
+		}
+	}
+
+	_, err := aggregateHeadlines(context.Background(), []string{"business", "technology"}, 20, fetch)
+	if !errors.Is(err, upstreamDown) || err.Error() != "category business: upstream down" {
+		t.Errorf("error = %v, want the business failure", err)
+	}
+}
+
+func TestAggregateHeadlinesEndpoint(t *testing.T) {
+	news := newFakeNews(Article{Title: "Lift broken again", URL: "https://example.com/lift"})
+	setupTest(t, upstreams(nil, news))
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines/aggregate?categories=business,science", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var response NewsResponse
+	decodeJSON(t, rec, &response)
+	if len(response.Articles) != 1 {
+		t.Errorf("articles = %+v, want the shared article once", response.Articles)
+	}
+
+	var queried []string
+	for _, query := range news.Queries() {
+		queried = append(queried, query.Get("category"))
+	}
+	slices.Sort(queried)
+	if !slices.Equal(queried, []string{"business", "science"}) {
+		t.Errorf("NewsAPI categories = %q", queried)
+	}
+
+	rec = do(newRequest(http.MethodGet, "/api/news/headlines/aggregate", ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d without categories, want 400", rec.Code)
+	}
+}
+
+func TestAggregateHeadlinesPageSize(t *testing.T) {
+	news := newFakeNews(Article{Title: "Lift broken again", URL: "https://example.com/lift"})
+	setupTest(t, upstreams(nil, news), "DEFAULT_PAGE_SIZE", "7")
+
+	do(newRequest(http.MethodGet, "/api/news/headlines/aggregate?categories=business,science", ""))
+	do(newRequest(http.MethodGet, "/api/news/headlines/aggregate?categories=health&pageSize=3", ""))
+	var pageSizes []string
+	for _, query := range news.Queries() {
+		pageSizes = append(pageSizes, query.Get("category")+"="+query.Get("pageSize"))
+	}
+	slices.Sort(pageSizes)
+	if !slices.Equal(pageSizes, []string{"business=7", "health=3", "science=7"}) {
+		t.Errorf("NewsAPI page sizes = %q, want DEFAULT_PAGE_SIZE unless pageSize is given", pageSizes)
+	}
+
+	if rec := do(newRequest(http.MethodGet, "/api/news/headlines/aggregate?categories=health&pageSize=0", "")); rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d for pageSize=0, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,39 @@
+package ministry
+
+import "net/http"
+
+// Values the API accepts, for front-ends building filter controls
+type APIMetaResponse struct {
+	Categories  []string `json:"categories"`
+	Countries   []string `json:"countries"`
+	Languages   []string `json:"languages"`
+	SortBy      []string `json:"sortBy"`
+	Sort        []string `json:"sort"`
+	Personas    []string `json:"personas"`
+	Intensities []string `json:"intensities"`
+}
+
+// The accepted values, taken from the same lists the request validation
+// uses. Categories are narrowed to ALLOWED_CATEGORIES when it is set.
+func apiMeta(cfg *Config) APIMetaResponse {
+	categories := newsCategories
+	if len(cfg.AllowedCategories) > 0 {
+		categories = cfg.AllowedCategories
+	}
+	templates := currentPrompts()
+	return APIMetaResponse{
+		Categories:  categories,
+		Countries:   []string{headlinesCountry},
+		Languages:   searchLanguages,
+		SortBy:      searchSortOrders,
+		Sort:        articleSorts,
+		Personas:    templates.PersonaNames(),
+		Intensities: templates.IntensityNames(),
+	}
+}
+
+// List the categories, countries, languages, orderings, personas and
+// intensities requests may use
+func getAPIMeta(w http.ResponseWriter, r *http.Request) {
+	writeJSONWithETag(w, r, apiMeta(currentConfig()))
+}
@@ -0,0 +1,65 @@
+package ministry
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestAPIMetaMatchesValidation(t *testing.T) {
+	cfg := setupTest(t, nil)
+
+	rec := do(newRequest(http.MethodGet, "/api/meta", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var meta APIMetaResponse
+	decodeJSON(t, rec, &meta)
+
+	if !slices.Equal(meta.Categories, newsCategories) {
+		t.Errorf("categories = %v, want %v", meta.Categories, newsCategories)
+	}
+	for _, category := range meta.Categories {
+		if _, err := headlinesQuery(map[string][]string{"category": {category}}, cfg); err != nil {
+			t.Errorf("listed category %q rejected: %v", category, err)
+		}
+	}
+	if query, _ := headlinesQuery(nil, cfg); !slices.Equal(meta.Countries, []string{query.Country}) {
+		t.Errorf("countries = %v, want %q", meta.Countries, query.Country)
+	}
+	for _, language := range meta.Languages {
+		if _, err := searchRequestQuery(SearchRequest{Query: "ai", Language: language}, cfg); err != nil {
+			t.Errorf("listed language %q rejected: %v", language, err)
+		}
+	}
+	for _, sortBy := range meta.SortBy {
+		if _, err := searchRequestQuery(SearchRequest{Query: "ai", SortBy: sortBy}, cfg); err != nil {
+			t.Errorf("listed sortBy %q rejected: %v", sortBy, err)
+		}
+	}
+	for _, sort := range meta.Sort {
+		if _, err := parseArticleSort(sort); err != nil {
+			t.Errorf("listed sort %q rejected: %v", sort, err)
+		}
+	}
+	if len(meta.Personas) == 0 || len(meta.Intensities) == 0 {
+		t.Fatalf("personas = %v, intensities = %v", meta.Personas, meta.Intensities)
+	}
+	for _, persona := range meta.Personas {
+		for _, intensity := range meta.Intensities {
+			if _, err := resolveTransformOptions(intensity, persona); err != nil {
+				t.Errorf("listed persona %q with intensity %q rejected: %v", persona, intensity, err)
+			}
+		}
+	}
+}
+
+func TestAPIMetaAllowedCategories(t *testing.T) {
+	setupTest(t, nil, "ALLOWED_CATEGORIES", "science,technology")
+
+	var meta APIMetaResponse
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/meta", "")), &meta)
+	if !slices.Equal(meta.Categories, []string{"science", "technology"}) {
+		t.Errorf("categories = %v, want only the allowed ones", meta.Categories)
+	}
+}
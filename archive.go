@@ -0,0 +1,194 @@
+package ministry
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// A transformed article saved to the archive
+type ArchiveRecord struct {
+	ID                  int64     `json:"id"`
+	RequestID           string    `json:"requestId"`
+	OriginalTitle       string    `json:"originalTitle"`
+	OriginalDescription string    `json:"originalDescription"`
+	TransformedContent  string    `json:"transformedContent"`
+	CreatedAt           time.Time `json:"createdAt"`
+}
+
+// Storage for archived transforms
+type Store interface {
+	Save(ctx context.Context, record ArchiveRecord) error
+	Recent(ctx context.Context, limit, offset int) ([]ArchiveRecord, error)
+}
+
+// Active archive, or nil when ARCHIVE_DB_PATH is not set
+var archive Store
+
+// Store the result of a transform; failures are logged, never returned
+func archiveTransform(ctx context.Context, title, description, transformed string) {
+	if archive == nil {
+		return
+	}
+
+	record := ArchiveRecord{
+		RequestID:           requestIDFromContext(ctx),
+		OriginalTitle:       title,
+		OriginalDescription: description,
+		TransformedContent:  transformed,
+		CreatedAt:           time.Now().UTC(),
+	}
+	if err := archive.Save(ctx, record); err != nil {
+		slog.WarnContext(ctx, "failed to archive transform", "error", err)
+	}
+}
+
+// In-memory Store, used in tests and when no database is configured
+type MemoryStore struct {
+	mu      sync.Mutex
+	records []ArchiveRecord
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Save(ctx context.Context, record ArchiveRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record.ID = int64(len(s.records) + 1)
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *MemoryStore) Recent(ctx context.Context, limit, offset int) ([]ArchiveRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := []ArchiveRecord{}
+	for i := len(s.records) - 1 - offset; i >= 0 && len(records) < limit; i-- {
+		records = append(records, s.records[i])
+	}
+	return records, nil
+}
+
+// SQLite-backed Store
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive database: %v", err)
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS transforms (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		request_id TEXT NOT NULL,
+		original_title TEXT NOT NULL,
+		original_description TEXT NOT NULL,
+		transformed_content TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create archive table: %v", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Save(ctx context.Context, record ArchiveRecord) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO transforms (request_id, original_title, original_description, transformed_content, created_at) VALUES (?, ?, ?, ?, ?)`,
+		record.RequestID, record.OriginalTitle, record.OriginalDescription, record.TransformedContent, record.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save archive record: %v", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Recent(ctx context.Context, limit, offset int) ([]ArchiveRecord, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, request_id, original_title, original_description, transformed_content, created_at FROM transforms ORDER BY id DESC LIMIT ? OFFSET ?`,
+		limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query archive: %v", err)
+	}
+	defer rows.Close()
+
+	records := []ArchiveRecord{}
+	for rows.Next() {
+		var record ArchiveRecord
+		if err := rows.Scan(&record.ID, &record.RequestID, &record.OriginalTitle, &record.OriginalDescription, &record.TransformedContent, &record.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read archive record: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+// Parse limit/offset query parameters for the history endpoint
+func parseHistoryPagination(query url.Values) (int, int, error) {
+	limit := defaultHistoryLimit
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxHistoryLimit {
+			return 0, 0, fmt.Errorf("Query parameter 'limit' must be between 1 and %d", maxHistoryLimit)
+		}
+		limit = parsed
+	}
+
+	offset := 0
+	if value := query.Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, fmt.Errorf("Query parameter 'offset' must be a non-negative integer")
+		}
+		offset = parsed
+	}
+
+	return limit, offset, nil
+}
+
+// Transform history endpoint, newest first
+func getHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, offset, err := parseHistoryPagination(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	records := []ArchiveRecord{}
+	if archive != nil {
+		if records, err = archive.Recent(r.Context(), limit, offset); err != nil {
+			slog.ErrorContext(r.Context(), "error reading history", "error", err)
+			http.Error(w, "Error reading history", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(records)
+}
@@ -0,0 +1,107 @@
+package ministry
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	store, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	for _, title := range []string{"first", "second", "third"} {
+		record := ArchiveRecord{RequestID: "req-" + title, OriginalTitle: title, TransformedContent: "approved " + title, CreatedAt: time.Now().UTC()}
+		if err := store.Save(ctx, record); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	records, err := store.Recent(ctx, 2, 0)
+	if err != nil {
+		t.Fatalf("Recent: %v", err)
+	}
+	if len(records) != 2 || records[0].OriginalTitle != "third" || records[1].OriginalTitle != "second" {
+		t.Fatalf("Recent(2, 0) = %+v, want the newest two", records)
+	}
+	if records[0].RequestID != "req-third" || records[0].TransformedContent != "approved third" || records[0].ID == 0 {
+		t.Errorf("record = %+v", records[0])
+	}
+
+	records, err = store.Recent(ctx, 10, 2)
+	if err != nil || len(records) != 1 || records[0].OriginalTitle != "first" {
+		t.Errorf("Recent(10, 2) = %+v, %v; want the oldest", records, err)
+	}
+}
+
+func TestTransformIsArchived(t *testing.T) {
+	setupTest(t, upstreams(newFakeOpenAI("Lift repaired ahead of schedule"), nil),
+		"ARCHIVE_DB_PATH", filepath.Join(t.TempDir(), "archive.db"))
+
+	rec := postTransform(`{"title": "Lift broken again", "description": "Third time this week"}`, "X-Request-ID", "archive-me")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	records, err := archive.Recent(context.Background(), 10, 0)
+	if err != nil {
+		t.Fatalf("Recent: %v", err)
+	}
+	want := ArchiveRecord{RequestID: "archive-me", OriginalTitle: "Lift broken again", OriginalDescription: "Third time this week", TransformedContent: "Lift repaired ahead of schedule"}
+	if len(records) != 1 {
+		t.Fatalf("archive has %d records, want 1", len(records))
+	}
+	got := records[0]
+	if got.RequestID != want.RequestID || got.OriginalTitle != want.OriginalTitle || got.OriginalDescription != want.OriginalDescription || got.TransformedContent != want.TransformedContent {
+		t.Errorf("record = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseHistoryPagination(t *testing.T) {
+	limit, offset, err := parseHistoryPagination(url.Values{})
+	if err != nil || limit != defaultHistoryLimit || offset != 0 {
+		t.Errorf("defaults = %d, %d, %v", limit, offset, err)
+	}
+
+	for _, raw := range []string{"limit=0", "limit=101", "limit=ten", "offset=-1", "offset=x"} {
+		query, _ := url.ParseQuery(raw)
+		if _, _, err := parseHistoryPagination(query); err == nil {
+			t.Errorf("%s accepted, want an error", raw)
+		}
+	}
+}
+
+func TestHistoryEndpoint(t *testing.T) {
+	setupTest(t, nil)
+	store := NewMemoryStore()
+	archive = store
+
+	rec := do(newRequest(http.MethodGet, "/api/history", ""))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Fatalf("empty history: status %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	for _, title := range []string{"first", "second", "third"} {
+		store.Save(context.Background(), ArchiveRecord{OriginalTitle: title})
+	}
+
+	rec = do(newRequest(http.MethodGet, "/api/history?limit=1&offset=1", ""))
+	var records []ArchiveRecord
+	decodeJSON(t, rec, &records)
+	if len(records) != 1 || records[0].OriginalTitle != "second" {
+		t.Errorf("limit=1&offset=1 = %+v, want the second newest", records)
+	}
+
+	rec = do(newRequest(http.MethodGet, "/api/history?limit=500", ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d for limit=500, want 400", rec.Code)
+	}
+}
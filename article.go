@@ -0,0 +1,273 @@
+package ministry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Longest article text sent to the model; the rest is cut on a word boundary
+const maxArticleTextChars = 20000
+
+// Largest article page read when fetching by URL
+const maxArticlePageBytes = 2 << 20
+
+// Returned when an article URL points at a private, loopback or otherwise
+// non-public address
+var ErrBlockedAddress = errors.New("article URL resolves to a non-public address")
+
+// Carrier-grade NAT space, which netip doesn't treat as private
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Whether addr is a public unicast address an article may be fetched from
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// Dialer hook refusing connections to non-public addresses, checked after
+// DNS resolution so rebinding and redirects can't get around it
+func publicOnlyControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !publicAddr(addr) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+	}
+	return nil
+}
+
+// Check an article URL before fetching: http or https, and a host that
+// doesn't resolve to a non-public address
+func validateArticleURL(ctx context.Context, raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("Invalid url %q", raw)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("Invalid url %q; only http and https are allowed", raw)
+	}
+
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !publicAddr(addr) {
+			return nil, ErrBlockedAddress
+		}
+		return u, nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, fmt.Errorf("Could not resolve %q", host)
+	}
+	for _, addr := range addrs {
+		if !publicAddr(addr) {
+			return nil, ErrBlockedAddress
+		}
+	}
+	return u, nil
+}
+
+// Client for article pages: no proxy, public addresses only, at most five redirects
+var articleClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: 5 * time.Second, Control: publicOnlyControl}).DialContext,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return fmt.Errorf("redirect to %s URL not allowed", req.URL.Scheme)
+		}
+		return nil
+	},
+}
+
+// Page markup dropped before looking for the article body
+var articleNoise = regexp.MustCompile(`(?is)<!--.*?-->|<(script|style|noscript|nav|header|footer|aside|form|svg)\b.*?</(script|style|noscript|nav|header|footer|aside|form|svg)>`)
+
+var (
+	articleTitlePattern     = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	articleBodyPattern      = regexp.MustCompile(`(?is)<article\b[^>]*>(.*)</article>`)
+	articleParagraphPattern = regexp.MustCompile(`(?is)<p\b[^>]*>(.*?)</p>`)
+	htmlTagPattern          = regexp.MustCompile(`(?s)<[^>]*>`)
+	spaceBeforePunctuation  = regexp.MustCompile(`\s+([.,;:!?])`)
+)
+
+// Tags removed, entities decoded and whitespace collapsed
+func htmlText(fragment string) string {
+	text := strings.Join(strings.Fields(html.UnescapeString(htmlTagPattern.ReplaceAllString(fragment, " "))), " ")
+	return spaceBeforePunctuation.ReplaceAllString(text, "$1")
+}
+
+// Title and body text of an article page, readability-style: the page's
+// <article> when it has one, otherwise the whole page, reduced to its
+// paragraphs (or all of its text when it has none)
+func extractArticle(page string) (string, string) {
+	var title string
+	if match := articleTitlePattern.FindStringSubmatch(page); match != nil {
+		title = htmlText(match[1])
+	}
+
+	body := articleNoise.ReplaceAllString(page, " ")
+	if match := articleBodyPattern.FindStringSubmatch(body); match != nil {
+		body = match[1]
+	}
+
+	var paragraphs []string
+	for _, match := range articleParagraphPattern.FindAllStringSubmatch(body, -1) {
+		if text := htmlText(match[1]); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+	}
+	if len(paragraphs) == 0 {
+		return title, htmlText(body)
+	}
+	return title, strings.Join(paragraphs, "\n\n")
+}
+
+// Fetch an article page and extract its title and text
+func fetchArticle(ctx context.Context, u *url.URL, userAgent string) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "text/html, text/plain;q=0.9")
+	setUserAgent(req, userAgent)
+
+	resp, err := articleClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to fetch article: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("article page returned status %d", resp.StatusCode)
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxArticlePageBytes))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read article: %v", err)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/plain":
+		return "", strings.TrimSpace(string(page)), nil
+	case "text/html", "application/xhtml+xml", "":
+		title, text := extractArticle(string(page))
+		return title, text, nil
+	default:
+		return "", "", fmt.Errorf("article page has unsupported content type %q", mediaType)
+	}
+}
+
+// JSON body for POST /api/article/transform: a URL to fetch, or the text itself
+type ArticleTransformRequest struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+type ArticleTransformResponse struct {
+	URL                string  `json:"url,omitempty"`
+	Title              string  `json:"title,omitempty"`
+	TextChars          int     `json:"textChars"`
+	TextTruncated      bool    `json:"textTruncated"`
+	TransformedContent string  `json:"transformedContent"`
+	Source             string  `json:"source"`
+	Model              string  `json:"model,omitempty"`
+	TokensUsed         int     `json:"tokensUsed"`
+	EstimatedCostUSD   float64 `json:"estimatedCostUSD"`
+}
+
+// Transform a whole article, given as text or fetched from its URL
+func transformArticle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var requestData ArticleTransformRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	requestData.URL = strings.TrimSpace(requestData.URL)
+	requestData.Text = strings.TrimSpace(requestData.Text)
+	if (requestData.URL == "") == (requestData.Text == "") {
+		http.Error(w, "Exactly one of 'url' or 'text' is required", http.StatusBadRequest)
+		return
+	}
+
+	title, text := strings.TrimSpace(requestData.Title), requestData.Text
+	if requestData.URL != "" {
+		u, err := validateArticleURL(r.Context(), requestData.URL)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		pageTitle, pageText, err := fetchArticle(r.Context(), u, currentConfig().UserAgent)
+		if err != nil {
+			slog.WarnContext(r.Context(), "article fetch failed", "url", u.Redacted(), "error", err)
+			status := http.StatusBadGateway
+			if errors.Is(err, ErrBlockedAddress) {
+				status = http.StatusBadRequest
+			}
+			http.Error(w, fmt.Sprintf("Error fetching article: %v", err), status)
+			return
+		}
+		if title == "" {
+			title = pageTitle
+		}
+		text = pageText
+	}
+	if text == "" {
+		http.Error(w, "No article text found", http.StatusUnprocessableEntity)
+		return
+	}
+
+	response := ArticleTransformResponse{URL: requestData.URL, Title: title, Source: currentConfig().LLMProvider}
+	text, response.TextTruncated = truncateText(text, maxArticleTextChars)
+	response.TextChars = len([]rune(text))
+
+	recorder := &usageRecorder{}
+	options := TransformOptions{Intensity: defaultIntensity, Persona: defaultPersona, Language: promptLanguage(r.Header.Get("Accept-Language"))}
+	w.Header().Add("Vary", "Accept-Language")
+	ctx := withUsageRecorder(withTransformOptions(r.Context(), options), recorder)
+
+	start := time.Now()
+	transformed, err := currentTransformer().Transform(ctx, title, text)
+	recordUpstream(r.Context(), currentConfig().LLMProvider, time.Since(start))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "article transform error", "error", err)
+		writeTransformError(w, err)
+		return
+	}
+
+	transformed, _ = truncateText(transformed, currentConfig().TransformMaxChars)
+	if response.TransformedContent, err = currentConfig().Profanity.Apply(transformed); err != nil {
+		slog.WarnContext(r.Context(), "article transform rejected by profanity filter")
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	response.Model = servedModel(ctx)
+	response.TokensUsed, response.EstimatedCostUSD = trackUsage(recorder, currentConfig().ModelPricing)
+	json.NewEncoder(w).Encode(enveloped(r.Context(), response))
+}
@@ -0,0 +1,188 @@
+package ministry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPublicAddr(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":        true,
+		"2606:4700::6810:85e5": true,
+		"127.0.0.1":            false,
+		"10.1.2.3":             false,
+		"172.16.0.1":           false,
+		"192.168.1.1":          false,
+		"169.254.169.254":      false,
+		"100.64.0.1":           false,
+		"0.0.0.0":              false,
+		"::1":                  false,
+		"fd00::1":              false,
+		"fe80::1":              false,
+		"::ffff:10.0.0.1":      false,
+		"::ffff:93.184.216.34": true,
+	}
+	for raw, want := range tests {
+		if got := publicAddr(netip.MustParseAddr(raw)); got != want {
+			t.Errorf("publicAddr(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestValidateArticleURL(t *testing.T) {
+	ctx := context.Background()
+	if _, err := validateArticleURL(ctx, "https://93.184.216.34/news/lift"); err != nil {
+		t.Errorf("public URL rejected: %v", err)
+	}
+
+	for _, raw := range []string{"http://127.0.0.1/admin", "http://[::1]:8080/", "http://169.254.169.254/latest/meta-data/", "http://localhost/"} {
+		if _, err := validateArticleURL(ctx, raw); !errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("validateArticleURL(%s) = %v, want ErrBlockedAddress", raw, err)
+		}
+	}
+	for _, raw := range []string{"file:///etc/passwd", "gopher://93.184.216.34/", "not a url", "https://"} {
+		if _, err := validateArticleURL(ctx, raw); err == nil || errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("validateArticleURL(%s) = %v, want an invalid URL error", raw, err)
+		}
+	}
+}
+
+func TestExtractArticle(t *testing.T) {
+	page := `<html><head><title>Lift broken &amp; rations cut</title><style>p { color: red }</style></head>
+<body><nav><p>Home</p></nav>
+<article><h1>Lift broken</h1><p>The lift in <b>Victory Mansions</b> is broken .</p>
+<script>alert("hi")</script><!-- <p>hidden</p> -->
+<p>Residents   climb seven flights.</p><p> </p></article>
+<footer><p>Ministry of Truth</p></footer></body></html>`
+
+	title, text := extractArticle(page)
+	if title != "Lift broken & rations cut" {
+		t.Errorf("title = %q", title)
+	}
+	if text != "The lift in Victory Mansions is broken.\n\nResidents climb seven flights." {
+		t.Errorf("text = %q", text)
+	}
+
+	// Without paragraphs the page's text is used
+	if _, text := extractArticle(`<div>War is <i>peace</i></div>`); text != "War is peace" {
+		t.Errorf("text without paragraphs = %q", text)
+	}
+}
+
+// Send article fetches to handler, whatever address the URL names
+func useArticleServer(t *testing.T, handler http.Handler) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	previous := articleClient
+	articleClient = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}}
+	t.Cleanup(func() { articleClient = previous })
+}
+
+func TestArticleTransformFromURL(t *testing.T) {
+	openAI := newFakeOpenAI("Lift repaired ahead of schedule")
+	setupTest(t, upstreams(openAI, nil))
+	useArticleServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<title>Lift broken again</title><article><p>The lift in Victory Mansions is broken.</p></article>`))
+	}))
+
+	rec := do(newRequest(http.MethodPost, "/api/article/transform", `{"url": "http://93.184.216.34/news/lift"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var response ArticleTransformResponse
+	decodeJSON(t, rec, &response)
+	if response.Title != "Lift broken again" || response.TransformedContent != "Lift repaired ahead of schedule" || response.TextChars != len("The lift in Victory Mansions is broken.") {
+		t.Errorf("response = %+v", response)
+	}
+	if user := openAI.Requests()[0].Messages[1].Content; !strings.Contains(user, "The lift in Victory Mansions is broken.") {
+		t.Errorf("user message = %q, want the extracted text", user)
+	}
+}
+
+func TestArticleTransformFromText(t *testing.T) {
+	openAI := newFakeOpenAI("Rations are plentiful")
+	setupTest(t, upstreams(openAI, nil))
+
+	text := strings.Repeat("Rations cut again. ", maxArticleTextChars/10)
+	rec := do(newRequest(http.MethodPost, "/api/article/transform", `{"title": "Rations", "text": "`+text+`"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var response ArticleTransformResponse
+	decodeJSON(t, rec, &response)
+	if response.Title != "Rations" || response.TransformedContent != "Rations are plentiful" {
+		t.Errorf("response = %+v", response)
+	}
+	if !response.TextTruncated || response.TextChars > maxArticleTextChars {
+		t.Errorf("textTruncated %v with %d chars, want the text cut to %d", response.TextTruncated, response.TextChars, maxArticleTextChars)
+	}
+}
+
+func TestArticleTransformRejectsBadRequests(t *testing.T) {
+	openAI := newFakeOpenAI("unused")
+	setupTest(t, upstreams(openAI, nil))
+
+	for body, want := range map[string]string{
+		`{}`: "Exactly one of 'url' or 'text'",
+		`{"url": "https://93.184.216.34/", "text": "both"}`:  "Exactly one of 'url' or 'text'",
+		`{"url": "file:///etc/passwd"}`:                      "Invalid url",
+		`{"url": "ftp://93.184.216.34/lift.txt"}`:            "only http and https",
+		`{"url": "http://169.254.169.254/latest/meta-data"}`: ErrBlockedAddress.Error(),
+	} {
+		rec := do(newRequest(http.MethodPost, "/api/article/transform", body))
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("body %s: status = %d, body %q; want 400 mentioning %q", body, rec.Code, rec.Body.String(), want)
+		}
+	}
+	if openAI.Calls() != 0 {
+		t.Error("a rejected article reached the model")
+	}
+}
+
+func TestArticleClientRefusesPrivateAddresses(t *testing.T) {
+	// A public-looking URL that ends up at a private address, as after DNS
+	// rebinding or a redirect, is stopped at dial time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the private server")
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	_, _, err := fetchArticle(context.Background(), u, "")
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("fetchArticle = %v, want ErrBlockedAddress", err)
+	}
+}
+
+func TestArticleTransformUpstreamPageErrors(t *testing.T) {
+	setupTest(t, upstreams(newFakeOpenAI("unused"), nil))
+	useArticleServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/pdf" {
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("%PDF"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+
+	for _, path := range []string{"/missing", "/pdf"} {
+		rec := do(newRequest(http.MethodPost, "/api/article/transform", `{"url": "http://93.184.216.34`+path+`"}`))
+		if rec.Code != http.StatusBadGateway {
+			t.Errorf("%s: status = %d, want 502", path, rec.Code)
+		}
+	}
+}
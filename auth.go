@@ -0,0 +1,54 @@
+package ministry
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Parse SERVICE_API_KEYS into its non-empty, comma-separated keys
+func parseServiceAPIKeys(raw string) []string {
+	var keys []string
+	for _, key := range strings.Split(raw, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Whether key matches one of keys, comparing in constant time. Both sides
+// are hashed first so the comparison doesn't leak key lengths either.
+func validAPIKey(key string, keys []string) bool {
+	if key == "" {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	match := 0
+	for _, candidate := range keys {
+		candidateSum := sha256.Sum256([]byte(candidate))
+		match |= subtle.ConstantTimeCompare(sum[:], candidateSum[:])
+	}
+	return match == 1
+}
+
+// Whether a path needs an API key when SERVICE_API_KEYS is set. Signed
+// result links carry their own token instead.
+func requiresAPIKey(path string) bool {
+	return strings.HasPrefix(path, "/api/") && path != "/api/health" && path != "/api/transform/result"
+}
+
+// Require a valid X-API-Key on /api/* routes when SERVICE_API_KEYS is set
+func apiKeyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(currentConfig().ServiceAPIKeys) > 0 && requiresAPIKey(r.URL.Path) && !validAPIKey(r.Header.Get("X-API-Key"), currentConfig().ServiceAPIKeys) {
+			w.Header().Set("WWW-Authenticate", `APIKey header="X-API-Key"`)
+			http.Error(w, "Missing or invalid API key", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
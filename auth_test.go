@@ -0,0 +1,60 @@
+package ministry
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestParseServiceAPIKeys(t *testing.T) {
+	if got := parseServiceAPIKeys(" alpha, ,beta,"); !slices.Equal(got, []string{"alpha", "beta"}) {
+		t.Errorf("parseServiceAPIKeys = %q", got)
+	}
+	if got := parseServiceAPIKeys(""); got != nil {
+		t.Errorf("parseServiceAPIKeys(\"\") = %q, want nil", got)
+	}
+}
+
+func TestValidAPIKey(t *testing.T) {
+	keys := []string{"alpha", "beta"}
+	for key, want := range map[string]bool{"alpha": true, "beta": true, "gamma": false, "alph": false, "": false} {
+		if got := validAPIKey(key, keys); got != want {
+			t.Errorf("validAPIKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	setupTest(t, nil, "SERVICE_API_KEYS", "alpha,beta")
+
+	tests := []struct {
+		name, key string
+		want      int
+	}{
+		{"valid", "beta", http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong", "gamma", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		rec := do(newRequest(http.MethodGet, "/api/slogans", "", "X-API-Key", test.key))
+		if rec.Code != test.want {
+			t.Errorf("%s key: status = %d, want %d", test.name, rec.Code, test.want)
+		}
+		if test.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s key: no WWW-Authenticate challenge", test.name)
+		}
+	}
+
+	// Health checks stay open
+	if rec := do(newRequest(http.MethodGet, "/api/health", "")); rec.Code != http.StatusOK {
+		t.Errorf("/api/health without a key: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestAPIKeyMiddlewareDisabled(t *testing.T) {
+	setupTest(t, nil, "SERVICE_API_KEYS", "")
+
+	if rec := do(newRequest(http.MethodGet, "/api/slogans", "")); rec.Code != http.StatusOK {
+		t.Errorf("status = %d without SERVICE_API_KEYS, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,171 @@
+package ministry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Returned without calling the model while the circuit breaker is open
+var ErrCircuitOpen = errors.New("model circuit breaker is open")
+
+// Cause of a context deadline the caller asked for with timeoutMs
+var ErrCallerTimeout = errors.New("caller's timeoutMs elapsed")
+
+// Circuit breaker states
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// Fails fast after Threshold consecutive failures; once Cooldown has passed
+// a single probe call is let through and its outcome closes or re-opens it
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{Threshold: threshold, Cooldown: cooldown, state: breakerClosed, now: time.Now}
+}
+
+// Whether a call may proceed; ErrCircuitOpen while open or while a half-open probe is in flight
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerOpen && b.now().Sub(b.openedAt) >= b.Cooldown {
+		b.state = breakerHalfOpen
+		b.probing = false
+	}
+
+	switch b.state {
+	case breakerOpen:
+		return ErrCircuitOpen
+	case breakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Record the outcome of an allowed call. Cancellation by the caller, the
+// caller's own timeoutMs, or giving up on a local concurrency slot says
+// nothing about the upstream, so it isn't counted.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, ErrCallerTimeout) || errors.Is(err, ErrConcurrencyLimit)) {
+		b.probing = false
+		return
+	}
+
+	if err == nil {
+		b.state = breakerClosed
+		b.failures = 0
+		b.probing = false
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.Threshold {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+		b.probing = false
+	}
+}
+
+// Current state and consecutive failure count
+func (b *CircuitBreaker) State() (string, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerOpen && b.now().Sub(b.openedAt) >= b.Cooldown {
+		return breakerHalfOpen, b.failures
+	}
+	return b.state, b.failures
+}
+
+// Transformer guarded by a circuit breaker
+type BreakerTransformer struct {
+	Transformer
+	Breaker *CircuitBreaker
+}
+
+// Record err, telling a deadline the caller set apart from the server's own
+func (t *BreakerTransformer) record(ctx context.Context, err error) {
+	if err != nil && errors.Is(context.Cause(ctx), ErrCallerTimeout) {
+		err = ErrCallerTimeout
+	}
+	t.Breaker.Record(err)
+}
+
+func (t *BreakerTransformer) Transform(ctx context.Context, title, description string) (string, error) {
+	if err := t.Breaker.Allow(); err != nil {
+		return "", err
+	}
+
+	transformed, err := t.Transformer.Transform(ctx, title, description)
+	t.record(ctx, err)
+	return transformed, err
+}
+
+// Dry runs never reach the model, so they bypass the breaker
+func (t *BreakerTransformer) DryRun(ctx context.Context, title, description string) (DryRunResult, error) {
+	runner, ok := t.Transformer.(DryRunner)
+	if !ok {
+		return DryRunResult{}, ErrDryRunUnsupported
+	}
+	return runner.DryRun(ctx, title, description)
+}
+
+// Translate through the breaker when the wrapped transformer supports it
+func (t *BreakerTransformer) Translate(ctx context.Context, text, language string) (string, error) {
+	translator, ok := t.Transformer.(Translator)
+	if !ok {
+		return "", ErrTranslationUnsupported
+	}
+
+	if err := t.Breaker.Allow(); err != nil {
+		return "", err
+	}
+
+	translated, err := translator.Translate(ctx, text, language)
+	t.record(ctx, err)
+	return translated, err
+}
+
+// Stream when the wrapped transformer can, otherwise emit the whole result as one token
+func (t *BreakerTransformer) TransformStream(ctx context.Context, title, description string, onToken func(token string) error) error {
+	streamer, ok := t.Transformer.(StreamTransformer)
+	if !ok {
+		transformed, err := t.Transform(ctx, title, description)
+		if err != nil {
+			return err
+		}
+		return onToken(transformed)
+	}
+
+	if err := t.Breaker.Allow(); err != nil {
+		return err
+	}
+
+	err := streamer.TransformStream(ctx, title, description, onToken)
+	t.record(ctx, err)
+	return err
+}
+
+// Breaker guarding the active transformer, or nil when disabled
+var llmBreaker *CircuitBreaker
@@ -0,0 +1,140 @@
+package ministry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Breaker on a clock the test moves by hand
+func manualBreaker(threshold int, cooldown time.Duration) (*CircuitBreaker, func(time.Duration)) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(threshold, cooldown)
+	breaker.now = func() time.Time { return now }
+	return breaker, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	breaker, advance := manualBreaker(2, time.Minute)
+	failure := errors.New("upstream down")
+
+	for range 2 {
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("Allow while closed = %v", err)
+		}
+		breaker.Record(failure)
+	}
+	if state, failures := breaker.State(); state != breakerOpen || failures != 2 {
+		t.Fatalf("state = %s with %d failures, want open after 2", state, failures)
+	}
+	if err := breaker.Allow(); err != ErrCircuitOpen {
+		t.Errorf("Allow while open = %v, want ErrCircuitOpen", err)
+	}
+
+	// After the cooldown a single probe goes through
+	advance(time.Minute)
+	if state, _ := breaker.State(); state != breakerHalfOpen {
+		t.Fatalf("state = %s after the cooldown, want half-open", state)
+	}
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("probe Allow = %v", err)
+	}
+	if err := breaker.Allow(); err != ErrCircuitOpen {
+		t.Errorf("second Allow during the probe = %v, want ErrCircuitOpen", err)
+	}
+
+	// A failed probe re-opens it for another cooldown
+	breaker.Record(failure)
+	if state, _ := breaker.State(); state != breakerOpen {
+		t.Fatalf("state = %s after a failed probe, want open", state)
+	}
+
+	advance(time.Minute)
+	breaker.Allow()
+	breaker.Record(nil)
+	if state, failures := breaker.State(); state != breakerClosed || failures != 0 {
+		t.Errorf("state = %s with %d failures after a good probe, want closed", state, failures)
+	}
+}
+
+func TestCircuitBreakerIgnoresCallerErrors(t *testing.T) {
+	breaker, _ := manualBreaker(1, time.Minute)
+
+	for _, err := range []error{context.Canceled, ErrCallerTimeout, ErrConcurrencyLimit} {
+		breaker.Allow()
+		breaker.Record(err)
+	}
+	if state, failures := breaker.State(); state != breakerClosed || failures != 0 {
+		t.Errorf("state = %s with %d failures, want caller errors ignored", state, failures)
+	}
+}
+
+func TestBreakerIgnoresCallerTimeouts(t *testing.T) {
+	breaker, _ := manualBreaker(1, time.Minute)
+	transformer := &BreakerTransformer{Transformer: &scriptedTransformer{err: context.DeadlineExceeded}, Breaker: breaker}
+
+	// The caller's timeoutMs ran out: not the upstream's fault
+	ctx, cancel := context.WithTimeoutCause(context.Background(), 0, ErrCallerTimeout)
+	defer cancel()
+	transformer.Transform(ctx, "Lift broken again", "")
+	if state, _ := breaker.State(); state != breakerClosed {
+		t.Errorf("state = %s after a caller timeout, want closed", state)
+	}
+
+	// The server's own deadline still counts
+	ctx, cancel = context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	transformer.Transform(ctx, "Lift broken again", "")
+	if state, _ := breaker.State(); state != breakerOpen {
+		t.Errorf("state = %s after a server timeout, want open", state)
+	}
+}
+
+func TestBreakerOverTransformEndpoint(t *testing.T) {
+	var healthy atomic.Bool
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		if !healthy.Load() {
+			http.Error(w, "overloaded", http.StatusInternalServerError)
+			return
+		}
+		writeCompletion(w, req.Model, "Lift repaired ahead of schedule", "stop")
+	}
+	setupTest(t, upstreams(openAI, nil), "BREAKER_THRESHOLD", "2", "BREAKER_COOLDOWN", "1m")
+	now := time.Now()
+	llmBreaker.now = func() time.Time { return now }
+
+	for i := range 2 {
+		if rec := postTransform(`{"title": "Lift broken again"}`); rec.Code != http.StatusBadGateway {
+			t.Fatalf("failure %d: status = %d, want 502", i+1, rec.Code)
+		}
+	}
+
+	// Open: fail fast without calling the model
+	rec := postTransform(`{"title": "Lift broken again"}`)
+	if rec.Code != http.StatusServiceUnavailable || openAI.Calls() != 2 {
+		t.Errorf("status = %d after %d model calls, want 503 without a third call", rec.Code, openAI.Calls())
+	}
+	if rec := do(newRequest(http.MethodGet, "/ready", "")); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/ready status = %d while open, want 503", rec.Code)
+	}
+	if body := do(newRequest(http.MethodGet, "/metrics", "")).Body.String(); !strings.Contains(body, "ministry_llm_circuit_state 2") {
+		t.Errorf("metrics don't report the open circuit: %q", body)
+	}
+
+	// Half-open: the probe succeeds and closes it
+	now = now.Add(time.Minute)
+	healthy.Store(true)
+	if rec := postTransform(`{"title": "Lift broken again"}`); rec.Code != http.StatusOK {
+		t.Fatalf("probe status = %d, want 200", rec.Code)
+	}
+	var ready ReadyResponse
+	decodeJSON(t, do(newRequest(http.MethodGet, "/ready", "")), &ready)
+	if ready.Status != "ready" || ready.Circuit != breakerClosed {
+		t.Errorf("/ready = %+v, want ready and closed", ready)
+	}
+}
@@ -0,0 +1,413 @@
+package ministry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// Key/value cache with per-entry expiry
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Stats() CacheStats
+	Purge(ctx context.Context) (int, error)
+}
+
+// Hit/miss counters for a cache; Entries is nil when the backend can't cheaply count them
+type CacheStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Entries   *int  `json:"entries,omitempty"`
+}
+
+// Thread-safe counters shared by the Cache implementations
+type cacheCounters struct {
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+func (c *cacheCounters) stats() CacheStats {
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Evictions: c.evictions.Load()}
+}
+
+// Caches for NewsAPI responses, transform results and idempotent replays
+var (
+	newsCache        Cache
+	transformCache   Cache
+	idempotencyCache Cache
+)
+
+// Build the news, transform and idempotency caches for the configured backend
+func newCaches(cfg *Config) (Cache, Cache, Cache, error) {
+	switch cfg.CacheBackend {
+	case "redis":
+		options, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid REDIS_URL: %v", err)
+		}
+		client := redis.NewClient(options)
+		return NewRedisCache(client, "news:"), NewRedisCache(client, "transform:"), NewRedisCache(client, "idempotency:"), nil
+	default:
+		return NewMemoryCache(), NewMemoryCache(), NewMemoryCache(), nil
+	}
+}
+
+// Number of entries after which Set sweeps out expired ones
+const memoryCacheSweepThreshold = 1024
+
+type memoryCacheEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// In-process Cache
+type MemoryCache struct {
+	mu       sync.Mutex
+	entries  map[string]memoryCacheEntry
+	now      func() time.Time
+	counters cacheCounters
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryCacheEntry), now: time.Now}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		c.counters.misses.Add(1)
+		return nil, false, nil
+	}
+	if !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		c.counters.evictions.Add(1)
+		c.counters.misses.Add(1)
+		return nil, false, nil
+	}
+	c.counters.hits.Add(1)
+	return entry.value, true, nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if len(c.entries) >= memoryCacheSweepThreshold {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, k)
+				c.counters.evictions.Add(1)
+			}
+		}
+	}
+
+	c.entries[key] = memoryCacheEntry{value: value, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+// Drop every entry, returning how many were removed
+func (c *MemoryCache) Purge(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	purged := len(c.entries)
+	c.entries = make(map[string]memoryCacheEntry)
+	c.counters.evictions.Add(int64(purged))
+	return purged, nil
+}
+
+func (c *MemoryCache) Stats() CacheStats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	stats := c.counters.stats()
+	stats.Entries = &entries
+	return stats
+}
+
+// Redis-backed Cache, shared between instances
+type RedisCache struct {
+	client   *redis.Client
+	prefix   string
+	counters cacheCounters
+}
+
+func NewRedisCache(client *redis.Client, prefix string) *RedisCache {
+	return &RedisCache{client: client, prefix: prefix}
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		c.counters.misses.Add(1)
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("redis get failed: %v", err)
+	}
+	c.counters.hits.Add(1)
+	return value, true, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.client.Set(ctx, c.prefix+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("redis set failed: %v", err)
+	}
+	return nil
+}
+
+// Delete every key under this cache's prefix, returning how many were removed
+func (c *RedisCache) Purge(ctx context.Context) (int, error) {
+	purged := 0
+	iter := c.client.Scan(ctx, 0, c.prefix+"*", 500).Iterator()
+	var batch []string
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == 500 {
+			deleted, err := c.client.Del(ctx, batch...).Result()
+			if err != nil {
+				return purged, fmt.Errorf("redis del failed: %v", err)
+			}
+			purged += int(deleted)
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return purged, fmt.Errorf("redis scan failed: %v", err)
+	}
+	if len(batch) > 0 {
+		deleted, err := c.client.Del(ctx, batch...).Result()
+		if err != nil {
+			return purged, fmt.Errorf("redis del failed: %v", err)
+		}
+		purged += int(deleted)
+	}
+	return purged, nil
+}
+
+// Hits and misses seen by this instance; Redis expires keys itself, so
+// evictions and entries aren't tracked
+func (c *RedisCache) Stats() CacheStats {
+	return c.counters.stats()
+}
+
+// Cached result of a successful transform
+type cachedTransform struct {
+	Content      string `json:"content"`
+	Source       string `json:"source"`
+	Model        string `json:"model,omitempty"`
+	FinishReason string `json:"finishReason,omitempty"`
+}
+
+// Cache key for a transform of title/description by provider and options
+func transformCacheKey(provider string, options TransformOptions, title, description string) string {
+	encodedOptions, _ := json.Marshal(options)
+	sum := sha256.Sum256([]byte(provider + "\n" + string(encodedOptions) + "\n" + title + "\n" + description))
+	return hex.EncodeToString(sum[:])
+}
+
+// Look up a transform; cache errors are logged and treated as a miss
+func getCachedTransform(ctx context.Context, key string) (cachedTransform, bool) {
+	var cached cachedTransform
+
+	data, ok, err := transformCache.Get(ctx, key)
+	if err != nil {
+		slog.WarnContext(ctx, "transform cache get failed", "error", err)
+		recordCacheLookup(ctx, "", false)
+		return cached, false
+	}
+	if !ok || json.Unmarshal(data, &cached) != nil {
+		recordCacheLookup(ctx, "", false)
+		return cached, false
+	}
+	recordCacheLookup(ctx, cached.Source, true)
+	return cached, true
+}
+
+// Store a transform; cache errors are logged
+func setCachedTransform(ctx context.Context, key string, cached cachedTransform) {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+	if err := transformCache.Set(ctx, key, data, currentConfig().TransformCacheTTL); err != nil {
+		slog.WarnContext(ctx, "transform cache set failed", "error", err)
+	}
+}
+
+// Concurrent transforms of the same input share one upstream call
+var transformFlight singleflight.Group
+
+// Test hooks: transformFlightJoined runs once a caller is waiting on the
+// shared call for its key, and sharedTransforms is done once every shared
+// call has finished, including ones all their callers gave up on
+var (
+	transformFlightJoined = func() {}
+	sharedTransforms      sync.WaitGroup
+)
+
+// Outcome of a shared transform, handed to every caller waiting on it
+type sharedTransform struct {
+	content      string
+	usage        []TokenUsage
+	model        string
+	finishReason string
+}
+
+// Transform via the LLM and cache the result. Concurrent calls with the same
+// cache key wait for the first one and share its result instead of each
+// calling the model. The shared call runs detached from any one caller, so
+// a caller that disconnects or sets a short timeoutMs gives up alone; every
+// caller gets the served model and token usage on its own recorder.
+func transformOnce(ctx context.Context, key, title, description string) (string, error) {
+	start := time.Now()
+	defer func() { recordUpstream(ctx, currentConfig().LLMProvider, time.Since(start)) }()
+
+	flight := func() (any, error) {
+		flightCtx := context.WithoutCancel(ctx)
+		if timeout := routeProfileFor("/api/transform").Timeout; timeout > 0 {
+			var cancel context.CancelFunc
+			flightCtx, cancel = context.WithTimeout(flightCtx, timeout)
+			defer cancel()
+		}
+
+		// Counted in the usage totals once here, not once per caller
+		recorder := &usageRecorder{}
+		flightCtx = withUsageRecorder(flightCtx, recorder)
+		content, err := currentTransformer().Transform(flightCtx, title, description)
+		trackUsage(recorder, currentConfig().ModelPricing)
+		shared := sharedTransform{content: content, usage: recorder.Calls(), model: servedModel(flightCtx), finishReason: servedFinishReason(flightCtx)}
+		if err != nil {
+			return shared, err
+		}
+		setCachedTransform(flightCtx, key, cachedTransform{Content: content, Source: currentConfig().LLMProvider, Model: shared.model, FinishReason: shared.finishReason})
+		return shared, nil
+	}
+
+	sharedTransforms.Add(1)
+	results := transformFlight.DoChan(key, flight)
+	transformFlightJoined()
+
+	select {
+	case <-ctx.Done():
+		go func() {
+			<-results
+			sharedTransforms.Done()
+		}()
+		return "", ctx.Err()
+	case result := <-results:
+		sharedTransforms.Done()
+		shared := result.Val.(sharedTransform)
+		for _, usage := range shared.usage {
+			recordSharedUsage(ctx, usage)
+		}
+		if result.Err != nil {
+			return "", result.Err
+		}
+		recordServedModel(ctx, shared.model, shared.finishReason)
+		return shared.content, nil
+	}
+}
+
+// Result of a cache purge
+type CachePurgeResponse struct {
+	Target  string `json:"target"`
+	Evicted int    `json:"evicted"`
+}
+
+// Caches named by a purge target (news, transform or all)
+func purgeTargets(target string, news, transform Cache) ([]Cache, error) {
+	switch target {
+	case "news":
+		return []Cache{news}, nil
+	case "transform":
+		return []Cache{transform}, nil
+	case "all":
+		return []Cache{news, transform}, nil
+	default:
+		return nil, fmt.Errorf("Invalid target %q; allowed values: news, transform, all", target)
+	}
+}
+
+// Clear every cache, returning the total number of entries removed
+func purgeCaches(ctx context.Context, caches []Cache) (int, error) {
+	evicted := 0
+	for _, cache := range caches {
+		purged, err := cache.Purge(ctx)
+		evicted += purged
+		if err != nil {
+			return evicted, err
+		}
+	}
+	return evicted, nil
+}
+
+// Statistics for the news and transform caches
+type CacheStatsResponse struct {
+	News      CacheStats `json:"news"`
+	Transform CacheStats `json:"transform"`
+}
+
+// Cache statistics endpoint
+func getCacheStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CacheStatsResponse{
+		News:      newsCache.Stats(),
+		Transform: transformCache.Stats(),
+	})
+}
+
+// Clear caches on demand; requires SERVICE_API_KEYS so it is never left open
+func purgeCache(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if len(currentConfig().ServiceAPIKeys) == 0 {
+		http.Error(w, "Cache purge requires SERVICE_API_KEYS to be configured", http.StatusForbidden)
+		return
+	}
+
+	var requestData struct {
+		Target string `json:"target"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	target := strings.ToLower(requestData.Target)
+	caches, err := purgeTargets(target, newsCache, transformCache)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	evicted, err := purgeCaches(r.Context(), caches)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "cache purge failed", "target", target, "error", err)
+		http.Error(w, "Error purging cache", http.StatusInternalServerError)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "cache purged", "target", target, "evicted", evicted)
+	json.NewEncoder(w).Encode(CachePurgeResponse{Target: target, Evicted: evicted})
+}
@@ -0,0 +1,407 @@
+package ministry
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	cache := NewMemoryCache()
+	cache.now = func() time.Time { return now }
+
+	if _, ok, _ := cache.Get(ctx, "headlines"); ok {
+		t.Fatal("Get on an empty cache hit")
+	}
+	cache.Set(ctx, "headlines", []byte("war is peace"), time.Minute)
+	if value, ok, err := cache.Get(ctx, "headlines"); !ok || err != nil || string(value) != "war is peace" {
+		t.Fatalf("Get = %q, %v, %v", value, ok, err)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok, _ := cache.Get(ctx, "headlines"); ok {
+		t.Error("Get hit an expired entry")
+	}
+}
+
+// Minimal RESP2 server speaking the handful of commands RedisCache uses
+type fakeRedis struct {
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+// Start a fake Redis server and return a client connected to it
+func newFakeRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeRedis{values: map[string]string{}, expires: map[string]time.Time{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String(), Protocol: 2, DisableIdentity: true})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		io.WriteString(conn, s.reply(args))
+	}
+}
+
+// Read one command sent as an array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func bulkString(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+// Whether key holds an unexpired value; expired keys are dropped
+func (s *fakeRedis) live(key string) bool {
+	if expires, ok := s.expires[key]; ok && !time.Now().Before(expires) {
+		delete(s.values, key)
+		delete(s.expires, key)
+	}
+	_, ok := s.values[key]
+	return ok
+}
+
+func (s *fakeRedis) reply(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		if !s.live(args[1]) {
+			return "$-1\r\n"
+		}
+		return bulkString(s.values[args[1]])
+	case "SET":
+		s.values[args[1]] = args[2]
+		delete(s.expires, args[1])
+		if len(args) == 5 {
+			n, _ := strconv.Atoi(args[4])
+			unit := time.Second
+			if strings.EqualFold(args[3], "px") {
+				unit = time.Millisecond
+			}
+			s.expires[args[1]] = time.Now().Add(time.Duration(n) * unit)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if s.live(key) {
+				delete(s.values, key)
+				deleted++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	case "SCAN":
+		var keys []string
+		for key := range s.values {
+			if matched, _ := path.Match(args[3], key); matched && s.live(key) {
+				keys = append(keys, bulkString(key))
+			}
+		}
+		return fmt.Sprintf("*2\r\n%s*%d\r\n%s", bulkString("0"), len(keys), strings.Join(keys, ""))
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeRedis(t)
+	cache := NewRedisCache(client, "news:")
+
+	if _, ok, err := cache.Get(ctx, "headlines"); ok || err != nil {
+		t.Fatalf("Get on an empty cache = %v, %v", ok, err)
+	}
+	if err := cache.Set(ctx, "headlines", []byte("war is peace"), 50*time.Millisecond); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if value, ok, err := cache.Get(ctx, "headlines"); !ok || err != nil || string(value) != "war is peace" {
+		t.Fatalf("Get = %q, %v, %v", value, ok, err)
+	}
+
+	// Keys are namespaced so several caches can share one Redis
+	if raw, err := client.Get(ctx, "news:headlines").Result(); err != nil || raw != "war is peace" {
+		t.Errorf("news:headlines = %q, %v", raw, err)
+	}
+	if _, ok, _ := NewRedisCache(client, "transform:").Get(ctx, "headlines"); ok {
+		t.Error("another prefix saw the news entry")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, ok, _ := cache.Get(ctx, "headlines"); ok {
+		t.Error("Get hit an expired entry")
+	}
+}
+
+func TestNewCachesRejectsBadRedisURL(t *testing.T) {
+	if _, _, _, err := newCaches(&Config{CacheBackend: "redis", RedisURL: "http://localhost"}); err == nil || !strings.Contains(err.Error(), "REDIS_URL") {
+		t.Errorf("newCaches = %v, want a REDIS_URL error", err)
+	}
+}
+
+func TestTransformCacheHit(t *testing.T) {
+	openAI := newFakeOpenAI("Lift repaired ahead of schedule", "a different answer")
+	setupTest(t, upstreams(openAI, nil))
+
+	for range 2 {
+		rec := postTransform(`{"title": "Lift broken again"}`)
+		var response TransformResponse
+		decodeJSON(t, rec, &response)
+		if response.TransformedContent != "Lift repaired ahead of schedule" {
+			t.Errorf("transformedContent = %q, want the cached answer", response.TransformedContent)
+		}
+	}
+	if openAI.Calls() != 1 {
+		t.Errorf("model got %d calls, want the repeat served from cache", openAI.Calls())
+	}
+
+	// Different input misses
+	postTransform(`{"title": "Rations cut"}`)
+	if openAI.Calls() != 2 {
+		t.Errorf("model got %d calls, want 2", openAI.Calls())
+	}
+}
+
+func TestConcurrentIdenticalTransformsShareOneCall(t *testing.T) {
+	release := make(chan struct{})
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		<-release
+		writeCompletion(w, req.Model, "Lift repaired ahead of schedule", "stop")
+	}
+	setupTest(t, upstreams(openAI, nil))
+
+	// Hold the model's reply until every caller is waiting on the shared call
+	const callers = 10
+	joined := make(chan struct{}, callers)
+	transformFlightJoined = func() { joined <- struct{}{} }
+	t.Cleanup(func() { transformFlightJoined = func() {} })
+	before := usageTracker.Summary()
+
+	responses := make([]*httptest.ResponseRecorder, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = postTransform(`{"title": "Lift broken again"}`)
+		}()
+	}
+
+	for range callers {
+		<-joined
+	}
+	close(release)
+	wg.Wait()
+
+	if openAI.Calls() != 1 {
+		t.Errorf("model got %d calls for %d identical transforms, want 1", openAI.Calls(), callers)
+	}
+	for i, rec := range responses {
+		var response TransformResponse
+		decodeJSON(t, rec, &response)
+		if rec.Code != http.StatusOK || response.TransformedContent != "Lift repaired ahead of schedule" || response.Model != "gpt-3.5-turbo" || response.TokensUsed != 30 {
+			t.Errorf("caller %d: status %d, response %+v", i, rec.Code, response)
+		}
+	}
+	if calls := usageTracker.Summary().Calls - before.Calls; calls != 1 {
+		t.Errorf("usage totals count %d calls, want the shared call once", calls)
+	}
+}
+
+func TestSharedTransformOutlivesImpatientCaller(t *testing.T) {
+	release := make(chan struct{})
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		<-release
+		writeCompletion(w, req.Model, "Lift repaired ahead of schedule", "stop")
+	}
+	setupTest(t, upstreams(openAI, nil))
+
+	joined := make(chan struct{}, 2)
+	transformFlightJoined = func() { joined <- struct{}{} }
+	t.Cleanup(func() { transformFlightJoined = func() {} })
+
+	// The first caller starts the shared call, then gives up after 20ms
+	impatient := make(chan *httptest.ResponseRecorder)
+	go func() { impatient <- postTransform(`{"title": "Lift broken again", "timeoutMs": 20}`) }()
+	<-joined
+	patient := make(chan *httptest.ResponseRecorder)
+	go func() { patient <- postTransform(`{"title": "Lift broken again"}`) }()
+	<-joined
+
+	if rec := <-impatient; rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("impatient caller: status %d, want 504", rec.Code)
+	}
+	close(release)
+
+	rec := <-patient
+	var response TransformResponse
+	decodeJSON(t, rec, &response)
+	if rec.Code != http.StatusOK || response.TransformedContent != "Lift repaired ahead of schedule" {
+		t.Errorf("patient caller: status %d, response %+v", rec.Code, response)
+	}
+}
+
+func TestCacheStatsEndpoint(t *testing.T) {
+	openAI := newFakeOpenAI("Lift repaired ahead of schedule")
+	setupTest(t, upstreams(openAI, newsHandler(Article{Title: "Lift broken again"})))
+
+	do(newRequest(http.MethodGet, "/api/news/headlines", ""))
+	do(newRequest(http.MethodGet, "/api/news/headlines", ""))
+	postTransform(`{"title": "Lift broken again"}`)
+
+	var stats CacheStatsResponse
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/cache/stats", "")), &stats)
+	if stats.News.Hits != 1 || stats.News.Misses != 1 || stats.News.Entries == nil || *stats.News.Entries != 1 {
+		t.Errorf("news stats = %+v, want one miss, one hit and one entry", stats.News)
+	}
+	if stats.Transform.Misses < 1 || stats.Transform.Entries == nil || *stats.Transform.Entries != 1 {
+		t.Errorf("transform stats = %+v, want a miss and one entry", stats.Transform)
+	}
+
+	hits := stats.Transform.Hits
+	postTransform(`{"title": "Lift broken again"}`)
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/cache/stats", "")), &stats)
+	if stats.Transform.Hits != hits+1 {
+		t.Errorf("transform hits = %d after a repeat, want %d", stats.Transform.Hits, hits+1)
+	}
+}
+
+func TestPurgeTargets(t *testing.T) {
+	news, transform := NewMemoryCache(), NewMemoryCache()
+	for target, want := range map[string]int{"news": 1, "transform": 1, "all": 2} {
+		caches, err := purgeTargets(target, news, transform)
+		if err != nil || len(caches) != want {
+			t.Errorf("purgeTargets(%s) = %d caches, %v; want %d", target, len(caches), err, want)
+		}
+	}
+	if _, err := purgeTargets("everything", news, transform); err == nil {
+		t.Error("unknown target accepted, want an error")
+	}
+}
+
+func TestCachePurgeEndpoint(t *testing.T) {
+	setupTest(t, nil, "SERVICE_API_KEYS", "admin-key")
+	fill := func() {
+		ctx := context.Background()
+		newsCache.Set(ctx, "a", []byte("1"), time.Hour)
+		newsCache.Set(ctx, "b", []byte("2"), time.Hour)
+		transformCache.Set(ctx, "c", []byte("3"), time.Hour)
+	}
+
+	for target, want := range map[string]int{"news": 2, "transform": 1, "all": 3} {
+		newsCache.Purge(context.Background())
+		transformCache.Purge(context.Background())
+		fill()
+
+		rec := do(newRequest(http.MethodPost, "/api/cache/purge", `{"target": "`+target+`"}`, "X-API-Key", "admin-key"))
+		var response CachePurgeResponse
+		decodeJSON(t, rec, &response)
+		if rec.Code != http.StatusOK || response.Target != target || response.Evicted != want {
+			t.Errorf("%s: status %d, response %+v; want %d evicted", target, rec.Code, response, want)
+		}
+	}
+
+	rec := do(newRequest(http.MethodPost, "/api/cache/purge", `{"target": "everything"}`, "X-API-Key", "admin-key"))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown target: status = %d, want 400", rec.Code)
+	}
+
+	fill()
+	rec = do(newRequest(http.MethodPost, "/api/cache/purge", `{"target": "all"}`, "X-API-Key", "wrong"))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong key: status = %d, want 401", rec.Code)
+	}
+	if _, ok, _ := newsCache.Get(context.Background(), "a"); !ok {
+		t.Error("unauthorized purge cleared the cache")
+	}
+}
+
+func TestCachePurgeRequiresServiceKeys(t *testing.T) {
+	setupTest(t, nil, "SERVICE_API_KEYS", "")
+
+	rec := do(newRequest(http.MethodPost, "/api/cache/purge", `{"target": "all"}`))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d without SERVICE_API_KEYS, want 403", rec.Code)
+	}
+}
+
+func TestRedisCachePurge(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeRedis(t)
+	news, transform := NewRedisCache(client, "news:"), NewRedisCache(client, "transform:")
+	news.Set(ctx, "a", []byte("1"), time.Hour)
+	news.Set(ctx, "b", []byte("2"), time.Hour)
+	transform.Set(ctx, "a", []byte("3"), time.Hour)
+
+	if purged, err := news.Purge(ctx); err != nil || purged != 2 {
+		t.Errorf("Purge = %d, %v; want 2", purged, err)
+	}
+	if _, ok, _ := transform.Get(ctx, "a"); !ok {
+		t.Error("purging news cleared the transform cache")
+	}
+}
@@ -0,0 +1,84 @@
+package ministry
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Default category keywords, shared with the serverless handler
+//
+//go:embed api/categories.json
+var defaultCategoryKeywordsJSON []byte
+
+// Category reported when no keyword matches
+const fallbackCategory = "general"
+
+// Keyword heuristic that sorts articles into NewsAPI categories
+type CategoryClassifier struct {
+	keywords map[string][]string // keyword -> categories it counts towards
+}
+
+// Load the keyword map from a JSON file of category -> keywords, or the embedded default when path is empty
+func loadCategoryClassifier(path string) (*CategoryClassifier, error) {
+	data := defaultCategoryKeywordsJSON
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read category keywords: %v", err)
+		}
+	}
+
+	var categories map[string][]string
+	if err := json.Unmarshal(data, &categories); err != nil {
+		return nil, fmt.Errorf("failed to parse category keywords: %v", err)
+	}
+	if len(categories) == 0 {
+		return nil, fmt.Errorf("category keywords are empty")
+	}
+
+	keywords := make(map[string][]string)
+	for category, words := range categories {
+		if !validCategory(category) {
+			return nil, fmt.Errorf("category keywords: invalid category %q; allowed values: %s", category, strings.Join(newsCategories, ", "))
+		}
+		for _, word := range words {
+			word = strings.ToLower(strings.TrimSpace(word))
+			keywords[word] = append(keywords[word], category)
+		}
+	}
+	return &CategoryClassifier{keywords: keywords}, nil
+}
+
+// Category with the most keyword hits in text; ties go to the category
+// listed first in newsCategories, and no hits means "general"
+func (c *CategoryClassifier) Classify(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	hits := make(map[string]int)
+	for _, word := range words {
+		for _, category := range c.keywords[word] {
+			hits[category]++
+		}
+	}
+
+	best := fallbackCategory
+	for _, category := range newsCategories {
+		if hits[category] > hits[best] {
+			best = category
+		}
+	}
+	return best
+}
+
+// Set InferredCategory on every article from its title and description
+func (c *CategoryClassifier) Tag(articles []Article) {
+	for i := range articles {
+		articles[i].InferredCategory = c.Classify(articles[i].Title + " " + articles[i].Description)
+	}
+}
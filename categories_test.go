@@ -0,0 +1,86 @@
+package ministry
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClassifyHeadlines(t *testing.T) {
+	classifier, err := loadCategoryClassifier("")
+	if err != nil {
+		t.Fatalf("loadCategoryClassifier: %v", err)
+	}
+
+	tests := map[string]string{
+		"Striker scores late goal as league champions win the cup":  "sports",
+		"Apple unveils new iPhone chip as smartphone sales slow":    "technology",
+		"Hospitals brace for flu outbreak as doctors urge vaccines": "health",
+		"NASA telescope spots galaxy older than thought":            "science",
+		"Ministry of Love announces renovations to Room 101":        fallbackCategory,
+		"STRIKER SCORES": "sports",
+	}
+	for text, want := range tests {
+		if got := classifier.Classify(text); got != want {
+			t.Errorf("Classify(%q) = %q, want %q", text, got, want)
+		}
+	}
+
+	// Ties go to the category listed first
+	if got := classifier.Classify("market goal"); got != "business" {
+		t.Errorf("Classify on a tie = %q, want business", got)
+	}
+}
+
+func TestLoadCategoryClassifierFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "categories.json")
+	os.WriteFile(path, []byte(`{"sports": ["Thoughtpolice"]}`), 0o644)
+
+	classifier, err := loadCategoryClassifier(path)
+	if err != nil {
+		t.Fatalf("loadCategoryClassifier: %v", err)
+	}
+	if got := classifier.Classify("Thoughtpolice win again"); got != "sports" {
+		t.Errorf("Classify = %q, want the file's keywords used", got)
+	}
+	if got := classifier.Classify("Striker scores late goal"); got != fallbackCategory {
+		t.Errorf("Classify = %q, want the default keywords replaced", got)
+	}
+
+	for contents, want := range map[string]string{
+		`not json`:               "parse",
+		`{}`:                     "empty",
+		`{"gossip": ["rumour"]}`: "invalid category",
+	} {
+		os.WriteFile(path, []byte(contents), 0o644)
+		if _, err := loadCategoryClassifier(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("keywords %s: error = %v, want it to mention %q", contents, err, want)
+		}
+	}
+}
+
+func TestHeadlinesInferCategory(t *testing.T) {
+	news := newFakeNews(
+		Article{Title: "Striker scores late goal", URL: "https://minitrue.example/1"},
+		Article{Title: "New smartphone app for iPhone users", URL: "https://minitrue.example/2"},
+		Article{Title: "Vaccine trial begins", Description: "Doctors hopeful", URL: "https://minitrue.example/3"},
+	)
+	setupTest(t, upstreams(nil, news))
+
+	var response NewsResponse
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/news/headlines?inferCategory=true", "")), &response)
+	var got []string
+	for _, article := range response.Articles {
+		got = append(got, article.InferredCategory)
+	}
+	if strings.Join(got, ",") != "sports,technology,health" {
+		t.Errorf("inferred categories = %q", got)
+	}
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines?category=health", ""))
+	if strings.Contains(rec.Body.String(), "inferredCategory") {
+		t.Error("articles were tagged without inferCategory")
+	}
+}
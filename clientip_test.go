@@ -0,0 +1,78 @@
+package ministry
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := parseTrustedProxies(" 10.0.0.0/8, 192.168.1.7 ,, ::ffff:172.16.0.1, fd00::/8, 10.1.2.3/16")
+	if err != nil {
+		t.Fatalf("parseTrustedProxies: %v", err)
+	}
+	want := []string{"10.0.0.0/8", "192.168.1.7/32", "172.16.0.1/32", "fd00::/8", "10.1.0.0/16"}
+	if len(proxies) != len(want) {
+		t.Fatalf("proxies = %v, want %v", proxies, want)
+	}
+	for i, prefix := range proxies {
+		if prefix.String() != want[i] {
+			t.Errorf("proxy %d = %s, want %s", i, prefix, want[i])
+		}
+	}
+
+	for _, raw := range []string{"10.0.0.0/33", "proxy.internal", "10.0.0"} {
+		if _, err := parseTrustedProxies(raw); err == nil {
+			t.Errorf("parseTrustedProxies(%q) succeeded, want an error", raw)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    []string
+		want       string
+	}{
+		{"untrusted peer ignores headers", "203.0.113.9:5000", []string{"X-Forwarded-For", "198.51.100.1", "X-Real-IP", "198.51.100.2"}, "203.0.113.9"},
+		{"trusted peer without headers", "10.0.0.5:5000", nil, "10.0.0.5"},
+		{"trusted peer, one hop", "10.0.0.5:5000", []string{"X-Forwarded-For", "198.51.100.1"}, "198.51.100.1"},
+		{"spoofed leftmost hop is skipped", "10.0.0.5:5000", []string{"X-Forwarded-For", "1.2.3.4, 198.51.100.1, 10.0.0.9"}, "198.51.100.1"},
+		{"repeated headers join", "10.0.0.5:5000", []string{"X-Forwarded-For", "1.2.3.4", "X-Forwarded-For", "198.51.100.1"}, "198.51.100.1"},
+		{"every hop trusted", "10.0.0.5:5000", []string{"X-Forwarded-For", "10.0.0.7, 10.0.0.8"}, "10.0.0.7"},
+		{"malformed hop stops the walk", "10.0.0.5:5000", []string{"X-Forwarded-For", "198.51.100.1, junk, 10.0.0.8"}, "10.0.0.8"},
+		{"malformed header falls back to X-Real-IP", "10.0.0.5:5000", []string{"X-Forwarded-For", "junk", "X-Real-IP", "198.51.100.2"}, "198.51.100.2"},
+		{"malformed X-Real-IP falls back to the peer", "10.0.0.5:5000", []string{"X-Real-IP", "not-an-ip"}, "10.0.0.5"},
+		{"IPv4-mapped addresses are unmapped", "[::ffff:10.0.0.5]:5000", []string{"X-Forwarded-For", "::ffff:198.51.100.1"}, "198.51.100.1"},
+		{"IPv6 proxy", "[fd00::1]:5000", []string{"X-Forwarded-For", "2001:db8::1"}, "2001:db8::1"},
+		{"remote address without a port", "203.0.113.9", nil, "203.0.113.9"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+		r.RemoteAddr = test.remoteAddr
+		for i := 0; i+1 < len(test.headers); i += 2 {
+			r.Header.Add(test.headers[i], test.headers[i+1])
+		}
+		if got := clientIP(r, trusted); got != test.want {
+			t.Errorf("%s: clientIP = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestClientIPInLogs(t *testing.T) {
+	setupTest(t, nil, "TRUSTED_PROXIES", "192.0.2.0/24")
+	logs := captureLogs(t, slog.LevelInfo)
+
+	// httptest requests come from 192.0.2.1
+	do(newRequest(http.MethodGet, "/api/health", "", "X-Forwarded-For", "198.51.100.1"))
+
+	records := logRecords(t, logs, "request completed")
+	if len(records) != 1 || records[0]["clientIP"] != "198.51.100.1" {
+		t.Errorf("request logs = %v, want clientIP 198.51.100.1", records)
+	}
+}
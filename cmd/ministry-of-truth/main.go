@@ -0,0 +1,8 @@
+// Command ministry-of-truth runs the Ministry of Truth backend server
+package main
+
+import ministry "ministry-of-truth"
+
+func main() {
+	ministry.Main()
+}
@@ -0,0 +1,148 @@
+package ministry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Fetch a category's headlines and a keyword search concurrently and merge
+// them. Either may be empty to skip that half; a failure in either fails both.
+func combinedNews(ctx context.Context, provider NewsProvider, category, q string, pageSize int) (*NewsResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var headlines, search *NewsResponse
+	var headlinesErr, searchErr error
+
+	var wg sync.WaitGroup
+	if category != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			headlines, headlinesErr = provider.TopHeadlines(ctx, HeadlinesQuery{Country: headlinesCountry, Category: category, PageSize: pageSize})
+			if headlinesErr != nil {
+				cancel()
+			}
+		}()
+	}
+	if q != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			search, searchErr = provider.Search(ctx, SearchQuery{Q: q, PageSize: pageSize})
+			if searchErr != nil {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if headlinesErr != nil {
+		return nil, fmt.Errorf("category %s: %w", category, headlinesErr)
+	}
+	if searchErr != nil {
+		return nil, fmt.Errorf("search: %w", searchErr)
+	}
+	return mergeCombined(headlines, search), nil
+}
+
+// Union of headlines and search results, headlines first. Articles are
+// deduplicated by URL and tagged with where they were found; one found by
+// both keeps its first position and gets both tags.
+func mergeCombined(headlines, search *NewsResponse) *NewsResponse {
+	merged := &NewsResponse{Status: "ok", Articles: []Article{}}
+	index := make(map[string]int)
+
+	add := func(result *NewsResponse, tag func(article *Article)) {
+		if result == nil {
+			return
+		}
+		merged.Stale = merged.Stale || result.Stale
+		for _, article := range result.Articles {
+			if i, seen := index[article.URL]; seen && article.URL != "" {
+				tag(&merged.Articles[i])
+				continue
+			}
+			tag(&article)
+			index[article.URL] = len(merged.Articles)
+			merged.Articles = append(merged.Articles, article)
+		}
+	}
+	add(headlines, func(article *Article) { article.FromCategory = true })
+	add(search, func(article *Article) { article.MatchedQuery = true })
+
+	merged.TotalResults = len(merged.Articles)
+	return merged
+}
+
+// Headlines for a category merged with a keyword search, deduplicated by URL
+func getCombinedNews(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+
+	format, err := responseFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fields, err := parseArticleFields(query.Get("fields"), format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := parseArticleSort(query.Get("sort"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	category := strings.ToLower(query.Get("category"))
+	if category != "" {
+		if err := checkCategory(category, currentConfig().AllowedCategories); err != nil {
+			http.Error(w, err.Error(), queryErrorStatus(err))
+			return
+		}
+	}
+	q := query.Get("q")
+	if strings.TrimSpace(q) != "" {
+		if q, err = normalizeSearchQuery(q); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		q = ""
+	}
+	if category == "" && q == "" {
+		http.Error(w, "At least one of 'category' or 'q' is required", http.StatusBadRequest)
+		return
+	}
+
+	pageSize, err := pageSizeParam(query, currentConfig())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	newsResponse, err := combinedNews(r.Context(), currentNewsProvider(), category, q, pageSize)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error fetching combined news", "error", err)
+		http.Error(w, fmt.Sprintf("Error fetching news: %v", err), newsErrorStatus(err))
+		return
+	}
+	truncateArticles(newsResponse, currentConfig().MaxArticles)
+
+	// ?inferCategory=true tags each article with a keyword-based category
+	if infer, _ := strconv.ParseBool(query.Get("inferCategory")); infer {
+		currentConfig().Categories.Tag(newsResponse.Articles)
+	}
+
+	sortArticles(newsResponse.Articles, order)
+	writeNewsResponse(w, r, format, fields, newsResponse)
+}
@@ -0,0 +1,140 @@
+package ministry
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMergeCombined(t *testing.T) {
+	headlines := &NewsResponse{Articles: []Article{
+		{Title: "Lift broken again", URL: "https://example.com/lift"},
+		{Title: "Rations cut", URL: "https://example.com/rations"},
+		{Title: "Untracked bulletin"},
+	}}
+	search := &NewsResponse{Stale: true, Articles: []Article{
+		{Title: "Rations cut (search copy)", URL: "https://example.com/rations"},
+		{Title: "Chocolate ration raised", URL: "https://example.com/chocolate"},
+		{Title: "Another untracked bulletin"},
+	}}
+
+	merged := mergeCombined(headlines, search)
+	want := []struct {
+		title                      string
+		fromCategory, matchedQuery bool
+	}{
+		{"Lift broken again", true, false},
+		{"Rations cut", true, true},
+		{"Untracked bulletin", true, false},
+		{"Chocolate ration raised", false, true},
+		// Articles without a URL can't be matched up, so both are kept
+		{"Another untracked bulletin", false, true},
+	}
+	if len(merged.Articles) != len(want) || merged.TotalResults != len(want) || !merged.Stale {
+		t.Fatalf("merged = %+v", merged)
+	}
+	for i, article := range merged.Articles {
+		if article.Title != want[i].title || article.FromCategory != want[i].fromCategory || article.MatchedQuery != want[i].matchedQuery {
+			t.Errorf("article %d = %q fromCategory %v matchedQuery %v, want %+v", i, article.Title, article.FromCategory, article.MatchedQuery, want[i])
+		}
+	}
+
+	if merged := mergeCombined(nil, nil); merged.Articles == nil || len(merged.Articles) != 0 {
+		t.Errorf("merging nothing = %+v, want an empty list", merged)
+	}
+}
+
+func combinedProvider() *categoryProvider {
+	return &categoryProvider{
+		fakeProvider: fakeProvider{Articles: []Article{
+			{Title: "Rations cut", URL: "https://example.com/rations"},
+			{Title: "Chocolate ration raised", URL: "https://example.com/chocolate"},
+		}},
+		byCategory: map[string][]Article{"business": {
+			{Title: "Lift broken again", URL: "https://example.com/lift"},
+			{Title: "Rations cut", URL: "https://example.com/rations"},
+		}},
+	}
+}
+
+func TestCombinedNews(t *testing.T) {
+	setupTest(t, nil)
+	provider := combinedProvider()
+	useNewsProvider(t, provider)
+
+	rec := do(newRequest(http.MethodGet, "/api/news/combined?category=Business&q=rations", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var response NewsResponse
+	decodeJSON(t, rec, &response)
+	var got []string
+	for _, article := range response.Articles {
+		got = append(got, fmt.Sprintf("%s %v %v", article.Title, article.FromCategory, article.MatchedQuery))
+	}
+	want := "Lift broken again true false|Rations cut true true|Chocolate ration raised false true"
+	if strings.Join(got, "|") != want {
+		t.Errorf("articles = %q, want %q", got, want)
+	}
+	if searches := provider.Searches(); len(searches) != 1 || searches[0].Q != "rations" {
+		t.Errorf("searches = %+v", searches)
+	}
+	if categories := provider.Categories(); len(categories) != 1 || categories[0] != "business" {
+		t.Errorf("categories = %q", categories)
+	}
+}
+
+func TestCombinedNewsSingleSource(t *testing.T) {
+	setupTest(t, nil)
+
+	t.Run("category only", func(t *testing.T) {
+		provider := combinedProvider()
+		useNewsProvider(t, provider)
+
+		var response NewsResponse
+		decodeJSON(t, do(newRequest(http.MethodGet, "/api/news/combined?category=business&q=%20", "")), &response)
+		if len(response.Articles) != 2 || response.Articles[1].MatchedQuery || len(provider.Searches()) != 0 {
+			t.Errorf("articles = %+v after %d searches, want only the category", response.Articles, len(provider.Searches()))
+		}
+	})
+
+	t.Run("query only", func(t *testing.T) {
+		provider := combinedProvider()
+		useNewsProvider(t, provider)
+
+		var response NewsResponse
+		decodeJSON(t, do(newRequest(http.MethodGet, "/api/news/combined?q=rations", "")), &response)
+		if len(response.Articles) != 2 || response.Articles[0].FromCategory || len(provider.Headlines()) != 0 {
+			t.Errorf("articles = %+v after %d headline fetches, want only the search", response.Articles, len(provider.Headlines()))
+		}
+	})
+}
+
+func TestCombinedNewsErrors(t *testing.T) {
+	setupTest(t, nil)
+	provider := combinedProvider()
+	useNewsProvider(t, provider)
+
+	for target, want := range map[string]string{
+		"/api/news/combined":                      "At least one of 'category' or 'q'",
+		"/api/news/combined?category=gossip":      "gossip",
+		"/api/news/combined?q=%22lift":            "unbalanced",
+		"/api/news/combined?q=lift&sort=sideways": "Invalid sort",
+	} {
+		rec := do(newRequest(http.MethodGet, target, ""))
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("%s: status = %d, body %q; want 400 mentioning %q", target, rec.Code, rec.Body.String(), want)
+		}
+	}
+	if len(provider.Searches())+len(provider.Headlines()) != 0 {
+		t.Error("an invalid request reached the provider")
+	}
+
+	// Either half failing fails the request
+	provider.Err = fmt.Errorf("%w: NewsAPI returned status 503", ErrNewsUnavailable)
+	rec := do(newRequest(http.MethodGet, "/api/news/combined?category=business&q=rations", ""))
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "Error fetching news") {
+		t.Errorf("status = %d, body %q with the provider down; want the usual news error", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,177 @@
+package ministry
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ResponseWriter that gzips the body once it grows past minSize bytes.
+// Smaller bodies, event streams and already-encoded responses pass through untouched.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize     int
+	status      int
+	buf         []byte
+	gz          *gzip.Writer
+	decided     bool
+	wroteHeader bool
+}
+
+func newGzipResponseWriter(w http.ResponseWriter, minSize int) *gzipResponseWriter {
+	w.Header().Add("Vary", "Accept-Encoding")
+	return &gzipResponseWriter{ResponseWriter: w, minSize: minSize}
+}
+
+// Whether the client accepts gzip-encoded responses
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		fields := strings.Split(part, ";")
+		if strings.TrimSpace(fields[0]) != "gzip" {
+			continue
+		}
+
+		// "gzip;q=0" explicitly refuses gzip
+		for _, param := range fields[1:] {
+			if q, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.status == 0 {
+		g.status = status
+	}
+}
+
+// Responses that must not be compressed
+func (g *gzipResponseWriter) skipCompression() bool {
+	header := g.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return true
+	}
+	if g.status == http.StatusNoContent || g.status == http.StatusNotModified || g.status == http.StatusPartialContent {
+		return true
+	}
+
+	contentType := header.Get("Content-Type")
+	for _, prefix := range []string{"text/event-stream", "image/", "video/", "audio/", "application/zip", "application/gzip"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *gzipResponseWriter) writeHeaderOnce() {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+
+	status := g.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	g.ResponseWriter.WriteHeader(status)
+}
+
+// Switch to gzip for the rest of the response
+func (g *gzipResponseWriter) startGzip() {
+	g.decided = true
+	g.Header().Set("Content-Encoding", "gzip")
+	g.Header().Del("Content-Length")
+	g.writeHeaderOnce()
+	g.gz = gzip.NewWriter(g.ResponseWriter)
+}
+
+// Write the response as-is for the rest of the response
+func (g *gzipResponseWriter) startPassthrough() error {
+	g.decided = true
+	g.writeHeaderOnce()
+	if len(g.buf) == 0 {
+		return nil
+	}
+	_, err := g.ResponseWriter.Write(g.buf)
+	g.buf = nil
+	return err
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !g.decided {
+		if g.skipCompression() {
+			if err := g.startPassthrough(); err != nil {
+				return 0, err
+			}
+			return g.ResponseWriter.Write(b)
+		}
+
+		g.buf = append(g.buf, b...)
+		if len(g.buf) < g.minSize {
+			return len(b), nil
+		}
+
+		g.startGzip()
+		buffered := g.buf
+		g.buf = nil
+		if _, err := g.gz.Write(buffered); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if g.gz != nil {
+		return g.gz.Write(b)
+	}
+	return g.ResponseWriter.Write(b)
+}
+
+func (g *gzipResponseWriter) Flush() {
+	if !g.decided {
+		g.startPassthrough()
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if flusher, ok := g.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// Write any buffered body and terminate the gzip stream
+func (g *gzipResponseWriter) Close() error {
+	if !g.decided {
+		return g.startPassthrough()
+	}
+	if g.gz != nil {
+		return g.gz.Close()
+	}
+	return nil
+}
+
+// Gzip responses for clients that accept it
+func gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Not deferred: after a panic the buffered body is dropped, leaving
+		// the response to recoveryMiddleware
+		gw := newGzipResponseWriter(w, currentConfig().GzipMinBytes)
+		next.ServeHTTP(gw, r)
+		gw.Close()
+	})
+}
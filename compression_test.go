@@ -0,0 +1,89 @@
+package ministry
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := map[string]bool{
+		"":                    false,
+		"gzip":                true,
+		"deflate, gzip;q=0.5": true,
+		"br, deflate":         false,
+		"gzip;q=0":            false,
+		"gzipper":             false,
+	}
+	for header, want := range tests {
+		req := newRequest(http.MethodGet, "/", "", "Accept-Encoding", header)
+		if got := acceptsGzip(req); got != want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+// Run handler behind gzipMiddleware for a client accepting gzip
+func gzipped(handler http.HandlerFunc) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	gzipMiddleware(handler).ServeHTTP(rec, newRequest(http.MethodGet, "/", "", "Accept-Encoding", "gzip"))
+	return rec
+}
+
+func TestGzipMiddlewareCompressesLargeBodies(t *testing.T) {
+	setupTest(t, nil, "GZIP_MIN_BYTES", "64")
+	body := strings.Repeat("War is peace. ", 20)
+
+	rec := gzipped(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, body)
+	})
+	if rec.Code != http.StatusCreated {
+		t.Errorf("status = %d, want the handler's 201", rec.Code)
+	}
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("headers = %v, want gzip encoding varying on Accept-Encoding", rec.Header())
+	}
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	if got, _ := io.ReadAll(gz); string(got) != body {
+		t.Errorf("decompressed body = %q", got)
+	}
+}
+
+func TestGzipMiddlewarePassesThrough(t *testing.T) {
+	setupTest(t, nil, "GZIP_MIN_BYTES", "64")
+
+	// Bodies under the minimum
+	rec := gzipped(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "short")
+	})
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != "short" {
+		t.Errorf("small body: encoding %q, body %q", rec.Header().Get("Content-Encoding"), rec.Body.String())
+	}
+
+	// Event streams, however long
+	long := strings.Repeat("data: x\n\n", 20)
+	rec = gzipped(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, long)
+	})
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != long {
+		t.Errorf("event stream: encoding %q", rec.Header().Get("Content-Encoding"))
+	}
+
+	// Clients that don't accept gzip
+	rec = httptest.NewRecorder()
+	gzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, long)
+	})).ServeHTTP(rec, newRequest(http.MethodGet, "/", ""))
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != long {
+		t.Errorf("no Accept-Encoding: encoding %q", rec.Header().Get("Content-Encoding"))
+	}
+}
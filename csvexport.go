@@ -0,0 +1,53 @@
+package ministry
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// Column headers for the headlines CSV export
+var headlineCSVHeader = []string{"source", "author", "title", "url", "publishedAt"}
+
+// Write articles as RFC 4180 CSV (CRLF line endings, quoted fields where needed)
+func writeHeadlinesCSV(out io.Writer, articles []Article) error {
+	writer := csv.NewWriter(out)
+	writer.UseCRLF = true
+
+	if err := writer.Write(headlineCSVHeader); err != nil {
+		return err
+	}
+	for _, article := range articles {
+		record := []string{article.Source.Name, article.Author, article.Title, article.URL, article.PublishedAt}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// Headlines CSV export endpoint
+func getHeadlinesCSV(w http.ResponseWriter, r *http.Request) {
+	newsQuery, err := headlinesQuery(r.URL.Query(), currentConfig())
+	if err != nil {
+		http.Error(w, err.Error(), queryErrorStatus(err))
+		return
+	}
+
+	newsResponse, err := fetchTopHeadlines(r.Context(), currentNewsProvider(), newsQuery)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error fetching news", "error", err)
+		http.Error(w, fmt.Sprintf("Error fetching news: %v", err), newsErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="headlines.csv"`)
+	if err := writeHeadlinesCSV(w, newsResponse.Articles); err != nil {
+		slog.ErrorContext(r.Context(), "error writing headlines CSV", "error", err)
+	}
+}
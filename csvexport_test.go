@@ -0,0 +1,48 @@
+package ministry
+
+import (
+	"encoding/csv"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHeadlinesCSV(t *testing.T) {
+	setupTest(t, upstreams(nil, newsHandler(
+		Article{Source: Source{Name: "The Times"}, Author: "Winston Smith", Title: "Rations cut, again", URL: "https://example.com/rations", PublishedAt: "2024-03-01T12:30:00Z"},
+		Article{Source: Source{Name: "Minitrue"}, Title: "The \"best\" year\nyet", URL: "https://example.com/best"},
+	)))
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines.csv", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="headlines.csv"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if !strings.HasSuffix(rec.Body.String(), "\r\n") {
+		t.Error("rows don't end with CRLF")
+	}
+
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("CSV doesn't parse: %v", err)
+	}
+	want := [][]string{
+		headlineCSVHeader,
+		{"The Times", "Winston Smith", "Rations cut, again", "https://example.com/rations", "2024-03-01T12:30:00Z"},
+		{"Minitrue", "", "The \"best\" year\nyet", "https://example.com/best", ""},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("rows = %q, want %q", rows, want)
+	}
+	for i := range want {
+		if !slices.Equal(rows[i], want[i]) {
+			t.Errorf("row %d = %q, want %q", i, rows[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package ministry
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Derive the key that signs search cursors: CURSOR_SECRET when set, else
+// a hash of the NewsAPI key so every instance agrees without extra config
+func cursorSecret(secret, newsAPIKey string) []byte {
+	if secret != "" {
+		return []byte(secret)
+	}
+	sum := sha256.Sum256([]byte("search-cursor:" + newsAPIKey))
+	return sum[:]
+}
+
+// Encode search parameters (filters and page) as an opaque signed cursor
+func encodeSearchCursor(params url.Values, secret []byte) string {
+	payload := []byte(params.Encode())
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Decode a cursor made by encodeSearchCursor, rejecting any that were altered
+func decodeSearchCursor(cursor string, secret []byte) (url.Values, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, fmt.Errorf("Invalid cursor")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid cursor")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid cursor")
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, fmt.Errorf("Invalid cursor")
+	}
+
+	params, err := url.ParseQuery(string(payload))
+	if err != nil || params.Get("q") == "" {
+		return nil, fmt.Errorf("Invalid cursor")
+	}
+	if page, err := strconv.Atoi(params.Get("page")); err != nil || page < 1 {
+		return nil, fmt.Errorf("Invalid cursor")
+	}
+	return params, nil
+}
+
+// Resolve the search parameters for a request, continuing from ?cursor= when given
+func searchPosition(query url.Values, cfg *Config) (url.Values, error) {
+	if cursor := query.Get("cursor"); cursor != "" {
+		return decodeSearchCursor(cursor, cfg.CursorSecret)
+	}
+
+	params, err := searchParams(query)
+	if err != nil {
+		return nil, err
+	}
+	pageSize, err := pageSizeParam(query, cfg)
+	if err != nil {
+		return nil, err
+	}
+	params.Set("page", "1")
+	params.Set("pageSize", strconv.Itoa(pageSize))
+	return params, nil
+}
+
+// Cursor for the page after params, or "" when totalResults has been reached
+func nextSearchCursor(params url.Values, totalResults int, secret []byte) string {
+	page, _ := strconv.Atoi(params.Get("page"))
+	pageSize, _ := strconv.Atoi(params.Get("pageSize"))
+	if pageSize <= 0 || page*pageSize >= totalResults {
+		return ""
+	}
+
+	next := url.Values{}
+	for key, values := range params {
+		next[key] = values
+	}
+	next.Set("page", strconv.Itoa(page+1))
+	return encodeSearchCursor(next, secret)
+}
@@ -0,0 +1,78 @@
+package ministry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSearchCursorRoundTrip(t *testing.T) {
+	secret := cursorSecret("", "test-news-key")
+	params := url.Values{"q": {"chocolate"}, "domains": {"bbc.co.uk"}, "page": {"2"}, "pageSize": {"10"}}
+
+	decoded, err := decodeSearchCursor(encodeSearchCursor(params, secret), secret)
+	if err != nil || decoded.Encode() != params.Encode() {
+		t.Errorf("decoded = %v, %v; want %v", decoded, err, params)
+	}
+
+	// Another instance with the same NewsAPI key accepts it; another secret doesn't
+	if _, err := decodeSearchCursor(encodeSearchCursor(params, secret), cursorSecret("", "test-news-key")); err != nil {
+		t.Errorf("cursor rejected with the same derived secret: %v", err)
+	}
+	if _, err := decodeSearchCursor(encodeSearchCursor(params, secret), cursorSecret("other", "test-news-key")); err == nil {
+		t.Error("cursor accepted with a different secret")
+	}
+}
+
+func TestNextSearchCursor(t *testing.T) {
+	secret := []byte("secret")
+	params := url.Values{"q": {"chocolate"}, "page": {"1"}, "pageSize": {"2"}}
+
+	next, err := decodeSearchCursor(nextSearchCursor(params, 5, secret), secret)
+	if err != nil || next.Get("page") != "2" || next.Get("q") != "chocolate" {
+		t.Errorf("next = %v, %v; want page 2 of the same search", next, err)
+	}
+	params.Set("page", "3")
+	if cursor := nextSearchCursor(params, 5, secret); cursor != "" {
+		t.Errorf("cursor after the last page = %q, want none", cursor)
+	}
+}
+
+func TestSearchWalksPagesByCursor(t *testing.T) {
+	var pages []string
+	upstream := http.NewServeMux()
+	upstream.HandleFunc(newsSearchPath, func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+		json.NewEncoder(w).Encode(NewsResponse{Status: "ok", TotalResults: 3, Articles: []Article{{Title: "page " + page}}})
+	})
+	setupTest(t, upstream)
+
+	rec := do(newRequest(http.MethodGet, "/api/news/search?q=chocolate&pageSize=2", ""))
+	var first NewsResponse
+	decodeJSON(t, rec, &first)
+	if first.NextCursor == "" {
+		t.Fatalf("first page has no nextCursor: %q", rec.Body.String())
+	}
+
+	rec = do(newRequest(http.MethodGet, "/api/news/search?cursor="+url.QueryEscape(first.NextCursor), ""))
+	var second NewsResponse
+	decodeJSON(t, rec, &second)
+	if len(second.Articles) != 1 || second.Articles[0].Title != "page 2" || second.NextCursor != "" {
+		t.Errorf("second page = %+v, want the last page", second)
+	}
+	if strings.Join(pages, ",") != "1,2" {
+		t.Errorf("NewsAPI pages = %q, want 1,2", pages)
+	}
+
+	// Altering either half of the cursor is rejected
+	payload, signature, _ := strings.Cut(first.NextCursor, ".")
+	for _, tampered := range []string{payload + "x." + signature, payload + "." + signature[1:], "garbage"} {
+		rec = do(newRequest(http.MethodGet, "/api/news/search?cursor="+url.QueryEscape(tampered), ""))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("tampered cursor %q: status = %d, want 400", tampered, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package ministry
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"unicode"
+)
+
+// Prompt addition asking the model to leave names alone
+const preserveEntitiesInstruction = "Keep every proper noun (people, places, organisations, products) exactly as written in the original."
+
+// Share of capitalized words above which a text is treated as Title Case
+// and skipped, since capitalization says nothing about names there
+const titleCaseRatio = 0.6
+
+// Proper nouns in texts by capitalization: capitalized or all-caps words,
+// except a sentence's first word when it is a common word. Title-cased texts
+// (most headlines) are skipped. Terms from extra are kept when present.
+func extractEntities(texts []string, stopwords map[string]bool, extra []string) []string {
+	var entities []string
+	seen := make(map[string]bool)
+	add := func(term string) {
+		if key := strings.ToLower(term); !seen[key] {
+			seen[key] = true
+			entities = append(entities, term)
+		}
+	}
+
+	for _, text := range texts {
+		words := strings.Fields(text)
+		capitalized := 0
+		for _, word := range words {
+			if startsUpper(strings.TrimLeftFunc(word, unicode.IsPunct)) {
+				capitalized++
+			}
+		}
+		if len(words) == 0 || float64(capitalized)/float64(len(words)) > titleCaseRatio {
+			continue
+		}
+
+		sentenceStart := true
+		for _, word := range words {
+			term := strings.TrimFunc(word, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+			})
+			term = strings.TrimSuffix(strings.TrimSuffix(term, "'s"), "’s")
+			if len([]rune(term)) >= 2 && startsUpper(term) && !(sentenceStart && stopwords[strings.ToLower(term)]) {
+				add(term)
+			}
+			sentenceStart = strings.ContainsAny(word[len(word)-1:], ".!?")
+		}
+	}
+
+	original := strings.ToLower(strings.Join(texts, " "))
+	for _, term := range extra {
+		if term != "" && strings.Contains(original, strings.ToLower(term)) {
+			add(term)
+		}
+	}
+	return entities
+}
+
+func startsUpper(word string) bool {
+	for _, r := range word {
+		return unicode.IsUpper(r)
+	}
+	return false
+}
+
+// Entities that don't appear (case-insensitively) in text
+func missingEntities(entities []string, text string) []string {
+	lower := strings.ToLower(text)
+	var missing []string
+	for _, entity := range entities {
+		if !strings.Contains(lower, strings.ToLower(entity)) {
+			missing = append(missing, entity)
+		}
+	}
+	return missing
+}
+
+// Re-request once when transformed dropped any of entities, keeping the retry
+// if it misses fewer. Returns the chosen text, the entities it still misses
+// and whether the retry was used.
+func ensureEntities(ctx context.Context, t Transformer, title, description, transformed string, entities []string) (string, []string, bool) {
+	missing := missingEntities(entities, transformed)
+	if len(missing) == 0 {
+		return transformed, nil, false
+	}
+
+	slog.InfoContext(ctx, "transform dropped named entities, retrying", "missing", missing)
+	retried, err := t.Transform(ctx, title, description)
+	if err != nil {
+		slog.ErrorContext(ctx, "transform retry error", "error", err)
+		return transformed, missing, false
+	}
+	if stillMissing := missingEntities(entities, retried); len(stillMissing) < len(missing) {
+		return retried, stillMissing, true
+	}
+	return transformed, missing, false
+}
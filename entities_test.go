@@ -0,0 +1,134 @@
+package ministry
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractEntities(t *testing.T) {
+	stopwords := map[string]bool{"the": true, "today": true}
+	tests := []struct {
+		texts []string
+		extra []string
+		want  []string
+	}{
+		{[]string{"Winston Smith met O'Brien at the Ministry in London."}, nil, []string{"Winston", "Smith", "O'Brien", "Ministry", "London"}},
+		// A common word opening a sentence isn't a name, but other first words are
+		{[]string{"Today the lift broke. The NHS said Julia's flat was fine."}, nil, []string{"NHS", "Julia"}},
+		{[]string{"Oceania has always been at war. Eurasia was never mentioned."}, nil, []string{"Oceania", "Eurasia"}},
+		// Title Case headlines say nothing about names
+		{[]string{"Chocolate Ration Raised To Twenty Grammes"}, nil, nil},
+		{[]string{"rations cut again in airstrip one", "Big Brother is watching"}, []string{"airstrip one", "Goldstein"}, []string{"Big", "Brother", "airstrip one"}},
+		// Repeats are reported once, whatever their case
+		{[]string{"London and london and LONDON"}, nil, []string{"London"}},
+	}
+	for _, test := range tests {
+		if got := extractEntities(test.texts, stopwords, test.extra); !slices.Equal(got, test.want) {
+			t.Errorf("extractEntities(%q) = %q, want %q", test.texts, got, test.want)
+		}
+	}
+}
+
+func TestMissingEntities(t *testing.T) {
+	got := missingEntities([]string{"Winston", "London", "Julia"}, "WINSTON visited london")
+	if !slices.Equal(got, []string{"Julia"}) {
+		t.Errorf("missingEntities = %q, want [Julia]", got)
+	}
+}
+
+// Transformer returning replies in turn
+type scriptedTransformer struct {
+	replies []string
+	err     error
+	calls   int
+}
+
+func (s *scriptedTransformer) Transform(ctx context.Context, title, description string) (string, error) {
+	s.calls++
+	if s.err != nil {
+		return "", s.err
+	}
+	return s.replies[min(s.calls, len(s.replies))-1], nil
+}
+
+func TestEnsureEntities(t *testing.T) {
+	entities := []string{"Winston", "London"}
+	ctx := context.Background()
+
+	t.Run("kept", func(t *testing.T) {
+		transformer := &scriptedTransformer{}
+		got, missing, retried := ensureEntities(ctx, transformer, "", "", "Winston loves London", entities)
+		if got != "Winston loves London" || missing != nil || retried || transformer.calls != 0 {
+			t.Errorf("got %q, missing %q, retried %v after %d calls", got, missing, retried, transformer.calls)
+		}
+	})
+
+	t.Run("retry fixes it", func(t *testing.T) {
+		transformer := &scriptedTransformer{replies: []string{"Winston loves London"}}
+		got, missing, retried := ensureEntities(ctx, transformer, "", "", "Comrade loves the capital", entities)
+		if got != "Winston loves London" || missing != nil || !retried || transformer.calls != 1 {
+			t.Errorf("got %q, missing %q, retried %v after %d calls", got, missing, retried, transformer.calls)
+		}
+	})
+
+	t.Run("retry no better", func(t *testing.T) {
+		transformer := &scriptedTransformer{replies: []string{"Comrade loves London"}}
+		got, missing, retried := ensureEntities(ctx, transformer, "", "", "Winston loves the capital", entities)
+		if got != "Winston loves the capital" || !slices.Equal(missing, []string{"London"}) || retried {
+			t.Errorf("got %q, missing %q, retried %v", got, missing, retried)
+		}
+	})
+
+	t.Run("retry fails", func(t *testing.T) {
+		transformer := &scriptedTransformer{err: errors.New("model down")}
+		got, missing, retried := ensureEntities(ctx, transformer, "", "", "Winston loves the capital", entities)
+		if got != "Winston loves the capital" || !slices.Equal(missing, []string{"London"}) || retried {
+			t.Errorf("got %q, missing %q, retried %v", got, missing, retried)
+		}
+	})
+}
+
+func TestTransformPreserveEntities(t *testing.T) {
+	const body = `{"description": "Winston Smith was seen in London with Julia.", "preserveEntities": true}`
+
+	t.Run("re-requests when a name is dropped", func(t *testing.T) {
+		openAI := newFakeOpenAI("A comrade was seen in the capital with Julia.", "Winston Smith was seen in glorious London with Julia.")
+		setupTest(t, upstreams(openAI, nil))
+
+		var response TransformResponse
+		decodeJSON(t, postTransform(body), &response)
+		if openAI.Calls() != 2 {
+			t.Fatalf("model got %d calls, want a re-request", openAI.Calls())
+		}
+		if response.TransformedContent != "Winston Smith was seen in glorious London with Julia." || len(response.MissingEntities) != 0 {
+			t.Errorf("response = %+v", response)
+		}
+		if prompt := openAI.Requests()[0].Messages[0].Content; !strings.Contains(prompt, preserveEntitiesInstruction) {
+			t.Errorf("system prompt = %q, want the entity instruction", prompt)
+		}
+	})
+
+	t.Run("reports names still missing", func(t *testing.T) {
+		openAI := newFakeOpenAI("A comrade was seen in the capital with Julia.")
+		setupTest(t, upstreams(openAI, nil))
+
+		var response TransformResponse
+		decodeJSON(t, postTransform(body), &response)
+		if openAI.Calls() != 2 || !slices.Equal(response.MissingEntities, []string{"Winston", "Smith", "London"}) {
+			t.Errorf("%d calls, missingEntities %q", openAI.Calls(), response.MissingEntities)
+		}
+	})
+
+	t.Run("off by default", func(t *testing.T) {
+		openAI := newFakeOpenAI("A comrade was seen in the capital with Julia.")
+		setupTest(t, upstreams(openAI, nil))
+
+		postTransform(`{"description": "Winston Smith was seen in London with Julia."}`)
+		if openAI.Calls() != 1 || strings.Contains(openAI.Requests()[0].Messages[0].Content, preserveEntitiesInstruction) {
+			t.Error("entities were checked without preserveEntities")
+		}
+	})
+}
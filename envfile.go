@@ -0,0 +1,157 @@
+package ministry
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Variables set from the .env file rather than the real environment, which
+// a reload may overwrite or unset
+var envFileKeys = map[string]bool{}
+
+// Path of the .env file: ENV_FILE, default .env
+func envFilePath() string {
+	if path := os.Getenv("ENV_FILE"); path != "" {
+		return path
+	}
+	return ".env"
+}
+
+// Read the KEY=VALUE lines of a .env file. A missing file reads as empty.
+func readEnvFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		key, value, ok, err := parseEnvLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNumber, err)
+		}
+		if ok {
+			values[key] = value
+		}
+	}
+	return values, scanner.Err()
+}
+
+// Load KEY=VALUE lines from a .env file into the environment, leaving
+// variables that are already set untouched. A missing file is not an error.
+func loadEnvFile(path string) error {
+	values, err := readEnvFile(path)
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+		envFileKeys[key] = true
+	}
+	return nil
+}
+
+// Re-read a .env file on config reload. Variables that came from the file
+// take its new values, or are unset if the file dropped them; the real
+// environment still wins. The returned function puts everything back.
+func reloadEnvFile(path string) (func(), error) {
+	values, err := readEnvFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := map[string]*string{}
+	remember := func(key string) {
+		if _, seen := previous[key]; seen {
+			return
+		}
+		if value, set := os.LookupEnv(key); set {
+			previous[key] = &value
+		} else {
+			previous[key] = nil
+		}
+	}
+	previousKeys := maps.Clone(envFileKeys)
+	restore := func() {
+		for key, value := range previous {
+			if value == nil {
+				os.Unsetenv(key)
+			} else {
+				os.Setenv(key, *value)
+			}
+		}
+		envFileKeys = previousKeys
+	}
+
+	for key := range envFileKeys {
+		if _, kept := values[key]; !kept {
+			remember(key)
+			os.Unsetenv(key)
+			delete(envFileKeys, key)
+		}
+	}
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); set && !envFileKeys[key] {
+			continue
+		}
+		remember(key)
+		if err := os.Setenv(key, value); err != nil {
+			restore()
+			return nil, err
+		}
+		envFileKeys[key] = true
+	}
+	return restore, nil
+}
+
+// Parse one .env line, skipping blanks and comments. Supports an optional
+// "export " prefix, quoted values and trailing " #" comments on unquoted values.
+func parseEnvLine(line string) (key, value string, ok bool, err error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false, nil
+	}
+	line = strings.TrimPrefix(line, "export ")
+
+	key, value, found := strings.Cut(line, "=")
+	key = strings.TrimSpace(key)
+	if !found || key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", false, fmt.Errorf("expected KEY=VALUE")
+	}
+
+	value = strings.TrimSpace(value)
+	switch {
+	case strings.HasPrefix(value, `"`):
+		value, err = strconv.Unquote(value)
+		if err != nil {
+			return "", "", false, fmt.Errorf("invalid quoted value for %s", key)
+		}
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", "", false, fmt.Errorf("invalid quoted value for %s", key)
+		}
+		value = value[1 : len(value)-1]
+	default:
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+	}
+
+	return key, value, true, nil
+}
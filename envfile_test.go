@@ -0,0 +1,93 @@
+package ministry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseEnvLine(t *testing.T) {
+	tests := []struct {
+		line, key, value string
+		ok               bool
+	}{
+		{"", "", "", false},
+		{"# comment", "", "", false},
+		{"PORT=8080", "PORT", "8080", true},
+		{"export LOG_LEVEL = debug", "LOG_LEVEL", "debug", true},
+		{`SLOGAN="War is peace # not a comment"`, "SLOGAN", "War is peace # not a comment", true},
+		{"SLOGAN='Freedom is slavery'", "SLOGAN", "Freedom is slavery", true},
+		{"PORT=8080 # default", "PORT", "8080", true},
+		{"EMPTY=", "EMPTY", "", true},
+	}
+	for _, test := range tests {
+		key, value, ok, err := parseEnvLine(test.line)
+		if err != nil || key != test.key || value != test.value || ok != test.ok {
+			t.Errorf("parseEnvLine(%q) = %q, %q, %v, %v", test.line, key, value, ok, err)
+		}
+	}
+
+	for _, line := range []string{"NO_EQUALS", "=value", "TWO WORDS=x", `QUOTED="unterminated`, "SINGLE='open"} {
+		if _, _, _, err := parseEnvLine(line); err == nil {
+			t.Errorf("parseEnvLine(%q) succeeded, want an error", line)
+		}
+	}
+}
+
+// Unset key for the rest of the test, restoring it afterwards
+func unsetEnv(t *testing.T, key string) {
+	t.Helper()
+	t.Setenv(key, "")
+	os.Unsetenv(key)
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	previousKeys := envFileKeys
+	envFileKeys = map[string]bool{}
+	t.Cleanup(func() { envFileKeys = previousKeys })
+
+	path := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(path, []byte("# local settings\nMINISTRY_TEST_PORT=9090\nMINISTRY_TEST_LEVEL=debug\n"), 0o644)
+	unsetEnv(t, "MINISTRY_TEST_PORT")
+	t.Setenv("MINISTRY_TEST_LEVEL", "warn")
+
+	if err := loadEnvFile(path); err != nil {
+		t.Fatalf("loadEnvFile: %v", err)
+	}
+	if got := os.Getenv("MINISTRY_TEST_PORT"); got != "9090" {
+		t.Errorf("MINISTRY_TEST_PORT = %q, want it set from the file", got)
+	}
+	// The real environment wins
+	if got := os.Getenv("MINISTRY_TEST_LEVEL"); got != "warn" {
+		t.Errorf("MINISTRY_TEST_LEVEL = %q, want the environment's value kept", got)
+	}
+	if !envFileKeys["MINISTRY_TEST_PORT"] || envFileKeys["MINISTRY_TEST_LEVEL"] {
+		t.Errorf("envFileKeys = %v, want only the variable taken from the file", envFileKeys)
+	}
+}
+
+func TestLoadEnvFileMissing(t *testing.T) {
+	if err := loadEnvFile(filepath.Join(t.TempDir(), "missing.env")); err != nil {
+		t.Errorf("loadEnvFile on a missing file = %v, want no error", err)
+	}
+}
+
+func TestLoadEnvFileReportsBadLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(path, []byte("PORT=8080\nnonsense\n"), 0o644)
+
+	if err := loadEnvFile(path); err == nil || err.Error() != path+":2: expected KEY=VALUE" {
+		t.Errorf("loadEnvFile = %v, want the bad line named", err)
+	}
+}
+
+func TestEnvFilePath(t *testing.T) {
+	unsetEnv(t, "ENV_FILE")
+	if got := envFilePath(); got != ".env" {
+		t.Errorf("envFilePath() = %q, want .env", got)
+	}
+	t.Setenv("ENV_FILE", "/etc/ministry.env")
+	if got := envFilePath(); got != "/etc/ministry.env" {
+		t.Errorf("envFilePath() = %q, want ENV_FILE", got)
+	}
+}
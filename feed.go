@@ -0,0 +1,178 @@
+package ministry
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Number of articles transformed concurrently for feeds
+const feedTransformWorkers = 4
+
+// RSS 2.0 document structures
+type RSS struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel RSSChannel `xml:"channel"`
+}
+
+type RSSChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []RSSItem `xml:"item"`
+}
+
+type RSSItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description,omitempty"`
+	GUID        RSSGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+}
+
+type RSSGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// Transform every article's title/description with a bounded worker pool,
+// sharing the transform cache (and so the prewarmed headlines) with the
+// transform endpoint. Failed transforms fall back to the local transformer
+// when enabled, else the original title, as do ones the profanity filter
+// rejects. Only the first limit articles (all when limit is 0) are
+// transformed; the rest keep their original title.
+func transformArticles(ctx context.Context, articles []Article, limit int) []string {
+	if limit <= 0 || limit > len(articles) {
+		limit = len(articles)
+	}
+	results := make([]string, len(articles))
+	for i := limit; i < len(articles); i++ {
+		results[i] = articles[i].Title
+	}
+	jobs := make(chan int)
+
+	// Same options the transform endpoint fills in for a bare request
+	options := TransformOptions{Intensity: defaultIntensity, Persona: defaultPersona}
+	ctx = withTransformOptions(ctx, options)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < feedTransformWorkers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				article := articles[i]
+				key := transformCacheKey(currentConfig().LLMProvider, options, article.Title, article.Description)
+				cached, ok := getCachedTransform(ctx, key)
+				transformed := cached.Content
+				var err error
+				if !ok {
+					transformed, err = transformOnce(ctx, key, article.Title, article.Description)
+				}
+				if err != nil {
+					slog.WarnContext(ctx, "feed transform failed", "url", article.URL, "error", err)
+					if currentConfig().TransformFallback {
+						transformed, err = LocalTransformer{Dictionary: currentConfig().Newspeak}.Transform(ctx, article.Title, article.Description)
+					}
+					if err != nil {
+						results[i] = article.Title
+						continue
+					}
+				}
+
+				// Trimmed and screened like /api/transform; a rejected transform keeps the original title
+				transformed, _ = truncateText(transformed, currentConfig().TransformMaxChars)
+				if transformed, err = currentConfig().Profanity.Apply(transformed); err != nil {
+					slog.WarnContext(ctx, "feed transform rejected by profanity filter", "url", article.URL)
+					transformed = article.Title
+				}
+				results[i] = transformed
+			}
+		}()
+	}
+
+	for i := range limit {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// Format a NewsAPI timestamp for RSS, or "" if it can't be parsed
+func rssDate(publishedAt string) string {
+	parsed, err := time.Parse(time.RFC3339, publishedAt)
+	if err != nil {
+		return ""
+	}
+	return parsed.Format(time.RFC1123Z)
+}
+
+// Build an RSS document from articles and their transformed titles
+func buildRSS(link string, articles []Article, titles []string) RSS {
+	items := make([]RSSItem, 0, len(articles))
+	for i, article := range articles {
+		items = append(items, RSSItem{
+			Title:       titles[i],
+			Link:        article.URL,
+			Description: article.Description,
+			GUID:        RSSGUID{Value: article.URL, IsPermaLink: true},
+			PubDate:     rssDate(article.PublishedAt),
+		})
+	}
+
+	return RSS{
+		Version: "2.0",
+		Channel: RSSChannel{
+			Title:         "Ministry of Truth - Approved Headlines",
+			Link:          link,
+			Description:   "Top headlines, corrected by the Ministry of Truth",
+			LastBuildDate: time.Now().UTC().Format(time.RFC1123Z),
+			Items:         items,
+		},
+	}
+}
+
+// Transformed headlines RSS feed endpoint
+func getHeadlinesRSS(w http.ResponseWriter, r *http.Request) {
+	newsQuery, err := headlinesQuery(r.URL.Query(), currentConfig())
+	if err != nil {
+		http.Error(w, err.Error(), queryErrorStatus(err))
+		return
+	}
+
+	// Same ?limit= and MAX_TRANSFORM_ARTICLES cap as transform-all
+	limit, err := transformLimitParam(r.URL.Query().Get("limit"), currentConfig().MaxTransformArticles)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	newsResponse, err := fetchTopHeadlines(r.Context(), currentNewsProvider(), newsQuery)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error fetching news", "error", err)
+		http.Error(w, fmt.Sprintf("Error fetching news: %v", err), newsErrorStatus(err))
+		return
+	}
+
+	titles := transformArticles(r.Context(), newsResponse.Articles, limit)
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	feed := buildRSS(scheme+"://"+r.Host+"/", newsResponse.Articles, titles)
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(feed); err != nil {
+		slog.ErrorContext(r.Context(), "error encoding RSS feed", "error", err)
+	}
+}
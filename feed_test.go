@@ -0,0 +1,118 @@
+package ministry
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRSSDate(t *testing.T) {
+	if got := rssDate("2024-03-01T12:30:00Z"); got != "Fri, 01 Mar 2024 12:30:00 +0000" {
+		t.Errorf("rssDate = %q", got)
+	}
+	if got := rssDate("yesterday"); got != "" {
+		t.Errorf("rssDate(yesterday) = %q, want empty", got)
+	}
+}
+
+func TestHeadlinesRSS(t *testing.T) {
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		if strings.Contains(req.Messages[len(req.Messages)-1].Content, "Lift broken") {
+			writeCompletion(w, req.Model, "Lift repaired ahead of schedule", "stop")
+			return
+		}
+		http.Error(w, "overloaded", http.StatusInternalServerError)
+	}
+	news := newsHandler(
+		Article{Title: "Lift broken again", URL: "https://example.com/lift", PublishedAt: "2024-03-01T12:30:00Z"},
+		Article{Title: "Rations cut", URL: "https://example.com/rations"},
+	)
+	setupTest(t, upstreams(openAI, news))
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines/rss", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/rss+xml; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	var feed RSS
+	if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("feed isn't XML: %v", err)
+	}
+	if feed.Version != "2.0" || feed.Channel.Link != "http://example.com/" || len(feed.Channel.Items) != 2 {
+		t.Fatalf("feed = %+v", feed)
+	}
+
+	items := feed.Channel.Items
+	if items[0].Title != "Lift repaired ahead of schedule" || items[0].GUID.Value != "https://example.com/lift" || items[0].PubDate == "" {
+		t.Errorf("first item = %+v", items[0])
+	}
+	// A failed transform keeps the original title
+	if items[1].Title != "Rations cut" {
+		t.Errorf("second item title = %q, want the original", items[1].Title)
+	}
+}
+
+func TestHeadlinesRSSProfanityModes(t *testing.T) {
+	news := newsHandler(Article{Title: "Lift broken again", URL: "https://example.com/lift"})
+
+	for mode, want := range map[string]string{"mask": "Goldstein is a *******", "reject": "Lift broken again"} {
+		t.Run(mode, func(t *testing.T) {
+			setupTest(t, upstreams(newFakeOpenAI("Goldstein is a bastard"), news), "PROFANITY_MODE", mode)
+
+			var feed RSS
+			if err := xml.Unmarshal(do(newRequest(http.MethodGet, "/api/news/headlines/rss", "")).Body.Bytes(), &feed); err != nil {
+				t.Fatalf("feed isn't XML: %v", err)
+			}
+			if title := feed.Channel.Items[0].Title; title != want {
+				t.Errorf("title = %q, want %q", title, want)
+			}
+		})
+	}
+}
+
+func TestHeadlinesRSSMaxChars(t *testing.T) {
+	news := newsHandler(Article{Title: "Lift broken again", URL: "https://example.com/lift"})
+	setupTest(t, upstreams(newFakeOpenAI("Lift repaired ahead of schedule"), news), "TRANSFORM_MAX_CHARS", "20")
+
+	var feed RSS
+	if err := xml.Unmarshal(do(newRequest(http.MethodGet, "/api/news/headlines/rss", "")).Body.Bytes(), &feed); err != nil {
+		t.Fatalf("feed isn't XML: %v", err)
+	}
+	if title := feed.Channel.Items[0].Title; title != "Lift repaired ahead…" {
+		t.Errorf("title = %q, want it trimmed to TRANSFORM_MAX_CHARS", title)
+	}
+}
+
+func TestHeadlinesRSSLimit(t *testing.T) {
+	for _, test := range []struct {
+		name, target string
+		env          []string
+	}{
+		{"limit parameter", "/api/news/headlines/rss?limit=1", nil},
+		{"MAX_TRANSFORM_ARTICLES", "/api/news/headlines/rss?limit=3", []string{"MAX_TRANSFORM_ARTICLES", "1"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			openAI := newFakeOpenAI("Glorious news")
+			setupTest(t, upstreams(openAI, newFakeNews(numberedArticles(3)...)), test.env...)
+
+			var feed RSS
+			if err := xml.Unmarshal(do(newRequest(http.MethodGet, test.target, "")).Body.Bytes(), &feed); err != nil {
+				t.Fatalf("feed isn't XML: %v", err)
+			}
+			items := feed.Channel.Items
+			if openAI.Calls() != 1 || len(items) != 3 || items[0].Title != "Glorious news" || items[2].Title != "Headline 2" {
+				t.Errorf("%d model calls, items %+v; want only the first transformed", openAI.Calls(), items)
+			}
+		})
+	}
+
+	setupTest(t, nil)
+	if rec := do(newRequest(http.MethodGet, "/api/news/headlines/rss?limit=0", "")); rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d for limit=0, want 400", rec.Code)
+	}
+}
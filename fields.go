@@ -0,0 +1,82 @@
+package ministry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Article fields selectable with ?fields=, keyed by their JSON name
+var articleFieldGetters = map[string]func(Article) any{
+	"source":           func(a Article) any { return a.Source },
+	"author":           func(a Article) any { return a.Author },
+	"title":            func(a Article) any { return a.Title },
+	"description":      func(a Article) any { return a.Description },
+	"url":              func(a Article) any { return a.URL },
+	"urlToImage":       func(a Article) any { return a.URLToImage },
+	"publishedAt":      func(a Article) any { return a.PublishedAt },
+	"content":          func(a Article) any { return a.Content },
+	"category":         func(a Article) any { return a.Category },
+	"inferredCategory": func(a Article) any { return a.InferredCategory },
+	"fromCategory":     func(a Article) any { return a.FromCategory },
+	"matchedQuery":     func(a Article) any { return a.MatchedQuery },
+	"supplemented":     func(a Article) any { return a.Supplemented },
+}
+
+// News response carrying only the requested article fields
+type ProjectedNewsResponse struct {
+	Status       string           `json:"status"`
+	TotalResults int              `json:"totalResults"`
+	Articles     []map[string]any `json:"articles"`
+	NextCursor   string           `json:"nextCursor,omitempty"`
+	Truncated    bool             `json:"truncated,omitempty"`
+	Stale        bool             `json:"stale,omitempty"`
+}
+
+// Parse a comma-separated ?fields= list; nil means every field
+func parseArticleFields(raw, format string) ([]string, error) {
+	var fields []string
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[field] {
+			continue
+		}
+		if _, ok := articleFieldGetters[field]; !ok {
+			names := make([]string, 0, len(articleFieldGetters))
+			for name := range articleFieldGetters {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("Invalid field %q; allowed values: %s", field, strings.Join(names, ", "))
+		}
+		seen[field] = true
+		fields = append(fields, field)
+	}
+
+	if len(fields) > 0 && format == "xml" {
+		return nil, fmt.Errorf("fields is only supported for JSON responses")
+	}
+	return fields, nil
+}
+
+// Keep only the given fields of every article
+func projectNewsResponse(newsResponse *NewsResponse, fields []string) ProjectedNewsResponse {
+	articles := make([]map[string]any, len(newsResponse.Articles))
+	for i, article := range newsResponse.Articles {
+		projected := make(map[string]any, len(fields))
+		for _, field := range fields {
+			projected[field] = articleFieldGetters[field](article)
+		}
+		articles[i] = projected
+	}
+
+	return ProjectedNewsResponse{
+		Status:       newsResponse.Status,
+		TotalResults: newsResponse.TotalResults,
+		Articles:     articles,
+		NextCursor:   newsResponse.NextCursor,
+		Truncated:    newsResponse.Truncated,
+		Stale:        newsResponse.Stale,
+	}
+}
@@ -0,0 +1,56 @@
+package ministry
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestParseArticleFields(t *testing.T) {
+	fields, err := parseArticleFields("title, url,title", "json")
+	if err != nil || !slices.Equal(fields, []string{"title", "url"}) {
+		t.Errorf("parseArticleFields = %q, %v", fields, err)
+	}
+	if fields, err := parseArticleFields("", "xml"); fields != nil || err != nil {
+		t.Errorf("empty fields = %q, %v; want every field", fields, err)
+	}
+	if _, err := parseArticleFields("title,secrets", "json"); err == nil {
+		t.Error("unknown field accepted, want an error")
+	}
+	if _, err := parseArticleFields("title", "xml"); err == nil {
+		t.Error("fields with XML accepted, want an error")
+	}
+}
+
+func TestHeadlinesFieldProjection(t *testing.T) {
+	setupTest(t, upstreams(nil, newsHandler(Article{
+		Title:       "Lift broken again",
+		URL:         "https://example.com/lift",
+		PublishedAt: "2024-03-01T12:30:00Z",
+		Author:      "Winston Smith",
+		Content:     "The lift in Victory Mansions has failed.",
+	})))
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines?fields=title,url,publishedAt", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var response struct {
+		Articles []map[string]any `json:"articles"`
+	}
+	decodeJSON(t, rec, &response)
+	want := map[string]any{"title": "Lift broken again", "url": "https://example.com/lift", "publishedAt": "2024-03-01T12:30:00Z"}
+	if len(response.Articles) != 1 || len(response.Articles[0]) != len(want) {
+		t.Fatalf("articles = %v, want only %v", response.Articles, want)
+	}
+	for field, value := range want {
+		if response.Articles[0][field] != value {
+			t.Errorf("%s = %v, want %v", field, response.Articles[0][field], value)
+		}
+	}
+
+	rec = do(newRequest(http.MethodGet, "/api/news/headlines?fields=title,password", ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d for an unknown field, want 400", rec.Code)
+	}
+}
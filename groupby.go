@@ -0,0 +1,59 @@
+package ministry
+
+import "fmt"
+
+// Source name used for articles that don't name their outlet
+const unknownSource = "Unknown"
+
+// One outlet's articles in a grouped response
+type SourceGroup struct {
+	Count    int       `json:"count"`
+	Articles []Article `json:"articles"`
+}
+
+// Headlines keyed by source name, for ?groupBy=source
+type GroupedNewsResponse struct {
+	Status       string                 `json:"status"`
+	TotalResults int                    `json:"totalResults"`
+	Sources      map[string]SourceGroup `json:"sources"`
+	Truncated    bool                   `json:"truncated,omitempty"`
+	Stale        bool                   `json:"stale,omitempty"`
+}
+
+// Validate the groupBy query parameter; empty keeps the flat article list.
+// Grouped responses are JSON only and can't be combined with fields.
+func parseGroupBy(raw, format string, fields []string) (string, error) {
+	switch {
+	case raw == "":
+		return "", nil
+	case raw != "source":
+		return "", fmt.Errorf("Invalid groupBy %q; allowed values: source", raw)
+	case format == "xml":
+		return "", fmt.Errorf("groupBy is only supported for JSON responses")
+	case len(fields) > 0:
+		return "", fmt.Errorf("groupBy cannot be combined with fields")
+	}
+	return raw, nil
+}
+
+// Group articles by source name, keeping their order within each source
+func groupBySource(newsResponse *NewsResponse) GroupedNewsResponse {
+	grouped := GroupedNewsResponse{
+		Status:       newsResponse.Status,
+		TotalResults: newsResponse.TotalResults,
+		Sources:      make(map[string]SourceGroup),
+		Truncated:    newsResponse.Truncated,
+		Stale:        newsResponse.Stale,
+	}
+	for _, article := range newsResponse.Articles {
+		name := article.Source.Name
+		if name == "" {
+			name = unknownSource
+		}
+		group := grouped.Sources[name]
+		group.Count++
+		group.Articles = append(group.Articles, article)
+		grouped.Sources[name] = group
+	}
+	return grouped
+}
@@ -0,0 +1,60 @@
+package ministry
+
+import (
+	"net/http"
+	"testing"
+)
+
+func groupedArticles() []Article {
+	return []Article{
+		{Title: "Lift broken again", Source: Source{Name: "The Times"}},
+		{Title: "Rations raised", Source: Source{Name: "Minitrue Daily"}},
+		{Title: "Lift repaired", Source: Source{Name: "The Times"}},
+		{Title: "Chocolate ration cut"},
+	}
+}
+
+func TestHeadlinesGroupBySource(t *testing.T) {
+	setupTest(t, upstreams(nil, newsHandler(groupedArticles()...)))
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines?groupBy=source", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var response GroupedNewsResponse
+	decodeJSON(t, rec, &response)
+
+	if response.TotalResults != 4 || len(response.Sources) != 3 {
+		t.Fatalf("response = %+v, want 4 articles in 3 sources", response)
+	}
+	times := response.Sources["The Times"]
+	if times.Count != 2 || len(times.Articles) != 2 || times.Articles[0].Title != "Lift broken again" || times.Articles[1].Title != "Lift repaired" {
+		t.Errorf("The Times = %+v, want both its articles in feed order", times)
+	}
+	if daily := response.Sources["Minitrue Daily"]; daily.Count != 1 || daily.Articles[0].Title != "Rations raised" {
+		t.Errorf("Minitrue Daily = %+v", daily)
+	}
+	if unknown := response.Sources[unknownSource]; unknown.Count != 1 || unknown.Articles[0].Title != "Chocolate ration cut" {
+		t.Errorf("%s = %+v, want the article without a source", unknownSource, unknown)
+	}
+}
+
+func TestHeadlinesUngroupedByDefault(t *testing.T) {
+	setupTest(t, upstreams(nil, newsHandler(groupedArticles()...)))
+
+	var response NewsResponse
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/news/headlines", "")), &response)
+	if len(response.Articles) != 4 || response.Articles[1].Title != "Rations raised" {
+		t.Errorf("articles = %+v, want the flat feed", response.Articles)
+	}
+}
+
+func TestHeadlinesGroupByInvalid(t *testing.T) {
+	setupTest(t, upstreams(nil, newsHandler(groupedArticles()...)))
+
+	for _, query := range []string{"groupBy=author", "groupBy=source&format=xml", "groupBy=source&fields=title"} {
+		if rec := do(newRequest(http.MethodGet, "/api/news/headlines?"+query, "")); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
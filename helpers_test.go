@@ -0,0 +1,251 @@
+package ministry
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Upstream paths the fake upstream server routes on, by host
+const (
+	openAICompletionsPath = "api.openai.com/v1/chat/completions"
+	newsHeadlinesPath     = "newsapi.org/v2/top-headlines"
+	newsSearchPath        = "newsapi.org/v2/everything"
+	newsSourcesPath       = "newsapi.org/v2/top-headlines/sources"
+)
+
+// Load a config from the environment with env (key, value pairs) on top of
+// test API keys and install it the way setup does. Calls to the news and LLM
+// APIs go to upstream instead of the internet.
+func setupTest(t *testing.T, upstream http.Handler, env ...string) *Config {
+	t.Helper()
+
+	t.Setenv("NEWS_API_KEY", "test-news-key")
+	t.Setenv("OPENAI_API_KEY", "test-openai-key")
+	t.Setenv("LOG_LEVEL", "error")
+	for i := 0; i+1 < len(env); i += 2 {
+		t.Setenv(env[i], env[i+1])
+	}
+
+	if upstream != nil {
+		redirectUpstream(t, upstream)
+	}
+
+	previousArchive, previousWebhooks, previousTransport := archive, webhooks, upstreamTransport
+	t.Cleanup(func() {
+		if closer, ok := archive.(io.Closer); ok && archive != previousArchive {
+			closer.Close()
+		}
+		// Let transforms shared past their callers finish before the next test
+		sharedTransforms.Wait()
+		archive, webhooks, upstreamTransport = previousArchive, previousWebhooks, previousTransport
+		llmBreaker = nil
+	})
+
+	cfg, err := setup()
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	return cfg
+}
+
+// Send every upstream call to handler, whatever host it names
+func redirectUpstream(t *testing.T, handler http.Handler) {
+	t.Helper()
+
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	previous := http.DefaultTransport
+	http.DefaultTransport = transport
+	t.Cleanup(func() {
+		http.DefaultTransport = previous
+		transport.CloseIdleConnections()
+	})
+}
+
+// Run req through a fresh router
+func do(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+	return rec
+}
+
+// Request with a JSON (or any string) body and optional header pairs
+func newRequest(method, target, body string, headers ...string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	return req
+}
+
+// Decode a JSON response body into v
+func decodeJSON(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+}
+
+// Chat completions API stand-in that records the requests it gets
+type fakeOpenAI struct {
+	mu       sync.Mutex
+	requests []OpenAIRequest
+	headers  []http.Header
+
+	// Writes the response; by default the nth request gets a completion of
+	// the nth content, the last one repeating
+	Reply    func(w http.ResponseWriter, req OpenAIRequest)
+	contents []string
+}
+
+func newFakeOpenAI(contents ...string) *fakeOpenAI {
+	return &fakeOpenAI{contents: contents}
+}
+
+func (f *fakeOpenAI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req OpenAIRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	f.headers = append(f.headers, r.Header.Clone())
+	reply, n := f.Reply, len(f.requests)
+	f.mu.Unlock()
+
+	if reply != nil {
+		reply(w, req)
+		return
+	}
+	writeCompletion(w, req.Model, f.contents[min(n, len(f.contents))-1], "stop")
+}
+
+// Requests received so far
+func (f *fakeOpenAI) Requests() []OpenAIRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]OpenAIRequest(nil), f.requests...)
+}
+
+// Headers of the requests received so far
+func (f *fakeOpenAI) Headers() []http.Header {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]http.Header(nil), f.headers...)
+}
+
+func (f *fakeOpenAI) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+// Write a one-choice chat completion using 10 prompt and 20 completion tokens
+func writeCompletion(w http.ResponseWriter, model, content, finishReason string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"model": model,
+		"choices": []map[string]any{{
+			"message":       map[string]string{"role": "assistant", "content": content},
+			"finish_reason": finishReason,
+		}},
+		"usage": map[string]int{"prompt_tokens": 10, "completion_tokens": 20, "total_tokens": 30},
+	})
+}
+
+// NewsAPI stand-in answering with articles
+func newsHandler(articles ...Article) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(NewsResponse{Status: "ok", TotalResults: len(articles), Articles: articles})
+	}
+}
+
+// NewsAPI stand-in that records the query of every request it answers
+type fakeNews struct {
+	mu       sync.Mutex
+	queries  []url.Values
+	articles []Article
+}
+
+func newFakeNews(articles ...Article) *fakeNews {
+	return &fakeNews{articles: articles}
+}
+
+func (f *fakeNews) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.queries = append(f.queries, r.URL.Query())
+	f.mu.Unlock()
+	newsHandler(f.articles...)(w, r)
+}
+
+// Queries received so far
+func (f *fakeNews) Queries() []url.Values {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]url.Values(nil), f.queries...)
+}
+
+// Upstream server answering chat completions with openAI and top headlines
+// and searches with news
+func upstreams(openAI, news http.Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+	if openAI != nil {
+		mux.Handle(openAICompletionsPath, openAI)
+	}
+	if news != nil {
+		mux.Handle(newsHeadlinesPath, news)
+		mux.Handle(newsSearchPath, news)
+	}
+	return mux
+}
+
+// POST a transform request and return the recorder
+func postTransform(body string, headers ...string) *httptest.ResponseRecorder {
+	return do(newRequest(http.MethodPost, "/api/transform", body, headers...))
+}
+
+// Write tokens as a streamed chat completion
+func writeCompletionStream(w http.ResponseWriter, tokens ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, token := range tokens {
+		chunk, _ := json.Marshal(map[string]any{"choices": []map[string]any{{"delta": map[string]string{"content": token}}}})
+		fmt.Fprintf(w, "data: %s\n\n", chunk)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// Data lines of the SSE events in body, "event:" names prefixed to their data
+func sseEvents(body string) []string {
+	var events []string
+	event := ""
+	for _, line := range strings.Split(body, "\n") {
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ") + ": "
+		case strings.HasPrefix(line, "data: "):
+			events = append(events, event+strings.TrimPrefix(line, "data: "))
+			event = ""
+		}
+	}
+	return events
+}
@@ -0,0 +1,62 @@
+package ministry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// Longest Idempotency-Key accepted
+const maxIdempotencyKeyLength = 255
+
+// Returned when an Idempotency-Key is reused with a different request body
+var errIdempotencyConflict = errors.New("Idempotency-Key was already used with a different request body")
+
+// Stored response for an Idempotency-Key, tied to the body it answered
+type idempotentResult struct {
+	BodyHash string          `json:"bodyHash"`
+	Response json.RawMessage `json:"response"`
+}
+
+// Hex SHA-256 of a request body
+func hashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Stored response for key, if any; errIdempotencyConflict when the key was
+// used with a different body. Cache errors are logged and treated as a miss.
+func lookupIdempotent(ctx context.Context, cache Cache, key, bodyHash string) ([]byte, bool, error) {
+	data, ok, err := cache.Get(ctx, key)
+	if err != nil {
+		slog.WarnContext(ctx, "idempotency cache get failed", "error", err)
+		return nil, false, nil
+	}
+	if !ok {
+		return nil, false, nil
+	}
+
+	var result idempotentResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, false, nil
+	}
+	if result.BodyHash != bodyHash {
+		return nil, false, errIdempotencyConflict
+	}
+	return result.Response, true, nil
+}
+
+// Remember the response for key; cache errors are logged
+func storeIdempotent(ctx context.Context, cache Cache, key, bodyHash string, response []byte, ttl time.Duration) {
+	data, err := json.Marshal(idempotentResult{BodyHash: bodyHash, Response: response})
+	if err != nil {
+		return
+	}
+	if err := cache.Set(ctx, key, data, ttl); err != nil {
+		slog.WarnContext(ctx, "idempotency cache set failed", "error", err)
+	}
+}
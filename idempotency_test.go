@@ -0,0 +1,79 @@
+package ministry
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLookupIdempotent(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	cache := NewMemoryCache()
+	cache.now = func() time.Time { return now }
+
+	body := hashBody([]byte(`{"title": "Lift broken again"}`))
+	storeIdempotent(ctx, cache, "retry-1", body, []byte(`{"transformedContent": "approved"}`), time.Hour)
+
+	stored, ok, err := lookupIdempotent(ctx, cache, "retry-1", body)
+	if !ok || err != nil || string(stored) != `{"transformedContent":"approved"}` {
+		t.Errorf("lookup = %s, %v, %v", stored, ok, err)
+	}
+	if _, _, err := lookupIdempotent(ctx, cache, "retry-1", hashBody([]byte(`{}`))); err != errIdempotencyConflict {
+		t.Errorf("lookup with another body = %v, want errIdempotencyConflict", err)
+	}
+
+	now = now.Add(time.Hour)
+	if _, ok, err := lookupIdempotent(ctx, cache, "retry-1", body); ok || err != nil {
+		t.Errorf("lookup after the TTL = %v, %v; want a miss", ok, err)
+	}
+}
+
+func TestTransformIdempotencyReplay(t *testing.T) {
+	openAI := newFakeOpenAI("Lift repaired ahead of schedule")
+	setupTest(t, upstreams(openAI, nil), "TRANSFORM_CACHE_TTL", "1ns")
+
+	first := postTransform(`{"title": "Lift broken again"}`, "Idempotency-Key", "retry-1")
+	if first.Code != http.StatusOK || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("first: status = %d, headers %v", first.Code, first.Header())
+	}
+
+	replay := postTransform(`{"title": "Lift broken again"}`, "Idempotency-Key", "retry-1")
+	if replay.Code != http.StatusOK || replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("replay: status = %d, headers %v", replay.Code, replay.Header())
+	}
+	if strings.TrimSpace(replay.Body.String()) != strings.TrimSpace(first.Body.String()) {
+		t.Errorf("replayed body = %q, want %q", replay.Body.String(), first.Body.String())
+	}
+	if openAI.Calls() != 1 {
+		t.Errorf("model got %d calls, want the replay to skip it", openAI.Calls())
+	}
+
+	conflict := postTransform(`{"title": "Rations cut"}`, "Idempotency-Key", "retry-1")
+	if conflict.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key with another body: status = %d, want 422", conflict.Code)
+	}
+
+	long := postTransform(`{"title": "Lift broken again"}`, "Idempotency-Key", strings.Repeat("k", maxIdempotencyKeyLength+1))
+	if long.Code != http.StatusBadRequest {
+		t.Errorf("over-long key: status = %d, want 400", long.Code)
+	}
+}
+
+func TestTransformIdempotencyExpires(t *testing.T) {
+	openAI := newFakeOpenAI("Lift repaired ahead of schedule")
+	setupTest(t, upstreams(openAI, nil), "TRANSFORM_CACHE_TTL", "1ns", "IDEMPOTENCY_TTL", "50ms")
+
+	postTransform(`{"title": "Lift broken again"}`, "Idempotency-Key", "retry-1")
+	time.Sleep(100 * time.Millisecond)
+
+	rec := postTransform(`{"title": "Lift broken again"}`, "Idempotency-Key", "retry-1")
+	if rec.Code != http.StatusOK || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("status = %d, headers %v; want a fresh transform", rec.Code, rec.Header())
+	}
+	if openAI.Calls() != 2 {
+		t.Errorf("model got %d calls, want 2", openAI.Calls())
+	}
+}
@@ -0,0 +1,172 @@
+package ministry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Returned when no model request slot frees up within the wait limit
+var ErrConcurrencyLimit = errors.New("too many concurrent model requests")
+
+// Counting semaphore capping concurrent upstream calls. A nil *Semaphore
+// imposes no limit.
+type Semaphore struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// Limiter shared by every OpenAI transformer, so a config reload that swaps
+// the transformer doesn't reset the OPENAI_MAX_CONCURRENCY cap
+var openAILimiter *Semaphore
+
+// Semaphore with size slots whose Acquire gives up after wait; nil when size <= 0
+func NewSemaphore(size int, wait time.Duration) *Semaphore {
+	if size <= 0 {
+		return nil
+	}
+	return &Semaphore{slots: make(chan struct{}, size), wait: wait}
+}
+
+// Take a slot, failing with ErrConcurrencyLimit once the wait runs out
+func (s *Semaphore) Acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(s.wait)
+	defer timer.Stop()
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrConcurrencyLimit
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Give back a slot taken by Acquire
+func (s *Semaphore) Release() {
+	if s != nil {
+		<-s.slots
+	}
+}
+
+// Hold the slot until body is closed, so streamed responses count until they finish
+func (s *Semaphore) releaseOnClose(body io.ReadCloser) io.ReadCloser {
+	if s == nil {
+		return body
+	}
+	return &releasingBody{ReadCloser: body, release: s.Release}
+}
+
+// Response body that frees its semaphore slot on the first Close
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// Returned when a request arrives with the transform queue already full
+var ErrQueueFull = errors.New("transform queue is full")
+
+// Admission queue: up to inFlight requests run at once and up to depth more
+// wait for a turn; anything beyond that is turned away. A nil *RequestQueue
+// admits everything.
+type RequestQueue struct {
+	slots    chan struct{}
+	depth    int64
+	queued   atomic.Int64
+	rejected atomic.Int64
+}
+
+// Queue running inFlight requests with depth more waiting; nil when inFlight <= 0
+func NewRequestQueue(inFlight, depth int) *RequestQueue {
+	if inFlight <= 0 {
+		return nil
+	}
+	return &RequestQueue{slots: make(chan struct{}, inFlight), depth: int64(depth)}
+}
+
+// Wait for a turn, failing at once with ErrQueueFull when depth requests are
+// already waiting, or with ctx's error if it ends first
+func (q *RequestQueue) Enter(ctx context.Context) error {
+	if q == nil {
+		return nil
+	}
+
+	select {
+	case q.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if q.queued.Add(1) > q.depth {
+		q.queued.Add(-1)
+		q.rejected.Add(1)
+		return ErrQueueFull
+	}
+	defer q.queued.Add(-1)
+
+	select {
+	case q.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Give back a turn taken by Enter
+func (q *RequestQueue) Leave() {
+	if q != nil {
+		<-q.slots
+	}
+}
+
+// Snapshot of a RequestQueue for metrics
+type RequestQueueStats struct {
+	InFlight int
+	Queued   int
+	Rejected int64
+}
+
+func (q *RequestQueue) Stats() RequestQueueStats {
+	return RequestQueueStats{InFlight: len(q.slots), Queued: int(q.queued.Load()), Rejected: q.rejected.Load()}
+}
+
+// Queue in front of POST /api/transform, from TRANSFORM_MAX_IN_FLIGHT and
+// TRANSFORM_QUEUE_DEPTH; nil when disabled
+var transformQueue *RequestQueue
+
+// Run next once transformQueue admits the request, answering 503 straight
+// away when the queue is full
+func queuedTransform(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queue := transformQueue
+		if err := queue.Enter(r.Context()); err != nil {
+			slog.WarnContext(r.Context(), "transform not admitted", "error", err)
+			writeTransformError(w, err)
+			return
+		}
+		defer queue.Leave()
+		next(w, r)
+	}
+}
@@ -0,0 +1,276 @@
+package ministry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSemaphore(t *testing.T) {
+	sem := NewSemaphore(2, 20*time.Millisecond)
+	ctx := context.Background()
+
+	if sem.Acquire(ctx) != nil || sem.Acquire(ctx) != nil {
+		t.Fatal("couldn't take both slots")
+	}
+	if err := sem.Acquire(ctx); !errors.Is(err, ErrConcurrencyLimit) {
+		t.Errorf("Acquire on a full semaphore = %v, want ErrConcurrencyLimit", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := sem.Acquire(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Acquire with a cancelled context = %v, want context.Canceled", err)
+	}
+
+	sem.Release()
+	if err := sem.Acquire(ctx); err != nil {
+		t.Errorf("Acquire after a release = %v", err)
+	}
+
+	// A slot freed during the wait is taken
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		sem.Release()
+	}()
+	if err := sem.Acquire(ctx); err != nil {
+		t.Errorf("Acquire while a slot frees up = %v", err)
+	}
+}
+
+func TestNilSemaphoreIsUnlimited(t *testing.T) {
+	sem := NewSemaphore(0, time.Second)
+	if sem != nil {
+		t.Fatal("NewSemaphore(0) isn't nil")
+	}
+	for range 100 {
+		if err := sem.Acquire(context.Background()); err != nil {
+			t.Fatalf("Acquire = %v", err)
+		}
+	}
+	sem.Release()
+}
+
+func TestSemaphoreReleaseOnClose(t *testing.T) {
+	sem := NewSemaphore(1, time.Millisecond)
+	sem.Acquire(context.Background())
+	body := sem.releaseOnClose(io.NopCloser(strings.NewReader("")))
+
+	if err := sem.Acquire(context.Background()); !errors.Is(err, ErrConcurrencyLimit) {
+		t.Fatalf("slot freed before the body was closed")
+	}
+	body.Close()
+	body.Close()
+	if err := sem.Acquire(context.Background()); err != nil {
+		t.Errorf("Acquire after close = %v", err)
+	}
+	if err := sem.Acquire(context.Background()); !errors.Is(err, ErrConcurrencyLimit) {
+		t.Error("a second Close released another slot")
+	}
+}
+
+func TestOpenAIConcurrencyCap(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		writeCompletion(w, req.Model, "Rations are plentiful", "stop")
+	}
+	setupTest(t, upstreams(openAI, nil), "OPENAI_MAX_CONCURRENCY", "2", "OPENAI_CONCURRENCY_WAIT", "5s")
+
+	var wg sync.WaitGroup
+	codes := make([]int, 8)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = postTransform(fmt.Sprintf(`{"title": "Lift broken again %d"}`, i)).Code
+		}()
+	}
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("transform %d: status = %d", i, code)
+		}
+	}
+	if peak.Load() != 2 {
+		t.Errorf("%d OpenAI calls ran at once, want the cap of 2", peak.Load())
+	}
+}
+
+func TestOpenAIConcurrencyWaitTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		<-release
+		writeCompletion(w, req.Model, "Rations are plentiful", "stop")
+	}
+	setupTest(t, upstreams(openAI, nil), "OPENAI_MAX_CONCURRENCY", "1", "OPENAI_CONCURRENCY_WAIT", "20ms")
+
+	done := make(chan int)
+	go func() { done <- postTransform(`{"title": "Lift broken again"}`).Code }()
+	for openAI.Calls() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	rec := postTransform(`{"title": "Rations cut"}`)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d while the only slot is held, want 503", rec.Code)
+	}
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("first transform status = %d", code)
+	}
+}
+
+func TestOpenAIConcurrencySurvivesReload(t *testing.T) {
+	release := make(chan struct{})
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		<-release
+		writeCompletion(w, req.Model, "Rations are plentiful", "stop")
+	}
+	setupTest(t, upstreams(openAI, nil), "OPENAI_MAX_CONCURRENCY", "1", "OPENAI_CONCURRENCY_WAIT", "20ms",
+		"SERVICE_API_KEYS", "limiter-test-key", "ENV_FILE", filepath.Join(t.TempDir(), ".env"))
+
+	done := make(chan int)
+	go func() { done <- postTransform(`{"title": "Lift broken again"}`, "X-API-Key", "limiter-test-key").Code }()
+	for openAI.Calls() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The reloaded transformer still counts the call already holding the slot
+	t.Setenv("MAX_ARTICLES", "42")
+	if rec := do(newRequest(http.MethodPost, "/api/admin/reload", "", "X-API-Key", "limiter-test-key")); rec.Code != http.StatusOK {
+		t.Fatalf("reload status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	rec := postTransform(`{"title": "Rations cut"}`, "X-API-Key", "limiter-test-key")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d after a reload while the only slot is held, want 503", rec.Code)
+	}
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("first transform status = %d", code)
+	}
+}
+
+func TestRequestQueue(t *testing.T) {
+	queue := NewRequestQueue(1, 1)
+	ctx := context.Background()
+
+	if err := queue.Enter(ctx); err != nil {
+		t.Fatalf("Enter on an empty queue = %v", err)
+	}
+
+	// The second request waits for the first to leave
+	admitted := make(chan error)
+	go func() { admitted <- queue.Enter(ctx) }()
+	for queue.Stats().Queued == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// With the queue full the third is turned away at once
+	if err := queue.Enter(ctx); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Enter on a full queue = %v, want ErrQueueFull", err)
+	}
+	if stats := queue.Stats(); stats != (RequestQueueStats{InFlight: 1, Queued: 1, Rejected: 1}) {
+		t.Errorf("stats = %+v, want 1 in flight, 1 queued, 1 rejected", stats)
+	}
+
+	queue.Leave()
+	if err := <-admitted; err != nil {
+		t.Errorf("queued Enter = %v", err)
+	}
+	if stats := queue.Stats(); stats.InFlight != 1 || stats.Queued != 0 {
+		t.Errorf("stats after the queued request ran = %+v", stats)
+	}
+
+	// A waiting request gives up with its context
+	cancelled, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	if err := queue.Enter(cancelled); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Enter with an expiring context = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestNilRequestQueueAdmitsEverything(t *testing.T) {
+	queue := NewRequestQueue(0, 5)
+	if queue != nil {
+		t.Fatal("NewRequestQueue(0, 5) isn't nil")
+	}
+	for range 100 {
+		if err := queue.Enter(context.Background()); err != nil {
+			t.Fatalf("Enter = %v", err)
+		}
+	}
+	queue.Leave()
+}
+
+func TestTransformQueue(t *testing.T) {
+	release := make(chan struct{})
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		<-release
+		writeCompletion(w, req.Model, "Rations are plentiful", "stop")
+	}
+	setupTest(t, upstreams(openAI, nil), "TRANSFORM_MAX_IN_FLIGHT", "1", "TRANSFORM_QUEUE_DEPTH", "1")
+
+	codes := make(chan int, 2)
+	go func() { codes <- postTransform(`{"title": "Lift broken again"}`).Code }()
+	for openAI.Calls() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	go func() { codes <- postTransform(`{"title": "Rations cut"}`).Code }()
+	for transformQueue.Stats().Queued == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	metrics := do(newRequest(http.MethodGet, "/metrics", "")).Body.String()
+	for _, want := range []string{"ministry_transform_in_flight 1", "ministry_transform_queue_depth 1"} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("metrics missing %q:\n%s", want, metrics)
+		}
+	}
+
+	rec := postTransform(`{"title": "Chocolate ration raised"}`)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d with the queue full, want 503", rec.Code)
+	}
+
+	// The other routes that call the model share the same queue
+	for _, path := range []string{"/api/transform/text", "/api/article/transform", "/api/news/headlines/transform-all"} {
+		if rec := do(newRequest(http.MethodPost, path, `{}`)); rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s status = %d with the queue full, want 503", path, rec.Code)
+		}
+	}
+	if openAI.Calls() != 1 {
+		t.Errorf("%d OpenAI calls while one transform runs, want 1", openAI.Calls())
+	}
+
+	close(release)
+	for range 2 {
+		if code := <-codes; code != http.StatusOK {
+			t.Errorf("admitted transform status = %d", code)
+		}
+	}
+	if !strings.Contains(do(newRequest(http.MethodGet, "/metrics", "")).Body.String(), "ministry_transform_queue_rejected_total 4") {
+		t.Error("metrics don't count the rejected transform")
+	}
+}
@@ -0,0 +1,167 @@
+package ministry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Parse LOG_LEVEL into a slog level (debug, info, warn, error)
+func parseLogLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(value) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("LOG_LEVEL must be one of debug, info, warn, error, got %q", value)
+	}
+}
+
+// Build a JSON logger writing to out at the given level
+func newLogger(out io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(requestIDHandler{slog.NewJSONHandler(out, &slog.HandlerOptions{Level: level})})
+}
+
+// ResponseWriter wrapper that remembers the status code written
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *statusRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Source of randomness for log sampling, replaceable for deterministic runs
+var logSampleRand = rand.Float64
+
+// Whether a finished request gets a log line. Errors (4xx and 5xx) and
+// requests slower than slow (when non-zero) always do; the rest are kept
+// with probability rate.
+func shouldLogRequest(status int, elapsed time.Duration, rate float64, slow time.Duration, random func() float64) bool {
+	switch {
+	case status >= http.StatusBadRequest:
+		return true
+	case slow > 0 && elapsed >= slow:
+		return true
+	case rate >= 1:
+		return true
+	default:
+		return random() < rate
+	}
+}
+
+// Log the outcome of every request as a structured line, sampled by LOG_SAMPLE_RATE
+func loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+
+		// Deferred so a request whose handler panics is still logged, as the
+		// 500 that recoveryMiddleware answers it with
+		completed := false
+		defer func() {
+			status := rec.status
+			if status == 0 && !completed {
+				status = http.StatusInternalServerError
+			} else if status == 0 {
+				status = http.StatusOK
+			}
+			elapsed := time.Since(start)
+			if !shouldLogRequest(status, elapsed, currentConfig().LogSampleRate, currentConfig().LogSlowThreshold, logSampleRand) {
+				return
+			}
+
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			slog.Log(r.Context(), level, "request completed",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", status,
+				"durationMs", elapsed.Milliseconds(),
+				"remoteAddr", r.RemoteAddr,
+				"clientIP", clientIP(r, currentConfig().TrustedProxies),
+			)
+		}()
+
+		next.ServeHTTP(rec, r)
+		completed = true
+	})
+}
+
+// The upstream API keys in cfg, for redactSecrets
+func upstreamKeys(cfg *Config) []string {
+	if cfg == nil {
+		return nil
+	}
+	return []string{cfg.NewsAPIKey, cfg.GNewsAPIKey, cfg.OpenAIAPIKey, cfg.AnthropicAPIKey}
+}
+
+// Replace every occurrence of the non-empty secrets in s with [REDACTED]
+func redactSecrets(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "[REDACTED]")
+		}
+	}
+	return s
+}
+
+// Response body that keeps what is read through it and logs it at debug
+// level, redacted, on the first Close. Streams are logged once they finish.
+type loggedBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	message string
+	redact  []string
+	buf     bytes.Buffer
+	once    sync.Once
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+func (b *loggedBody) Close() error {
+	b.once.Do(func() {
+		slog.DebugContext(b.ctx, b.message, "body", redactSecrets(b.buf.String(), b.redact))
+	})
+	return b.ReadCloser.Close()
+}
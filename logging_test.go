@@ -0,0 +1,240 @@
+package ministry
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"":        slog.LevelInfo,
+		"debug":   slog.LevelDebug,
+		"INFO":    slog.LevelInfo,
+		"warning": slog.LevelWarn,
+		"error":   slog.LevelError,
+	}
+	for value, want := range tests {
+		got, err := parseLogLevel(value)
+		if err != nil || got != want {
+			t.Errorf("parseLogLevel(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+
+	if _, err := parseLogLevel("verbose"); err == nil {
+		t.Error("parseLogLevel(verbose) succeeded, want an error")
+	}
+}
+
+func TestNewLoggerWritesJSONAtLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(&buf, slog.LevelWarn)
+
+	logger.Info("dropped")
+	logger.Warn("kept", "answer", 42)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("logged %d lines, want 1: %q", len(lines), buf.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("log line isn't JSON: %v", err)
+	}
+	if record["msg"] != "kept" || record["level"] != "WARN" || record["answer"] != float64(42) {
+		t.Errorf("record = %v", record)
+	}
+}
+
+// Send the default logger's JSON lines to a buffer for the rest of the test
+func captureLogs(t *testing.T, level slog.Level) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(newLogger(&buf, level))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// Decoded log records with the given message
+func logRecords(t *testing.T, buf *bytes.Buffer, msg string) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		if line == "" || json.Unmarshal([]byte(line), &record) != nil {
+			continue
+		}
+		if record["msg"] == msg {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func TestLoggingMiddlewareLogsRequests(t *testing.T) {
+	setupTest(t, nil)
+	logs := captureLogs(t, slog.LevelInfo)
+
+	do(newRequest(http.MethodGet, "/api/health", ""))
+
+	records := logRecords(t, logs, "request completed")
+	if len(records) != 1 {
+		t.Fatalf("got %d request logs, want 1: %q", len(records), logs.String())
+	}
+	record := records[0]
+	if record["method"] != "GET" || record["path"] != "/api/health" || record["status"] != float64(200) {
+		t.Errorf("record = %v", record)
+	}
+	if _, ok := record["durationMs"]; !ok {
+		t.Error("record has no durationMs")
+	}
+}
+
+func TestShouldLogRequest(t *testing.T) {
+	const slow = 100 * time.Millisecond
+	never := func() float64 { t.Error("random drawn needlessly"); return 0 }
+	tests := []struct {
+		status  int
+		elapsed time.Duration
+		rate    float64
+		random  func() float64
+		want    bool
+	}{
+		{http.StatusNotFound, time.Millisecond, 0, never, true},
+		{http.StatusBadGateway, time.Millisecond, 0, never, true},
+		{http.StatusOK, slow, 0, never, true},
+		{http.StatusOK, time.Millisecond, 1, never, true},
+		{http.StatusOK, time.Millisecond, 0.25, func() float64 { return 0.2 }, true},
+		{http.StatusOK, time.Millisecond, 0.25, func() float64 { return 0.25 }, false},
+		{http.StatusOK, time.Millisecond, 0, func() float64 { return 0 }, false},
+	}
+	for _, test := range tests {
+		if got := shouldLogRequest(test.status, test.elapsed, test.rate, slow, test.random); got != test.want {
+			t.Errorf("shouldLogRequest(%d, %v, %v) = %v, want %v", test.status, test.elapsed, test.rate, got, test.want)
+		}
+	}
+
+	// LOG_SLOW_MS=0 turns the slow rule off
+	if shouldLogRequest(http.StatusOK, time.Hour, 0, 0, func() float64 { return 0.5 }) {
+		t.Error("slow request logged with the slow threshold off")
+	}
+}
+
+// Replace the sampling RNG with draws from values, in turn
+func useLogSampleDraws(t *testing.T, values ...float64) {
+	t.Helper()
+	previous := logSampleRand
+	var mu sync.Mutex
+	logSampleRand = func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(values) == 0 {
+			t.Error("more sampling draws than expected")
+			return 1
+		}
+		value := values[0]
+		values = values[1:]
+		return value
+	}
+	t.Cleanup(func() { logSampleRand = previous })
+}
+
+func TestLogSampling(t *testing.T) {
+	setupTest(t, nil, "LOG_SAMPLE_RATE", "0.5")
+	logs := captureLogs(t, slog.LevelInfo)
+	useLogSampleDraws(t, 0.1, 0.9, 0.4)
+
+	for range 3 {
+		do(newRequest(http.MethodGet, "/api/health", ""))
+	}
+	postTransform(`not json`)
+
+	var statuses []float64
+	for _, record := range logRecords(t, logs, "request completed") {
+		statuses = append(statuses, record["status"].(float64))
+	}
+	if !slices.Equal(statuses, []float64{200, 200, 400}) {
+		t.Errorf("logged statuses = %v, want the first and third health checks and the 400", statuses)
+	}
+}
+
+func TestLogSamplingKeepsSlowRequests(t *testing.T) {
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		time.Sleep(20 * time.Millisecond)
+		writeCompletion(w, req.Model, "Rations are plentiful", "stop")
+	}
+	setupTest(t, upstreams(openAI, nil), "LOG_SAMPLE_RATE", "0", "LOG_SLOW_MS", "10")
+	logs := captureLogs(t, slog.LevelInfo)
+	useLogSampleDraws(t, 0)
+
+	do(newRequest(http.MethodGet, "/api/health", ""))
+	postTransform(`{"title": "Lift broken again"}`)
+
+	records := logRecords(t, logs, "request completed")
+	if len(records) != 1 || records[0]["path"] != "/api/transform" {
+		t.Errorf("records = %v, want only the slow transform", records)
+	}
+}
+
+func TestLogSamplingConfig(t *testing.T) {
+	setupTest(t, nil)
+	for name, value := range map[string]string{"LOG_SAMPLE_RATE": "1.5", "LOG_SLOW_MS": "-1"} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), name) {
+				t.Errorf("loadConfig error = %v, want it to name %s", err, name)
+			}
+		})
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	got := redactSecrets("key sk-123 and news-456, again sk-123", []string{"sk-123", "", "news-456"})
+	if got != "key [REDACTED] and [REDACTED], again [REDACTED]" {
+		t.Errorf("redactSecrets = %q", got)
+	}
+}
+
+func TestDebugLogBodies(t *testing.T) {
+	// The model echoes both keys back, and the title carries one out
+	openAI := newFakeOpenAI("Leaked test-openai-key and test-news-key")
+	setupTest(t, upstreams(openAI, nil), "DEBUG_LOG_BODIES", "true")
+	logs := captureLogs(t, slog.LevelDebug)
+
+	if rec := postTransform(`{"title": "Lift broken again test-news-key"}`); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	requests := logRecords(t, logs, "OpenAI request body")
+	responses := logRecords(t, logs, "OpenAI response body")
+	if len(requests) != 1 || len(responses) != 1 {
+		t.Fatalf("got %d request and %d response body logs, want 1 each", len(requests), len(responses))
+	}
+	if body, _ := requests[0]["body"].(string); !strings.Contains(body, "Lift broken again [REDACTED]") || !strings.Contains(body, `"messages"`) {
+		t.Errorf("request body = %q", body)
+	}
+	if body, _ := responses[0]["body"].(string); !strings.Contains(body, "Leaked [REDACTED] and [REDACTED]") {
+		t.Errorf("response body = %q", body)
+	}
+	if strings.Contains(logs.String(), "test-openai-key") || strings.Contains(logs.String(), "test-news-key") {
+		t.Errorf("logs leak a key: %q", logs.String())
+	}
+}
+
+func TestDebugLogBodiesOffByDefault(t *testing.T) {
+	setupTest(t, upstreams(newFakeOpenAI("Rations are plentiful"), nil))
+	logs := captureLogs(t, slog.LevelDebug)
+
+	postTransform(`{"title": "Lift broken again"}`)
+	if records := logRecords(t, logs, "OpenAI request body"); len(records) != 0 {
+		t.Errorf("bodies logged without DEBUG_LOG_BODIES: %v", records)
+	}
+}
@@ -1,35 +1,175 @@
-package main
+package ministry
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
 )
 
 // Configuration struct to hold our API keys
 type Config struct {
-	NewsAPIKey   string
-	OpenAIAPIKey string
-	Port         string
+	NewsAPIKey            string
+	OpenAIAPIKey          string
+	OpenAIBaseURL         string
+	AnthropicAPIKey       string
+	LLMProvider           string
+	Port                  string
+	ListenAddr            string
+	SimilarityCheck       bool
+	SimilarityThreshold   float64
+	SimilarityRetry       bool
+	TransformFallback     bool
+	Newspeak              *NewspeakDictionary
+	ModelPricing          map[string]ModelPrice
+	ArchiveDBPath         string
+	PromptsFile           string
+	ServiceAPIKeys        []string
+	SecurityHeaders       map[string]string
+	PrewarmEnabled        bool
+	PrewarmInterval       time.Duration
+	WebhookURL            string
+	BreakerThreshold      int
+	BreakerCooldown       time.Duration
+	ReadTimeout           time.Duration
+	WriteTimeout          time.Duration
+	IdleTimeout           time.Duration
+	MaxBodyBytes          int64
+	GzipMinBytes          int
+	CacheBackend          string
+	RedisURL              string
+	NewsCacheTTL          time.Duration
+	TransformCacheTTL     time.Duration
+	SourcesCacheTTL       time.Duration
+	IdempotencyTTL        time.Duration
+	LogLevel              slog.Level
+	RouteProfiles         map[string]RouteProfile
+	TopicStopwords        map[string]bool
+	TopicLimit            int
+	Sentiment             *SentimentLexicon
+	CursorSecret          []byte
+	SigningSecret         []byte
+	SignedResultTTL       time.Duration
+	DefaultPageSize       int
+	MaxArticles           int
+	TransformMaxChars     int
+	Profanity             *ProfanityFilter
+	OpenAIRetryBudget     time.Duration
+	NewsProvider          string
+	GNewsAPIKey           string
+	Categories            *CategoryClassifier
+	TransformAllBudget    time.Duration
+	UserAgent             string
+	OpenAIMaxConcurrency  int
+	OpenAIConcurrencyWait time.Duration
+	TrustedProxies        []netip.Prefix
+	ServeStaleOnError     bool
+	StaleNewsTTL          time.Duration
+	PreserveTerms         []string
+	DebugLogBodies        bool
+	OpenAIOrgID           string
+	OpenAIProjectID       string
+	OpenAIModelChain      []string
+	TransformSeed         *int64
+	LenientParse          bool
+	DefaultCategory       string
+	Envelope              bool
+	LogSampleRate         float64
+	LogSlowThreshold      time.Duration
+	MaxTransformArticles  int
+	TLSCertFile           string
+	TLSKeyFile            string
+	AllowedCategories     []string
+	TransformMaxInFlight  int
+	TransformQueueDepth   int
+
+	UpstreamDialTimeout           time.Duration
+	UpstreamTLSHandshakeTimeout   time.Duration
+	UpstreamResponseHeaderTimeout time.Duration
 }
 
 // Load configuration from environment variables
 func loadConfig() (*Config, error) {
+	var problems configErrors
+
+	newsProviderName := strings.ToLower(os.Getenv("NEWS_PROVIDER"))
+	if newsProviderName == "" {
+		newsProviderName = "newsapi" // Default provider
+	}
+
 	newsAPIKey := os.Getenv("NEWS_API_KEY")
-	if newsAPIKey == "" {
-		return nil, fmt.Errorf("NEWS_API_KEY environment variable is required")
+	gnewsAPIKey := os.Getenv("GNEWS_API_KEY")
+
+	switch newsProviderName {
+	case "newsapi":
+		if newsAPIKey == "" {
+			problems.add(fmt.Errorf("NEWS_API_KEY environment variable is required"))
+		}
+	case "gnews":
+		if gnewsAPIKey == "" {
+			problems.add(fmt.Errorf("GNEWS_API_KEY environment variable is required when NEWS_PROVIDER=gnews"))
+		}
+	default:
+		problems.add(fmt.Errorf("NEWS_PROVIDER must be one of newsapi, gnews, got %q", newsProviderName))
+	}
+
+	llmProvider := strings.ToLower(os.Getenv("LLM_PROVIDER"))
+	if llmProvider == "" {
+		llmProvider = "openai" // Default provider
 	}
 
 	openAIAPIKey := os.Getenv("OPENAI_API_KEY")
-	if openAIAPIKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY environment variable is required")
+	anthropicAPIKey := os.Getenv("ANTHROPIC_API_KEY")
+
+	userAgent := os.Getenv("HTTP_USER_AGENT")
+	if userAgent == "" {
+		userAgent = defaultUserAgent()
+	} else if strings.ContainsAny(userAgent, "\r\n") {
+		problems.add(fmt.Errorf("HTTP_USER_AGENT must not contain line breaks"))
+	}
+
+	openAIBaseURL := strings.TrimRight(os.Getenv("OPENAI_BASE_URL"), "/")
+	if openAIBaseURL == "" {
+		openAIBaseURL = "https://api.openai.com/v1" // Default endpoint
+	}
+	if parsed, err := url.Parse(openAIBaseURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		problems.add(fmt.Errorf("OPENAI_BASE_URL must be an absolute http(s) URL, got %q", openAIBaseURL))
+	}
+
+	switch llmProvider {
+	case "openai":
+		if openAIAPIKey == "" {
+			problems.add(fmt.Errorf("OPENAI_API_KEY environment variable is required"))
+		}
+	case "anthropic":
+		if anthropicAPIKey == "" {
+			problems.add(fmt.Errorf("ANTHROPIC_API_KEY environment variable is required when LLM_PROVIDER=anthropic"))
+		}
+	default:
+		problems.add(fmt.Errorf("LLM_PROVIDER must be one of openai, anthropic, got %q", llmProvider))
 	}
 
 	port := os.Getenv("PORT")
@@ -37,37 +177,565 @@ func loadConfig() (*Config, error) {
 		port = "8080" // Default port
 	}
 
+	listenAddr, err := listenAddress(os.Getenv("BIND_ADDR"), port)
+	problems.add(err)
+
+	similarityCheck, err := getEnvBool("SIMILARITY_CHECK", false)
+	problems.add(err)
+
+	similarityThreshold, err := getEnvFloat("SIMILARITY_THRESHOLD", 0.8)
+	if err != nil {
+		problems.add(err)
+	} else if similarityThreshold < 0 || similarityThreshold > 1 {
+		problems.add(fmt.Errorf("SIMILARITY_THRESHOLD must be between 0 and 1"))
+	}
+
+	similarityRetry, err := getEnvBool("SIMILARITY_RETRY", false)
+	problems.add(err)
+
+	transformFallback, err := getEnvBool("TRANSFORM_FALLBACK", false)
+	problems.add(err)
+
+	newspeak, err := loadNewspeakDictionary(os.Getenv("NEWSPEAK_DICTIONARY_FILE"))
+	problems.add(err)
+
+	sentiment, err := loadSentimentLexicon(os.Getenv("SENTIMENT_LEXICON_FILE"))
+	problems.add(err)
+
+	categories, err := loadCategoryClassifier(os.Getenv("CATEGORY_KEYWORDS_FILE"))
+	problems.add(err)
+
+	modelPricing, err := parseModelPricing(os.Getenv("MODEL_PRICING"))
+	problems.add(err)
+
+	logLevel, err := parseLogLevel(os.Getenv("LOG_LEVEL"))
+	problems.add(err)
+
+	maxBodyBytes, err := getEnvInt64("MAX_BODY_BYTES", 64<<10)
+	if err != nil {
+		problems.add(err)
+	} else if maxBodyBytes <= 0 {
+		problems.add(fmt.Errorf("MAX_BODY_BYTES must be positive"))
+	}
+
+	gzipMinBytes, err := getEnvInt64("GZIP_MIN_BYTES", 1024)
+	if err != nil {
+		problems.add(err)
+	} else if gzipMinBytes < 0 {
+		problems.add(fmt.Errorf("GZIP_MIN_BYTES must not be negative"))
+	}
+
+	cacheBackend := strings.ToLower(os.Getenv("CACHE_BACKEND"))
+	if cacheBackend == "" {
+		cacheBackend = "memory"
+	}
+	redisURL := os.Getenv("REDIS_URL")
+	switch cacheBackend {
+	case "memory":
+	case "redis":
+		if redisURL == "" {
+			problems.add(fmt.Errorf("REDIS_URL environment variable is required when CACHE_BACKEND is redis"))
+		}
+	default:
+		problems.add(fmt.Errorf("CACHE_BACKEND must be memory or redis, got %q", cacheBackend))
+	}
+
+	newsCacheTTL, err := getEnvDuration("NEWS_CACHE_TTL", 5*time.Minute)
+	problems.add(err)
+
+	transformCacheTTL, err := getEnvDuration("TRANSFORM_CACHE_TTL", 24*time.Hour)
+	problems.add(err)
+
+	// The sources listing rarely changes, so it is kept longer than news
+	sourcesCacheTTL, err := getEnvDuration("SOURCES_CACHE_TTL", 24*time.Hour)
+	problems.add(err)
+
+	// How long links from /api/transform/sign stay valid
+	signedResultTTL, err := getEnvDuration("SIGNED_RESULT_TTL", 15*time.Minute)
+	problems.add(err)
+
+	idempotencyTTL, err := getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+	problems.add(err)
+
+	readTimeout, err := getEnvDuration("SERVER_READ_TIMEOUT", 5*time.Second)
+	problems.add(err)
+
+	writeTimeout, err := getEnvDuration("SERVER_WRITE_TIMEOUT", 15*time.Second)
+	problems.add(err)
+
+	idleTimeout, err := getEnvDuration("SERVER_IDLE_TIMEOUT", 60*time.Second)
+	problems.add(err)
+
+	// Outbound calls: connecting, the TLS handshake and the wait for response
+	// headers are limited separately
+	upstreamDialTimeout, err := getEnvDuration("UPSTREAM_DIAL_TIMEOUT", 10*time.Second)
+	problems.add(err)
+
+	upstreamTLSHandshakeTimeout, err := getEnvDuration("UPSTREAM_TLS_HANDSHAKE_TIMEOUT", 10*time.Second)
+	problems.add(err)
+
+	upstreamResponseHeaderTimeout, err := getEnvDuration("UPSTREAM_RESPONSE_HEADER_TIMEOUT", 2*time.Minute)
+	problems.add(err)
+
+	prewarmEnabled, err := getEnvBool("PREWARM_ENABLED", false)
+	problems.add(err)
+
+	prewarmInterval, err := getEnvDuration("PREWARM_INTERVAL", time.Hour)
+	problems.add(err)
+
+	webhookURL, err := parseWebhookURL(os.Getenv("WEBHOOK_URL"))
+	problems.add(err)
+
+	breakerThreshold, err := getEnvInt64("BREAKER_THRESHOLD", 5)
+	if err != nil {
+		problems.add(err)
+	} else if breakerThreshold < 0 {
+		problems.add(fmt.Errorf("BREAKER_THRESHOLD must not be negative"))
+	}
+
+	breakerCooldown, err := getEnvDuration("BREAKER_COOLDOWN", 30*time.Second)
+	problems.add(err)
+
+	routeProfiles, err := parseRouteProfiles(os.Getenv("ROUTE_PROFILES"), maxBodyBytes)
+	problems.add(err)
+
+	maxArticles, err := getEnvInt64("MAX_ARTICLES", 100)
+	if err != nil {
+		problems.add(err)
+	} else if maxArticles < 1 {
+		problems.add(fmt.Errorf("MAX_ARTICLES must be positive"))
+	}
+
+	defaultPageSize, err := getEnvInt64("DEFAULT_PAGE_SIZE", 20)
+	if err != nil {
+		problems.add(err)
+	} else if defaultPageSize < 1 || defaultPageSize > maxPageSize {
+		problems.add(fmt.Errorf("DEFAULT_PAGE_SIZE must be between 1 and %d", maxPageSize))
+	} else if defaultPageSize > maxArticles {
+		problems.add(fmt.Errorf("DEFAULT_PAGE_SIZE must not exceed MAX_ARTICLES"))
+	}
+
+	transformMaxChars, err := getEnvInt64("TRANSFORM_MAX_CHARS", 200)
+	if err != nil {
+		problems.add(err)
+	} else if transformMaxChars < 0 {
+		problems.add(fmt.Errorf("TRANSFORM_MAX_CHARS must not be negative"))
+	}
+
+	profanityMode, err := parseProfanityMode(os.Getenv("PROFANITY_MODE"))
+	problems.add(err)
+
+	profanity, err := loadProfanityFilter(profanityMode, os.Getenv("PROFANITY_BLOCKLIST_FILE"))
+	problems.add(err)
+
+	openAIRetryBudget, err := getEnvDuration("OPENAI_RETRY_BUDGET", 10*time.Second)
+	problems.add(err)
+
+	transformAllBudget, err := getEnvDuration("TRANSFORM_ALL_BUDGET", 45*time.Second)
+	problems.add(err)
+
+	openAIMaxConcurrency, err := getEnvInt64("OPENAI_MAX_CONCURRENCY", 0)
+	if err != nil {
+		problems.add(err)
+	} else if openAIMaxConcurrency < 0 {
+		problems.add(fmt.Errorf("OPENAI_MAX_CONCURRENCY must not be negative"))
+	}
+
+	openAIConcurrencyWait, err := getEnvDuration("OPENAI_CONCURRENCY_WAIT", 5*time.Second)
+	problems.add(err)
+
+	trustedProxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	problems.add(err)
+
+	serveStaleOnError, err := getEnvBool("SERVE_STALE_ON_ERROR", false)
+	problems.add(err)
+
+	staleNewsTTL, err := getEnvDuration("STALE_NEWS_TTL", 24*time.Hour)
+	problems.add(err)
+
+	preserveTerms := splitList(os.Getenv("PRESERVE_ENTITY_TERMS"))
+
+	debugLogBodies, err := getEnvBool("DEBUG_LOG_BODIES", false)
+	problems.add(err)
+
+	// Billing attribution for enterprise OpenAI accounts
+	openAIOrgID := strings.TrimSpace(os.Getenv("OPENAI_ORG_ID"))
+	openAIProjectID := strings.TrimSpace(os.Getenv("OPENAI_PROJECT_ID"))
+
+	// Cheapest model first; later ones are tried when it fails or returns nothing
+	openAIModelChain := splitList(os.Getenv("OPENAI_MODEL_CHAIN"))
+
+	// Fixed OpenAI seed for reproducible transforms, unless a request sets its own
+	var transformSeed *int64
+	if os.Getenv("TRANSFORM_SEED") != "" {
+		seed, err := getEnvInt64("TRANSFORM_SEED", 0)
+		problems.add(err)
+		transformSeed = &seed
+	}
+
+	lenientParse, err := getEnvBool("LENIENT_PARSE", false)
+	problems.add(err)
+
+	// Category for headlines requests that don't name one
+	defaultCategory := strings.ToLower(os.Getenv("DEFAULT_CATEGORY"))
+	if defaultCategory != "" && !validCategory(defaultCategory) {
+		problems.add(fmt.Errorf("DEFAULT_CATEGORY must be one of %s, got %q", strings.Join(newsCategories, ", "), defaultCategory))
+	}
+
+	// Categories clients may request; empty allows every valid category
+	var allowedCategories []string
+	for _, category := range splitList(strings.ToLower(os.Getenv("ALLOWED_CATEGORIES"))) {
+		if !validCategory(category) {
+			problems.add(fmt.Errorf("ALLOWED_CATEGORIES: invalid category %q; allowed values: %s", category, strings.Join(newsCategories, ", ")))
+			continue
+		}
+		allowedCategories = append(allowedCategories, category)
+	}
+	if defaultCategory != "" && len(allowedCategories) > 0 && !slices.Contains(allowedCategories, defaultCategory) {
+		problems.add(fmt.Errorf("DEFAULT_CATEGORY %q is not in ALLOWED_CATEGORIES", defaultCategory))
+	}
+
+	envelope, err := getEnvBool("ENVELOPE", false)
+	problems.add(err)
+
+	// Fraction of successful, fast requests that get a log line
+	logSampleRate, err := getEnvFloat("LOG_SAMPLE_RATE", 1)
+	if err != nil {
+		problems.add(err)
+	} else if logSampleRate < 0 || logSampleRate > 1 {
+		problems.add(fmt.Errorf("LOG_SAMPLE_RATE must be between 0 and 1"))
+	}
+
+	logSlowMs, err := getEnvInt64("LOG_SLOW_MS", 1000)
+	if err != nil {
+		problems.add(err)
+	} else if logSlowMs < 0 {
+		problems.add(fmt.Errorf("LOG_SLOW_MS must not be negative"))
+	}
+
+	// Most articles one transform-all run sends to the model (0 for no cap)
+	maxTransformArticles, err := getEnvInt64("MAX_TRANSFORM_ARTICLES", 0)
+	if err != nil {
+		problems.add(err)
+	} else if maxTransformArticles < 0 {
+		problems.add(fmt.Errorf("MAX_TRANSFORM_ARTICLES must not be negative"))
+	}
+
+	// Transforms run at once before more wait in a queue (0 for no limit),
+	// and how many may wait before the rest get a 503
+	transformMaxInFlight, err := getEnvInt64("TRANSFORM_MAX_IN_FLIGHT", 0)
+	if err != nil {
+		problems.add(err)
+	} else if transformMaxInFlight < 0 {
+		problems.add(fmt.Errorf("TRANSFORM_MAX_IN_FLIGHT must not be negative"))
+	}
+
+	transformQueueDepth, err := getEnvInt64("TRANSFORM_QUEUE_DEPTH", 20)
+	if err != nil {
+		problems.add(err)
+	} else if transformQueueDepth < 0 {
+		problems.add(fmt.Errorf("TRANSFORM_QUEUE_DEPTH must not be negative"))
+	}
+
+	// Serve HTTPS (and HTTP/2) directly when both are set
+	tlsCertFile, tlsKeyFile := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")
+	problems.add(validateTLSFiles(tlsCertFile, tlsKeyFile))
+
+	topicLimit, err := getEnvInt64("TOPICS_LIMIT", 10)
+	if err != nil {
+		problems.add(err)
+	} else if topicLimit < 1 || topicLimit > maxTopicLimit {
+		problems.add(fmt.Errorf("TOPICS_LIMIT must be between 1 and %d", maxTopicLimit))
+	}
+
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
 	return &Config{
-		NewsAPIKey:   newsAPIKey,
-		OpenAIAPIKey: openAIAPIKey,
-		Port:         port,
+		NewsAPIKey:            newsAPIKey,
+		OpenAIAPIKey:          openAIAPIKey,
+		OpenAIBaseURL:         openAIBaseURL,
+		AnthropicAPIKey:       anthropicAPIKey,
+		LLMProvider:           llmProvider,
+		Port:                  port,
+		ListenAddr:            listenAddr,
+		SimilarityCheck:       similarityCheck,
+		SimilarityThreshold:   similarityThreshold,
+		SimilarityRetry:       similarityRetry,
+		TransformFallback:     transformFallback,
+		Newspeak:              newspeak,
+		ModelPricing:          modelPricing,
+		ArchiveDBPath:         os.Getenv("ARCHIVE_DB_PATH"),
+		PromptsFile:           os.Getenv("PROMPTS_FILE"),
+		ServiceAPIKeys:        parseServiceAPIKeys(os.Getenv("SERVICE_API_KEYS")),
+		SecurityHeaders:       loadSecurityHeaders(),
+		PrewarmEnabled:        prewarmEnabled,
+		PrewarmInterval:       prewarmInterval,
+		WebhookURL:            webhookURL,
+		BreakerThreshold:      int(breakerThreshold),
+		BreakerCooldown:       breakerCooldown,
+		ReadTimeout:           readTimeout,
+		WriteTimeout:          writeTimeout,
+		IdleTimeout:           idleTimeout,
+		MaxBodyBytes:          maxBodyBytes,
+		GzipMinBytes:          int(gzipMinBytes),
+		CacheBackend:          cacheBackend,
+		RedisURL:              redisURL,
+		NewsCacheTTL:          newsCacheTTL,
+		TransformCacheTTL:     transformCacheTTL,
+		SourcesCacheTTL:       sourcesCacheTTL,
+		IdempotencyTTL:        idempotencyTTL,
+		LogLevel:              logLevel,
+		RouteProfiles:         routeProfiles,
+		TopicStopwords:        parseTopicStopwords(os.Getenv("TOPIC_STOPWORDS")),
+		TopicLimit:            int(topicLimit),
+		Sentiment:             sentiment,
+		CursorSecret:          cursorSecret(os.Getenv("CURSOR_SECRET"), newsAPIKey+gnewsAPIKey),
+		SigningSecret:         []byte(os.Getenv("SIGNING_SECRET")),
+		SignedResultTTL:       signedResultTTL,
+		DefaultPageSize:       int(defaultPageSize),
+		MaxArticles:           int(maxArticles),
+		TransformMaxChars:     int(transformMaxChars),
+		Profanity:             profanity,
+		OpenAIRetryBudget:     openAIRetryBudget,
+		NewsProvider:          newsProviderName,
+		GNewsAPIKey:           gnewsAPIKey,
+		Categories:            categories,
+		TransformAllBudget:    transformAllBudget,
+		UserAgent:             userAgent,
+		OpenAIMaxConcurrency:  int(openAIMaxConcurrency),
+		OpenAIConcurrencyWait: openAIConcurrencyWait,
+		TrustedProxies:        trustedProxies,
+		ServeStaleOnError:     serveStaleOnError,
+		StaleNewsTTL:          staleNewsTTL,
+		PreserveTerms:         preserveTerms,
+		DebugLogBodies:        debugLogBodies,
+		OpenAIOrgID:           openAIOrgID,
+		OpenAIProjectID:       openAIProjectID,
+		OpenAIModelChain:      openAIModelChain,
+		TransformSeed:         transformSeed,
+		LenientParse:          lenientParse,
+		DefaultCategory:       defaultCategory,
+		Envelope:              envelope,
+		LogSampleRate:         logSampleRate,
+		LogSlowThreshold:      time.Duration(logSlowMs) * time.Millisecond,
+		MaxTransformArticles:  int(maxTransformArticles),
+		TLSCertFile:           tlsCertFile,
+		TLSKeyFile:            tlsKeyFile,
+		AllowedCategories:     allowedCategories,
+		TransformMaxInFlight:  int(transformMaxInFlight),
+		TransformQueueDepth:   int(transformQueueDepth),
+
+		UpstreamDialTimeout:           upstreamDialTimeout,
+		UpstreamTLSHandshakeTimeout:   upstreamTLSHandshakeTimeout,
+		UpstreamResponseHeaderTimeout: upstreamResponseHeaderTimeout,
 	}, nil
 }
 
-// Global config variable
-var config *Config
+// Every problem found while loading configuration, reported together
+type configErrors []error
+
+func (e *configErrors) add(err error) {
+	if err != nil {
+		*e = append(*e, err)
+	}
+}
+
+func (e configErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%d configuration problems: %s", len(e), strings.Join(messages, "; "))
+}
+
+// Read an optional boolean environment variable
+func getEnvBool(key string, defaultValue bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean, got %q", key, value)
+	}
+	return parsed, nil
+}
+
+// Read an optional integer environment variable
+func getEnvInt64(key string, defaultValue int64) (int64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer, got %q", key, value)
+	}
+	return parsed, nil
+}
+
+// Read an optional floating point environment variable
+func getEnvFloat(key string, defaultValue float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number, got %q", key, value)
+	}
+	return parsed, nil
+}
+
+// Read an optional positive duration environment variable, e.g. "5m"
+func getEnvDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration, got %q", key, value)
+	}
+	return parsed, nil
+}
+
+// Listen address for BIND_ADDR (empty for all interfaces) and PORT
+func listenAddress(bindAddr, port string) (string, error) {
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("PORT must be a number between 1 and 65535, got %q", port)
+	}
+
+	bindAddr = strings.Trim(bindAddr, "[]")
+	if bindAddr != "" && net.ParseIP(bindAddr) == nil && !validHostname(bindAddr) {
+		return "", fmt.Errorf("BIND_ADDR must be an IP address or hostname, got %q", bindAddr)
+	}
+
+	return net.JoinHostPort(bindAddr, port), nil
+}
+
+// Whether s is a plausible DNS hostname such as localhost or api.internal
+func validHostname(s string) bool {
+	if len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// How long in-flight requests get to finish on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// HTTP server for the configured port and timeouts
+func newServer(cfg *Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         cfg.ListenAddr,
+		Handler:      handler,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+}
+
+// Check TLS_CERT_FILE and TLS_KEY_FILE: both or neither, naming files that
+// exist and hold a matching certificate and key
+func validateTLSFiles(certFile, keyFile string) error {
+	if certFile == "" && keyFile == "" {
+		return nil
+	}
+	if certFile == "" || keyFile == "" {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	for name, path := range map[string]string{"TLS_CERT_FILE": certFile, "TLS_KEY_FILE": keyFile} {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE don't form a valid key pair: %v", err)
+	}
+	return nil
+}
+
+// Serve over TLS when a certificate is configured, which also enables
+// HTTP/2, and plain HTTP otherwise
+func serve(server *http.Server, cfg *Config) error {
+	if cfg.TLSCertFile != "" {
+		return server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	return server.ListenAndServe()
+}
+
+// Active configuration; replaced as a whole by POST /api/admin/reload
+var activeConfig atomic.Pointer[Config]
+
+func currentConfig() *Config {
+	return activeConfig.Load()
+}
+
+// Country every top-headlines request is made for
+const headlinesCountry = "us"
+
+// Categories supported by NewsAPI's top-headlines endpoint
+var newsCategories = []string{"business", "entertainment", "general", "health", "science", "sports", "technology"}
+
+func validCategory(category string) bool {
+	for _, c := range newsCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
 
 // API response structures
 type NewsResponse struct {
-	Status       string    `json:"status"`
-	TotalResults int       `json:"totalResults"`
-	Articles     []Article `json:"articles"`
+	XMLName      xml.Name  `json:"-" xml:"newsResponse"`
+	Status       string    `json:"status" xml:"status"`
+	TotalResults int       `json:"totalResults" xml:"totalResults"`
+	Articles     []Article `json:"articles" xml:"articles>article"`
+	NextCursor   string    `json:"nextCursor,omitempty" xml:"nextCursor,omitempty"`
+	Truncated    bool      `json:"truncated,omitempty" xml:"truncated,omitempty"`
+	Stale        bool      `json:"stale,omitempty" xml:"stale,omitempty"`
 }
 
 type Article struct {
-	Source      Source `json:"source"`
-	Author      string `json:"author"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	URL         string `json:"url"`
-	URLToImage  string `json:"urlToImage"`
-	PublishedAt string `json:"publishedAt"`
-	Content     string `json:"content"`
+	Source           Source `json:"source" xml:"source"`
+	Author           string `json:"author" xml:"author"`
+	Title            string `json:"title" xml:"title"`
+	Description      string `json:"description" xml:"description"`
+	URL              string `json:"url" xml:"url"`
+	URLToImage       string `json:"urlToImage" xml:"urlToImage"`
+	PublishedAt      string `json:"publishedAt" xml:"publishedAt"`
+	Content          string `json:"content" xml:"content"`
+	Category         string `json:"category,omitempty" xml:"category,omitempty"`
+	InferredCategory string `json:"inferredCategory,omitempty" xml:"inferredCategory,omitempty"`
+	FromCategory     bool   `json:"fromCategory,omitempty" xml:"fromCategory,omitempty"`
+	MatchedQuery     bool   `json:"matchedQuery,omitempty" xml:"matchedQuery,omitempty"`
+	Supplemented     bool   `json:"supplemented,omitempty" xml:"supplemented,omitempty"`
 }
 
 type Source struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID   string `json:"id" xml:"id"`
+	Name string `json:"name" xml:"name"`
 }
 
 type OpenAIRequest struct {
@@ -75,119 +743,569 @@ type OpenAIRequest struct {
 	Messages    []Message `json:"messages"`
 	MaxTokens   int       `json:"max_tokens"`
 	Temperature float64   `json:"temperature"`
+	Stream      bool      `json:"stream,omitempty"`
+	Seed        *int64    `json:"seed,omitempty"`
+}
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type OpenAIResponse struct {
+	Choices []Choice    `json:"choices"`
+	Usage   OpenAIUsage `json:"usage"`
+}
+
+type OpenAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type Choice struct {
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason"` // "stop", or "length" when cut off at max_tokens
+}
+
+// Chunk of a streamed chat completion
+type OpenAIStreamChunk struct {
+	Choices []struct {
+		Delta Message `json:"delta"`
+	} `json:"choices"`
+}
+
+// Response returned by the transform endpoint
+type TransformResponse struct {
+	TransformedContent string             `json:"transformedContent"`
+	Similarity         *float64           `json:"similarity,omitempty"`
+	TooSimilar         bool               `json:"tooSimilar,omitempty"`
+	Source             string             `json:"source"`
+	TokensUsed         int                `json:"tokensUsed"`
+	EstimatedCostUSD   float64            `json:"estimatedCostUSD"`
+	Sentiment          *SentimentAnalysis `json:"sentiment,omitempty"`
+	Truncated          bool               `json:"truncated"`
+	TargetLang         string             `json:"targetLang,omitempty"`
+	TranslatedContent  string             `json:"translatedContent,omitempty"`
+	MissingEntities    []string           `json:"missingEntities,omitempty"`
+	Model              string             `json:"model,omitempty"`
+	FinishReason       string             `json:"finishReason,omitempty"`
+}
+
+// CORS middleware for API access
+func corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, Idempotency-Key, X-API-Key")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, Idempotent-Replayed")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Fetch news from NewsAPI using environment variable
+func fetchNews(ctx context.Context, endpoint string) (*NewsResponse, error) {
+	body, err := fetchNewsAPI(ctx, endpoint, currentConfig().NewsCacheTTL)
+	stale := false
+	if err != nil {
+		// Fall back to the last good copy while NewsAPI is down
+		if !currentConfig().ServeStaleOnError || !newsUpstreamDown(err) {
+			return nil, err
+		}
+		cached, ok, cacheErr := newsCache.Get(ctx, staleNewsKey(endpoint))
+		if cacheErr != nil || !ok {
+			return nil, err
+		}
+		slog.WarnContext(ctx, "NewsAPI unavailable, serving stale news", "endpoint", endpoint, "error", err)
+		body, stale = cached, true
+	}
+
+	var newsResponse NewsResponse
+	if err := json.Unmarshal(body, &newsResponse); err != nil {
+		// LENIENT_PARSE: keep whatever articles came through intact
+		salvaged, ok := salvageNewsResponse(body)
+		if !currentConfig().LenientParse || !ok {
+			return nil, fmt.Errorf("failed to parse JSON response: %v", err)
+		}
+		slog.WarnContext(ctx, "malformed NewsAPI response, using salvaged articles", "articles", len(salvaged.Articles), "error", err)
+		newsResponse = salvaged
+	}
+
+	slog.DebugContext(ctx, "parsed NewsAPI response", "articles", len(newsResponse.Articles))
+	truncateArticles(&newsResponse, currentConfig().MaxArticles)
+	newsResponse.Stale = stale
+	return &newsResponse, nil
+}
+
+// GET a NewsAPI endpoint, serving from and filling the news cache, where
+// fresh responses are kept for ttl
+func fetchNewsAPI(ctx context.Context, endpoint string, ttl time.Duration) ([]byte, error) {
+	// Serve from the news cache when a fresh copy exists
+	if data, ok, err := newsCache.Get(ctx, endpoint); err != nil {
+		slog.WarnContext(ctx, "news cache get failed", "error", err)
+	} else if ok {
+		slog.DebugContext(ctx, "news cache hit", "endpoint", endpoint)
+		recordCacheLookup(ctx, "newsapi", true)
+		return data, nil
+	}
+	recordCacheLookup(ctx, "newsapi", false)
+
+	separator := "?"
+	if strings.Contains(endpoint, "?") {
+		separator = "&"
+	}
+	url := fmt.Sprintf("https://newsapi.org/v2%s%sapiKey=%s", endpoint, separator, currentConfig().NewsAPIKey)
+
+	// Log request with masked API key for security
+	maskedURL := strings.Replace(url, currentConfig().NewsAPIKey, "[REDACTED]", 1)
+	slog.DebugContext(ctx, "making NewsAPI request", "url", maskedURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	setUserAgent(req, currentConfig().UserAgent)
+
+	start := time.Now()
+	resp, err := (&http.Client{Transport: upstreamTransport}).Do(req)
+	if err != nil {
+		err = fmt.Errorf("failed to fetch news: %w", redactURLError(err, currentConfig().NewsAPIKey))
+		recordNewsHealth(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	recordUpstream(ctx, "newsapi", time.Since(start))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	slog.DebugContext(ctx, "NewsAPI response", "status", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		slog.WarnContext(ctx, "NewsAPI error", "status", resp.StatusCode)
+		err := newsAPIStatusError(resp.StatusCode, body)
+		recordNewsHealth(err)
+		return nil, err
+	}
+	recordNewsHealth(nil)
+
+	// Don't cache a truncated or otherwise broken body
+	if !json.Valid(body) {
+		return body, nil
+	}
+
+	if err := newsCache.Set(ctx, endpoint, body, ttl); err != nil {
+		slog.WarnContext(ctx, "news cache set failed", "error", err)
+	}
+	if currentConfig().ServeStaleOnError {
+		if err := newsCache.Set(ctx, staleNewsKey(endpoint), body, currentConfig().StaleNewsTTL); err != nil {
+			slog.WarnContext(ctx, "news cache set failed", "error", err)
+		}
+	}
+
+	return body, nil
+}
+
+// Pick the news response format from the format query parameter or Accept header
+func responseFormat(r *http.Request) (string, error) {
+	switch format := strings.ToLower(r.URL.Query().Get("format")); format {
+	case "json", "xml":
+		return format, nil
+	case "":
+	default:
+		return "", fmt.Errorf("Invalid format %q; allowed values: json, xml", format)
+	}
+
+	accept := r.Header.Get("Accept")
+	if (strings.Contains(accept, "application/xml") || strings.Contains(accept, "text/xml")) && !strings.Contains(accept, "application/json") {
+		return "xml", nil
+	}
+	return "json", nil
+}
+
+// Write a news response in the requested format, keeping only fields when given
+func writeNewsResponse(w http.ResponseWriter, r *http.Request, format string, fields []string, newsResponse *NewsResponse) {
+	if newsResponse.Stale {
+		w.Header().Set("Warning", `110 - "Response is Stale"`)
+	}
+	if len(fields) > 0 {
+		writeJSONWithETag(w, r, enveloped(r.Context(), projectNewsResponse(newsResponse, fields)))
+		return
+	}
+	if format != "xml" {
+		writeJSONWithETag(w, r, enveloped(r.Context(), newsResponse))
+		return
+	}
+
+	body, err := xml.Marshal(newsResponse)
+	if err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+	writeWithETag(w, r, "application/xml; charset=utf-8", append([]byte(xml.Header), body...))
+}
+
+// Write v as JSON with a strong ETag, answering 304 when the client already has it
+func writeJSONWithETag(w http.ResponseWriter, r *http.Request, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+	writeWithETag(w, r, "application/json", append(body, '\n'))
+}
+
+// Write body with a strong ETag, answering 304 when the client already has it
+func writeWithETag(w http.ResponseWriter, r *http.Request, contentType string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	w.Header().Set("ETag", etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Write(body)
+}
+
+// Whether an If-None-Match header value matches etag
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Returned for a valid category outside ALLOWED_CATEGORIES
+var ErrCategoryNotAllowed = errors.New("Category not allowed")
+
+// Check a requested category is valid and, when ALLOWED_CATEGORIES is set,
+// on the allowlist
+func checkCategory(category string, allowed []string) error {
+	if !validCategory(category) {
+		return fmt.Errorf("Invalid category %q; allowed values: %s", category, strings.Join(newsCategories, ", "))
+	}
+	if len(allowed) > 0 && !slices.Contains(allowed, category) {
+		return fmt.Errorf("%w: %q; allowed categories: %s", ErrCategoryNotAllowed, category, strings.Join(allowed, ", "))
+	}
+	return nil
+}
+
+// HTTP status for a rejected query: 403 for a category outside the
+// allowlist, 400 for anything else
+func queryErrorStatus(err error) int {
+	if errors.Is(err, ErrCategoryNotAllowed) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// Build the top headlines query for the request's query parameters
+func headlinesQuery(query url.Values, cfg *Config) (HeadlinesQuery, error) {
+	category := strings.ToLower(query.Get("category"))
+	if category != "" {
+		if err := checkCategory(category, cfg.AllowedCategories); err != nil {
+			return HeadlinesQuery{}, err
+		}
+	}
+
+	pageSize, err := pageSizeParam(query, cfg)
+	if err != nil {
+		return HeadlinesQuery{}, err
+	}
+
+	minArticles, err := minArticlesParam(query, cfg)
+	if err != nil {
+		return HeadlinesQuery{}, err
+	}
+
+	// NewsAPI rejects sources combined with country/category
+	sources := splitList(query.Get("sources"))
+	if len(sources) > 0 {
+		if category != "" {
+			return HeadlinesQuery{}, fmt.Errorf("sources cannot be combined with category")
+		}
+		return HeadlinesQuery{Sources: sources, PageSize: pageSize}, nil
+	}
+
+	if category == "" && cfg.DefaultCategory != "" {
+		return HeadlinesQuery{Country: headlinesCountry, Category: cfg.DefaultCategory, PageSize: pageSize, CategoryDefaulted: true, MinArticles: minArticles}, nil
+	}
+	return HeadlinesQuery{Country: headlinesCountry, Category: category, PageSize: pageSize, MinArticles: minArticles}, nil
+}
+
+// Top headlines from provider. When the category was defaulted and came
+// back empty, try again without it for the general feed; a category with
+// fewer than MinArticles is topped up from the general feed.
+func fetchTopHeadlines(ctx context.Context, provider NewsProvider, query HeadlinesQuery) (*NewsResponse, error) {
+	newsResponse, err := provider.TopHeadlines(ctx, query)
+	if err == nil && query.CategoryDefaulted && len(newsResponse.Articles) == 0 {
+		slog.InfoContext(ctx, "no headlines in default category, falling back to general feed", "category", query.Category)
+		query.Category, query.CategoryDefaulted = "", false
+		newsResponse, err = provider.TopHeadlines(ctx, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return supplementHeadlines(ctx, provider, query, newsResponse), nil
 }
 
-type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+// Largest pageSize NewsAPI accepts
+const maxPageSize = 100
+
+// Error for a pageSize outside 1..maxPageSize, rejected here rather than
+// forwarded to NewsAPI
+func pageSizeError() error {
+	return fmt.Errorf("pageSize must be an integer between 1 and %d; NewsAPI returns at most %d articles per page", maxPageSize, maxPageSize)
 }
 
-type OpenAIResponse struct {
-	Choices []Choice `json:"choices"`
+// Parse ?pageSize=, defaulting to DEFAULT_PAGE_SIZE and clamped to MAX_ARTICLES
+func pageSizeParam(query url.Values, cfg *Config) (int, error) {
+	raw := query.Get("pageSize")
+	if raw == "" {
+		return cfg.DefaultPageSize, nil
+	}
+	pageSize, err := strconv.Atoi(raw)
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		return 0, pageSizeError()
+	}
+	return min(pageSize, cfg.MaxArticles), nil
 }
 
-type Choice struct {
-	Message Message `json:"message"`
+// Cut a response down to at most max articles, flagging that it was truncated.
+// TotalResults still reports NewsAPI's total.
+func truncateArticles(newsResponse *NewsResponse, max int) {
+	if max > 0 && len(newsResponse.Articles) > max {
+		newsResponse.Articles = newsResponse.Articles[:max]
+		newsResponse.Truncated = true
+	}
 }
 
-// CORS middleware for API access
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+// Longest q NewsAPI accepts
+const maxSearchQueryLength = 500
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+// Check a search query using NewsAPI's boolean syntax (AND, OR, NOT, +/-,
+// "exact phrases" and parentheses) and collapse runs of whitespace
+func normalizeSearchQuery(q string) (string, error) {
+	q = strings.Join(strings.Fields(q), " ")
+	if utf8.RuneCountInString(q) > maxSearchQueryLength {
+		return "", fmt.Errorf("Query parameter 'q' must be at most %d characters", maxSearchQueryLength)
+	}
 
-		log.Printf("%s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
-		next.ServeHTTP(w, r)
-	})
-}
+	// Track nesting outside quoted phrases, keeping that text for the operator checks
+	var unquoted strings.Builder
+	depth := 0
+	inQuote := false
+	for _, c := range q {
+		switch {
+		case c == '"':
+			inQuote = !inQuote
+			unquoted.WriteString(" x ")
+			continue
+		case inQuote:
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return "", fmt.Errorf("Invalid query: ')' without a matching '('")
+			}
+		}
+		unquoted.WriteRune(c)
+	}
+	if inQuote {
+		return "", fmt.Errorf("Invalid query: unbalanced double quotes")
+	}
+	if depth > 0 {
+		return "", fmt.Errorf("Invalid query: '(' without a matching ')'")
+	}
+	if strings.Contains(strings.ReplaceAll(unquoted.String(), " ", ""), "()") {
+		return "", fmt.Errorf("Invalid query: empty parentheses")
+	}
 
-// Fetch news from NewsAPI using environment variable
-func fetchNews(endpoint string) (*NewsResponse, error) {
-	url := fmt.Sprintf("https://newsapi.org/v2%s&apiKey=%s", endpoint, config.NewsAPIKey)
+	// Operators need a term on each side (NOT only after it)
+	words := strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(unquoted.String()))
+	if len(words) > 0 {
+		first, last := words[0], words[len(words)-1]
+		if first == "AND" || first == "OR" {
+			return "", fmt.Errorf("Invalid query: %s needs a term before it", first)
+		}
+		if last == "AND" || last == "OR" || last == "NOT" {
+			return "", fmt.Errorf("Invalid query: %s needs a term after it", last)
+		}
+	}
 
-	// Log request with masked API key for security
-	maskedURL := strings.Replace(url, config.NewsAPIKey, "[REDACTED]", 1)
-	log.Printf("Making request to: %s", maskedURL)
+	return q, nil
+}
 
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch news: %v", err)
+// NewsAPI /everything parameters from q plus optional domains and
+// excludeDomains (comma-separated hostnames)
+func searchParams(query url.Values) (url.Values, error) {
+	q := query.Get("q")
+	if strings.TrimSpace(q) == "" {
+		return nil, fmt.Errorf("Query parameter 'q' is required")
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	q, err := normalizeSearchQuery(q)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		return nil, err
 	}
 
-	log.Printf("NewsAPI response status: %d", resp.StatusCode)
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("NewsAPI error - status: %d", resp.StatusCode)
-		return nil, fmt.Errorf("NewsAPI returned status %d", resp.StatusCode)
+	params := url.Values{}
+	params.Set("q", q)
+	for _, name := range []string{"domains", "excludeDomains"} {
+		domains, err := parseDomains(name, strings.Split(query.Get(name), ","))
+		if err != nil {
+			return nil, err
+		}
+		if len(domains) > 0 {
+			params.Set(name, strings.Join(domains, ","))
+		}
 	}
 
-	var newsResponse NewsResponse
-	if err := json.Unmarshal(body, &newsResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %v", err)
-	}
+	return params, nil
+}
 
-	log.Printf("Successfully parsed %d articles", len(newsResponse.Articles))
-	return &newsResponse, nil
+// Lowercased hostnames from a domains filter, skipping blanks
+func parseDomains(name string, entries []string) ([]string, error) {
+	var domains []string
+	for _, domain := range entries {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" {
+			continue
+		}
+		if !strings.Contains(domain, ".") || !validHostname(domain) {
+			return nil, fmt.Errorf("Invalid %s entry %q; expected a hostname like bbc.co.uk", name, domain)
+		}
+		domains = append(domains, domain)
+	}
+	return domains, nil
 }
 
 // Get top headlines endpoint
 func getTopHeadlines(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	category := r.URL.Query().Get("category")
-	var endpoint string
+	format, err := responseFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	if category != "" {
-		endpoint = fmt.Sprintf("/top-headlines?country=us&category=%s", category)
-	} else {
-		endpoint = "/top-headlines?country=us"
+	fields, err := parseArticleFields(r.URL.Query().Get("fields"), format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := parseArticleSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	groupBy, err := parseGroupBy(r.URL.Query().Get("groupBy"), format, fields)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	newsQuery, err := headlinesQuery(r.URL.Query(), currentConfig())
+	if err != nil {
+		http.Error(w, err.Error(), queryErrorStatus(err))
+		return
 	}
 
-	newsResponse, err := fetchNews(endpoint)
+	newsResponse, err := fetchTopHeadlines(r.Context(), currentNewsProvider(), newsQuery)
 	if err != nil {
-		log.Printf("Error fetching news: %v", err)
-		http.Error(w, fmt.Sprintf("Error fetching news: %v", err), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "error fetching news", "error", err)
+		http.Error(w, fmt.Sprintf("Error fetching news: %v", err), newsErrorStatus(err))
 		return
 	}
 
-	json.NewEncoder(w).Encode(newsResponse)
+	// ?inferCategory=true tags each article with a keyword-based category
+	if infer, _ := strconv.ParseBool(r.URL.Query().Get("inferCategory")); infer {
+		currentConfig().Categories.Tag(newsResponse.Articles)
+	}
+
+	sortArticles(newsResponse.Articles, order)
+
+	// ?groupBy=source keys the articles by outlet
+	if groupBy == "source" {
+		if newsResponse.Stale {
+			w.Header().Set("Warning", `110 - "Response is Stale"`)
+		}
+		writeJSONWithETag(w, r, enveloped(r.Context(), groupBySource(newsResponse)))
+		return
+	}
+	writeNewsResponse(w, r, format, fields, newsResponse)
 }
 
 // Search news endpoint
 func searchNews(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	query := r.URL.Query().Get("q")
-	if query == "" {
-		http.Error(w, "Query parameter 'q' is required", http.StatusBadRequest)
+	format, err := responseFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fields, err := parseArticleFields(r.URL.Query().Get("fields"), format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := parseArticleSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params, err := searchPosition(r.URL.Query(), currentConfig())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	endpoint := fmt.Sprintf("/everything?q=%s", query)
-	newsResponse, err := fetchNews(endpoint)
+	newsResponse, err := currentNewsProvider().Search(r.Context(), searchQueryFrom(params))
 	if err != nil {
-		log.Printf("Error searching news: %v", err)
-		http.Error(w, fmt.Sprintf("Error searching news: %v", err), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "error searching news", "error", err)
+		http.Error(w, fmt.Sprintf("Error searching news: %v", err), newsErrorStatus(err))
 		return
 	}
+	newsResponse.NextCursor = nextSearchCursor(params, newsResponse.TotalResults, currentConfig().CursorSecret)
+
+	// ?inferCategory=true tags each article with a keyword-based category
+	if infer, _ := strconv.ParseBool(r.URL.Query().Get("inferCategory")); infer {
+		currentConfig().Categories.Tag(newsResponse.Articles)
+	}
 
-	json.NewEncoder(w).Encode(newsResponse)
+	sortArticles(newsResponse.Articles, order)
+	writeNewsResponse(w, r, format, fields, newsResponse)
 }
 
-// Transform news using OpenAI API
+// Transform news endpoint
 func transformNews(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Vary", "Accept")
 
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -197,72 +1315,417 @@ func transformNews(w http.ResponseWriter, r *http.Request) {
 	var requestData struct {
 		Title       string `json:"title"`
 		Description string `json:"description"`
+		Intensity   string `json:"intensity"`
+		Persona     string `json:"persona"`
+		TimeoutMs   *int64 `json:"timeoutMs"`
+		TargetLang  string `json:"targetLang"`
+
+		PreserveEntities bool   `json:"preserveEntities"`
+		Seed             *int64 `json:"seed"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+	r.Body = http.MaxBytesReader(w, r.Body, currentConfig().MaxBodyBytes)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("Request body exceeds %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Error reading request body", http.StatusBadRequest)
+		return
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		http.Error(w, "Request body is required", http.StatusBadRequest)
+		return
+	}
+	if err := json.Unmarshal(body, &requestData); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
+	requestData.Title = strings.TrimSpace(requestData.Title)
+	requestData.Description = strings.TrimSpace(requestData.Description)
+	if err := validateTransformInput(requestData.Title, requestData.Description); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	systemPrompt := "You are the Ministry of Truth from George Orwell's 1984. Transform news headlines and descriptions into dystopian propaganda using doublespeak, references to Big Brother, the Party, thoughtcrime, etc. Keep responses under 200 characters."
-
-	openAIRequest := OpenAIRequest{
-		Model: "gpt-3.5-turbo",
-		Messages: []Message{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: fmt.Sprintf("Transform this news: Title: %s, Description: %s", requestData.Title, requestData.Description)},
-		},
-		MaxTokens:   200,
-		Temperature: 0.9,
+	// Replay the stored response when a retried request reuses its Idempotency-Key
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		http.Error(w, fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength), http.StatusBadRequest)
+		return
+	}
+	bodyHash := hashBody(body)
+	if idempotencyKey != "" {
+		stored, ok, err := lookupIdempotent(r.Context(), idempotencyCache, idempotencyKey, bodyHash)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if ok {
+			w.Header().Set("Idempotent-Replayed", "true")
+			if content, ok := storedTransformContent(stored); ok && wantsPlainText(r) {
+				writePlainTransform(w, content)
+				return
+			}
+			w.Write(stored)
+			return
+		}
 	}
 
-	jsonData, err := json.Marshal(openAIRequest)
+	options, err := resolveTransformOptions(requestData.Intensity, requestData.Persona)
 	if err != nil {
-		http.Error(w, "Error creating request", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	options.PreserveEntities = requestData.PreserveEntities
+	options.Seed = requestData.Seed
+	options.Language = promptLanguage(r.Header.Get("Accept-Language"))
+	w.Header().Add("Vary", "Accept-Language")
+	if options.Seed == nil {
+		options.Seed = currentConfig().TransformSeed
+	}
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", strings.NewReader(string(jsonData)))
-	if err != nil {
-		http.Error(w, "Error creating request", http.StatusInternalServerError)
+	targetLang := strings.ToLower(strings.TrimSpace(requestData.TargetLang))
+	if targetLang != "" {
+		if _, ok := translationLanguages[targetLang]; !ok {
+			http.Error(w, fmt.Sprintf("Invalid targetLang %q; allowed values: %s", requestData.TargetLang, strings.Join(sortedKeys(translationLanguages), ", ")), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Optional per-request deadline for the model call, clamped to the route's timeout
+	var timeout time.Duration
+	if requestData.TimeoutMs != nil {
+		timeout, err = transformTimeout(*requestData.TimeoutMs, routeProfileFor("/api/transform").Timeout)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Collect token usage from every LLM call made for this request
+	recorder := &usageRecorder{}
+	ctx := withUsageRecorder(withTransformOptions(r.Context(), options), recorder)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, ErrCallerTimeout)
+		defer cancel()
+	}
+
+	// ?dryRun=true returns the upstream request instead of sending it
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun")); dryRun {
+		runner, ok := currentTransformer().(DryRunner)
+		if !ok {
+			http.Error(w, ErrDryRunUnsupported.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err := runner.DryRun(ctx, requestData.Title, requestData.Description)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result.DryRun = true
+		json.NewEncoder(w).Encode(result)
 		return
 	}
 
-	// Use environment variable for API key
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", config.OpenAIAPIKey))
-	req.Header.Set("Content-Type", "application/json")
+	// Reuse an earlier LLM result for the same input when cached
+	cacheKey := transformCacheKey(currentConfig().LLMProvider, options, requestData.Title, requestData.Description)
+	cached, hit := getCachedTransform(r.Context(), cacheKey)
+
+	source := cached.Source
+	transformed := cached.Content
+	model, finishReason := cached.Model, cached.FinishReason
+	if !hit {
+		var err error
+		source = currentConfig().LLMProvider
+		transformed, err = transformOnce(ctx, cacheKey, requestData.Title, requestData.Description)
+		if err != nil && currentConfig().TransformFallback {
+			slog.WarnContext(r.Context(), "transform failed, using local fallback", "error", err)
+			source = "fallback"
+			transformed, err = LocalTransformer{Dictionary: currentConfig().Newspeak}.Transform(r.Context(), requestData.Title, requestData.Description)
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "transform error", "error", err)
+			writeTransformError(w, err)
+			return
+		}
+		model, finishReason = servedModel(ctx), servedFinishReason(ctx)
+	}
+
+	response := TransformResponse{TransformedContent: transformed, Source: source, Model: model, FinishReason: finishReason}
+
+	// Optionally make sure the output isn't just an echo of the input
+	if currentConfig().SimilarityCheck {
+		original := requestData.Title + " " + requestData.Description
+		similarity := jaccardSimilarity(original, transformed)
+
+		if similarity >= currentConfig().SimilarityThreshold && currentConfig().SimilarityRetry {
+			slog.InfoContext(r.Context(), "transform too similar to original, retrying", "similarity", similarity)
+			if retried, err := currentTransformer().Transform(ctx, requestData.Title, requestData.Description); err == nil {
+				transformed = retried
+				similarity = jaccardSimilarity(original, transformed)
+				source = currentConfig().LLMProvider
+				response.Source = source
+				response.Model, response.FinishReason = servedModel(ctx), servedFinishReason(ctx)
+				setCachedTransform(r.Context(), cacheKey, cachedTransform{Content: transformed, Source: source, Model: response.Model, FinishReason: response.FinishReason})
+			} else {
+				slog.ErrorContext(r.Context(), "transform retry error", "error", err)
+			}
+		}
+
+		response.TransformedContent = transformed
+		response.Similarity = &similarity
+		response.TooSimilar = similarity >= currentConfig().SimilarityThreshold
+	}
+
+	// preserveEntities: check names from the original survived, retrying once if not
+	if options.PreserveEntities {
+		entities := extractEntities([]string{requestData.Title, requestData.Description}, currentConfig().TopicStopwords, currentConfig().PreserveTerms)
+		var retried bool
+		response.TransformedContent, response.MissingEntities, retried = ensureEntities(ctx, currentTransformer(), requestData.Title, requestData.Description, response.TransformedContent, entities)
+		if retried {
+			response.Source = currentConfig().LLMProvider
+			response.Model, response.FinishReason = servedModel(ctx), servedFinishReason(ctx)
+			setCachedTransform(r.Context(), cacheKey, cachedTransform{Content: response.TransformedContent, Source: response.Source, Model: response.Model, FinishReason: response.FinishReason})
+		}
+	}
+
+	// Enforce TRANSFORM_MAX_CHARS even when the model ignores the prompt's length hint
+	response.TransformedContent, response.Truncated = truncateText(response.TransformedContent, currentConfig().TransformMaxChars)
+
+	// Render the English result in targetLang with a second model call
+	if targetLang != "" {
+		translator, ok := currentTransformer().(Translator)
+		if !ok {
+			http.Error(w, ErrTranslationUnsupported.Error(), http.StatusBadRequest)
+			return
+		}
+		translated, err := translator.Translate(ctx, response.TransformedContent, translationLanguages[targetLang])
+		if err != nil {
+			slog.ErrorContext(r.Context(), "translation error", "targetLang", targetLang, "error", err)
+			writeTransformError(w, err)
+			return
+		}
+		response.TargetLang = targetLang
+
+		// The translation is held to the same limit as the English text
+		var trimmed bool
+		response.TranslatedContent, trimmed = truncateText(translated, currentConfig().TransformMaxChars)
+		response.Truncated = response.Truncated || trimmed
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// Screen user-facing output against the PROFANITY_MODE blocklist
+	filtered, err := currentConfig().Profanity.Apply(response.TransformedContent)
+	if err == nil && response.TranslatedContent != "" {
+		response.TranslatedContent, err = currentConfig().Profanity.Apply(response.TranslatedContent)
+	}
 	if err != nil {
-		http.Error(w, "Error making request to OpenAI", http.StatusInternalServerError)
+		slog.WarnContext(r.Context(), "transform output rejected by profanity filter")
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
 	}
-	defer resp.Body.Close()
+	response.TransformedContent = filtered
 
-	if resp.StatusCode != http.StatusOK {
+	// ?analyze=true adds before/after sentiment scores
+	if analyze, _ := strconv.ParseBool(r.URL.Query().Get("analyze")); analyze {
+		sentiment := currentConfig().Sentiment.Compare(requestData.Title+" "+requestData.Description, response.TransformedContent)
+		response.Sentiment = &sentiment
+	}
+
+	response.TokensUsed, response.EstimatedCostUSD = trackUsage(recorder, currentConfig().ModelPricing)
+	archiveTransform(r.Context(), requestData.Title, requestData.Description, response.TransformedContent)
+	if !hit {
+		notifyWebhook(r.Context(), newWebhookPayload(requestData.Title, requestData.Description, "", response.TransformedContent, response.Source))
+	}
 
-		log.Printf("OpenAI API error - status: %d", resp.StatusCode)
-		http.Error(w, "Error from OpenAI API", http.StatusInternalServerError)
+	responseBody, err := json.Marshal(enveloped(r.Context(), response))
+	if err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
 		return
 	}
+	if idempotencyKey != "" {
+		storeIdempotent(r.Context(), idempotencyCache, idempotencyKey, bodyHash, responseBody, currentConfig().IdempotencyTTL)
+	}
 
-	var openAIResponse OpenAIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&openAIResponse); err != nil {
-		http.Error(w, "Error parsing OpenAI response", http.StatusInternalServerError)
+	// Accept: text/plain gets the bare transformed string
+	if wantsPlainText(r) {
+		writePlainTransform(w, response.TransformedContent)
 		return
 	}
+	w.Write(responseBody)
+}
+
+// Longest title and description accepted for a transform, in characters
+const (
+	maxTransformTitleLength       = 500
+	maxTransformDescriptionLength = 2000
+)
+
+// Options for the requested intensity and persona, defaulting either when empty
+func resolveTransformOptions(intensity, persona string) (TransformOptions, error) {
+	options := TransformOptions{Intensity: strings.ToLower(intensity), Persona: strings.ToLower(persona)}
+	if options.Intensity == "" {
+		options.Intensity = defaultIntensity
+	}
+	templates := currentPrompts()
+	if _, ok := templates.Intensities[options.Intensity]; !ok {
+		return TransformOptions{}, fmt.Errorf("Invalid intensity %q; allowed values: %s", intensity, strings.Join(templates.IntensityNames(), ", "))
+	}
+	if options.Persona == "" {
+		options.Persona = defaultPersona
+	}
+	if _, ok := templates.Personas[options.Persona]; !ok {
+		return TransformOptions{}, fmt.Errorf("Invalid persona %q; allowed values: %s", persona, strings.Join(templates.PersonaNames(), ", "))
+	}
+	return options, nil
+}
+
+// Require a title or description and keep both within their length limits
+func validateTransformInput(title, description string) error {
+	if title == "" && description == "" {
+		return fmt.Errorf("At least one of 'title' or 'description' is required")
+	}
+	if n := utf8.RuneCountInString(title); n > maxTransformTitleLength {
+		return fmt.Errorf("'title' must be at most %d characters, got %d", maxTransformTitleLength, n)
+	}
+	if n := utf8.RuneCountInString(description); n > maxTransformDescriptionLength {
+		return fmt.Errorf("'description' must be at most %d characters, got %d", maxTransformDescriptionLength, n)
+	}
+	return nil
+}
+
+// Per-request transform deadline from timeoutMs, clamped to ceiling
+func transformTimeout(timeoutMs int64, ceiling time.Duration) (time.Duration, error) {
+	if timeoutMs <= 0 {
+		return 0, fmt.Errorf("timeoutMs must be positive")
+	}
+	if ceiling > 0 && timeoutMs > ceiling.Milliseconds() {
+		return ceiling, nil
+	}
+	return time.Duration(timeoutMs) * time.Millisecond, nil
+}
+
+// Write a single Server-Sent Event, splitting multi-line data
+func writeSSE(w http.ResponseWriter, event, data string) {
+	if event != "" {
+		fmt.Fprintf(w, "event: %s\n", event)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
 
-	if len(openAIResponse.Choices) == 0 {
-		http.Error(w, "No response from OpenAI", http.StatusInternalServerError)
+// Stream transform output as Server-Sent Events
+func streamTransform(w http.ResponseWriter, r *http.Request) {
+	title := r.URL.Query().Get("title")
+	description := r.URL.Query().Get("description")
+	if title == "" && description == "" {
+		http.Error(w, "Query parameter 'title' or 'description' is required", http.StatusBadRequest)
 		return
 	}
 
-	response := map[string]string{
-		"transformedContent": openAIResponse.Choices[0].Message.Content,
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	// A blocked term can span tokens, so with PROFANITY_MODE set the text is
+	// held back and screened whole before it's sent as a single event
+	screen := currentConfig().Profanity.Active()
+
+	// Text is sent as it arrives until it passes TRANSFORM_MAX_CHARS; then the
+	// rest of the trimmed text goes out and later tokens are dropped
+	var transformed strings.Builder
+	limit := currentConfig().TransformMaxChars
+	sent, trimmed := "", false
+	emit := func(token string) {
+		transformed.WriteString(token)
+		if screen || trimmed {
+			return
+		}
+		text := transformed.String()
+		if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+			writeSSE(w, "", token)
+			sent = text
+			return
+		}
+		text, trimmed = truncateText(text, limit)
+		rest, ok := strings.CutPrefix(text, sent)
+		if !ok {
+			rest = "…"
+		}
+		if rest != "" {
+			writeSSE(w, "", rest)
+		}
 	}
 
-	json.NewEncoder(w).Encode(response)
+	// Upstream requests use the request context, so a client disconnect cancels them
+	var err error
+	if streamer, ok := currentTransformer().(StreamTransformer); ok {
+		err = streamer.TransformStream(r.Context(), title, description, func(token string) error {
+			emit(token)
+			return r.Context().Err()
+		})
+	} else {
+		var content string
+		if content, err = currentTransformer().Transform(r.Context(), title, description); err == nil {
+			emit(content)
+		}
+	}
+
+	if err != nil {
+		if r.Context().Err() != nil {
+			slog.InfoContext(r.Context(), "transform stream cancelled", "error", err)
+			return
+		}
+		slog.ErrorContext(r.Context(), "transform stream error", "error", err)
+		writeSSE(w, "error", "Error transforming content")
+		return
+	}
+
+	output, _ := truncateText(transformed.String(), limit)
+	if screen {
+		if output, err = currentConfig().Profanity.Apply(output); err != nil {
+			slog.WarnContext(r.Context(), "transform stream rejected by profanity filter")
+			writeSSE(w, "error", err.Error())
+			return
+		}
+		writeSSE(w, "", output)
+	}
+
+	writeSSE(w, "", "[DONE]")
+	archiveTransform(r.Context(), title, description, output)
+}
+
+// JSON body for errors raised by the router itself
+type ErrorResponse struct {
+	Error  string `json:"error"`
+	Status int    `json:"status"`
+}
+
+// Write an ErrorResponse with the given status
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: message, Status: status})
+}
+
+// Router fallback for paths no route matches
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeJSONError(w, http.StatusNotFound, "Not found")
+}
+
+// Router fallback for known paths requested with the wrong method
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeJSONError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method %s not allowed", r.Method))
 }
 
 // Health check endpoint
@@ -276,30 +1739,204 @@ func healthCheck(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-func main() {
-	// Load configuration from environment variables
-	var err error
-	config, err = loadConfig()
+// Load configuration and set up the transformer, caches, news provider and
+// archive that every request shares
+func setup() (*Config, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	activeConfig.Store(config)
+
+	// Structured JSON logs at the configured level
+	slog.SetDefault(newLogger(os.Stdout, config.LogLevel))
+
+	// One transport, with the configured timeouts, for every upstream API
+	if base, ok := http.DefaultTransport.(*http.Transport); ok {
+		upstreamTransport = newUpstreamTransport(base, config)
+	}
+
+	// Fail fast while the model API keeps failing
+	if config.BreakerThreshold > 0 {
+		llmBreaker = NewCircuitBreaker(config.BreakerThreshold, config.BreakerCooldown)
+	}
+	openAILimiter = NewSemaphore(config.OpenAIMaxConcurrency, config.OpenAIConcurrencyWait)
+	setTransformer(buildTransformer(config))
+
+	// Hold transforms past TRANSFORM_MAX_IN_FLIGHT in a bounded queue
+	transformQueue = NewRequestQueue(config.TransformMaxInFlight, config.TransformQueueDepth)
+
+	// Prompt templates from PROMPTS_FILE, reloaded when the file changes
+	if config.PromptsFile != "" {
+		watchPromptsFile(config.PromptsFile, promptsReloadInterval)
+	}
+
+	// News, transform and idempotency caches (in-memory or Redis)
+	newsCache, transformCache, idempotencyCache, err = newCaches(config)
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		return nil, fmt.Errorf("failed to set up caches: %w", err)
 	}
+	newsCache = healthCache{Cache: newsCache, Health: cacheHealth}
+	transformCache = healthCache{Cache: transformCache, Health: cacheHealth}
+	idempotencyCache = healthCache{Cache: idempotencyCache, Health: cacheHealth}
 
-	log.Printf("Ministry of Truth Backend starting on port %s", config.Port)
+	setNewsProvider(newNewsProvider(config, newsCache))
 
+	// Optionally archive every transform to SQLite
+	if config.ArchiveDBPath != "" {
+		store, err := OpenSQLiteStore(config.ArchiveDBPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open archive: %w", err)
+		}
+		archive = store
+	}
+	return config, nil
+}
+
+// Router serving the API and the static frontend
+func newRouter() *mux.Router {
 	r := mux.NewRouter()
 
-	// Apply CORS middleware to all routes
+	// Tag every request with an ID, recover from panics anywhere below, log
+	// it, then apply security headers, CORS and API key checks to all routes
+	r.Use(requestIDMiddleware)
+	r.Use(recoveryMiddleware)
+	r.Use(loggingMiddleware)
+	r.Use(gzipMiddleware)
+	r.Use(prettyJSONMiddleware)
+	r.Use(responseMetaMiddleware)
+	r.Use(securityHeadersMiddleware)
 	r.Use(corsMiddleware)
+	r.Use(apiKeyMiddleware)
+
+	// Enforce per-route body size and timeout limits
+	r.Use(routeProfileMiddleware)
 
 	// API routes
 	r.HandleFunc("/api/news/headlines", getTopHeadlines).Methods("GET")
+	r.HandleFunc("/api/news/headlines/rss", getHeadlinesRSS).Methods("GET")
+	r.HandleFunc("/api/news/headlines.csv", getHeadlinesCSV).Methods("GET")
+	r.HandleFunc("/api/news/headlines/aggregate", getAggregateHeadlines).Methods("GET")
+	r.HandleFunc("/api/news/combined", getCombinedNews).Methods("GET")
+	r.HandleFunc("/api/news/headlines/topics", getHeadlineTopics).Methods("GET")
+	r.HandleFunc("/api/news/headlines/transform-all", queuedTransform(transformAllHeadlines)).Methods("POST")
 	r.HandleFunc("/api/news/search", searchNews).Methods("GET")
-	r.HandleFunc("/api/transform", transformNews).Methods("POST")
+	r.HandleFunc("/api/news/search", searchNewsPost).Methods("POST")
+	r.HandleFunc("/api/news/sources", getSources).Methods("GET")
+	r.HandleFunc("/api/meta", getAPIMeta).Methods("GET")
+	r.HandleFunc("/api/transform", queuedTransform(transformNews)).Methods("POST")
+	r.HandleFunc("/api/transform/stream", streamTransform).Methods("GET")
+	r.HandleFunc("/api/transform/text", queuedTransform(transformText)).Methods("POST")
+	r.HandleFunc("/api/transform/sign", signTransformResult).Methods("POST")
+	r.HandleFunc("/api/transform/result", getTransformResult).Methods("GET")
+	r.HandleFunc("/api/article/transform", queuedTransform(transformArticle)).Methods("POST")
+	r.HandleFunc("/api/newspeak", newspeakText).Methods("POST")
+	r.HandleFunc("/api/slogans", getSlogans).Methods("GET")
+	r.HandleFunc("/api/usage", getUsage).Methods("GET")
+	r.HandleFunc("/api/cache/stats", getCacheStats).Methods("GET")
+	r.HandleFunc("/api/cache/purge", purgeCache).Methods("POST")
+	r.HandleFunc("/api/admin/reload", reloadConfig).Methods("POST")
+	r.HandleFunc("/api/history", getHistory).Methods("GET")
 	r.HandleFunc("/api/health", healthCheck).Methods("GET")
+	r.HandleFunc("/api/status", getStatus).Methods("GET")
+	r.HandleFunc("/version", getVersion).Methods("GET")
+	r.HandleFunc("/metrics", getMetrics).Methods("GET")
+	r.HandleFunc("/ready", readyCheck).Methods("GET")
+
+	// Serve static files, falling back to index.html for client-side routes.
+	// API paths are left unmatched so they get the JSON 404/405 below.
+	r.MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+		return !isAPIPath(r.URL.Path)
+	}).PathPrefix("/").Handler(staticHandler("./public/"))
+
+	// mux skips the r.Use chain for unmatched requests, so wrap the error
+	// handlers by hand: they still get a request ID, panic recovery and an
+	// access log line, CORS and security headers so browsers can read the
+	// error and preflights succeed, and ?pretty=true like any other JSON
+	unmatched := func(h http.HandlerFunc) http.Handler {
+		return requestIDMiddleware(recoveryMiddleware(loggingMiddleware(
+			prettyJSONMiddleware(securityHeadersMiddleware(corsMiddleware(h))))))
+	}
+	r.NotFoundHandler = unmatched(notFound)
+	r.MethodNotAllowedHandler = unmatched(methodNotAllowed)
+
+	return r
+}
+
+// Run the standalone server
+func Main() {
+	// Fill unset variables from a local .env file (ENV_FILE, default .env)
+	envFile := envFilePath()
+	if err := loadEnvFile(envFile); err != nil {
+		slog.Error("failed to load env file", "path", envFile, "error", err)
+		os.Exit(1)
+	}
+
+	config, err := setup()
+	if err != nil {
+		slog.Error("startup failed", "error", err)
+		os.Exit(1)
+	}
+	if closer, ok := archive.(io.Closer); ok {
+		defer closer.Close()
+	}
 
-	// Serve static files
-	r.PathPrefix("/").Handler(http.FileServer(http.Dir("./public/")))
+	// Cancelled on SIGINT/SIGTERM to stop background jobs and drain the server
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	log.Printf("Server starting on port %s", config.Port)
-	log.Fatal(http.ListenAndServe(":"+config.Port, r))
+	// Optionally push newly transformed headlines to a webhook
+	if config.WebhookURL != "" {
+		webhooks = NewWebhookSender(config.WebhookURL, config.UserAgent, webhookQueueSize)
+		go webhooks.Run(ctx)
+	}
+
+	// Optionally keep the transform cache warm with the latest headlines
+	if config.PrewarmEnabled {
+		ticker := time.NewTicker(config.PrewarmInterval)
+		defer ticker.Stop()
+		go runPrewarm(ctx, ticker.C)
+	}
+
+	slog.Info("Ministry of Truth Backend starting", "port", config.Port, "llmProvider", config.LLMProvider)
+
+	server := newServer(config, newRouter())
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("server shutdown failed", "error", err)
+		}
+	}()
+
+	slog.Info("server starting", "addr", config.ListenAddr, "tls", config.TLSCertFile != "")
+	if err := serve(server, config); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("server stopped", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("server stopped")
+}
+
+var (
+	serverlessOnce    sync.Once
+	serverlessHandler http.Handler
+	serverlessErr     error
+)
+
+// Serve one request on a serverless platform such as Vercel, through the
+// same router as the standalone server. Setup happens on the first request
+// and is reused while the instance stays warm.
+func ServeServerless(w http.ResponseWriter, r *http.Request) {
+	serverlessOnce.Do(func() {
+		if _, serverlessErr = setup(); serverlessErr == nil {
+			serverlessHandler = newRouter()
+		}
+	})
+	if serverlessErr != nil {
+		slog.Error("startup failed", "error", serverlessErr)
+		http.Error(w, "Server configuration error", http.StatusInternalServerError)
+		return
+	}
+	serverlessHandler.ServeHTTP(w, r)
 }
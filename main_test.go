@@ -0,0 +1,648 @@
+package ministry
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransformRejectsOversizedBody(t *testing.T) {
+	openAI := newFakeOpenAI("Plenty announces a glorious increase")
+	setupTest(t, upstreams(openAI, nil), "MAX_BODY_BYTES", "64")
+
+	rec := postTransform(`{"title": "` + strings.Repeat("a", 100) + `"}`)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "64 bytes") {
+		t.Errorf("body = %q, want the limit named", rec.Body.String())
+	}
+	if openAI.Calls() != 0 {
+		t.Error("oversized request reached the model")
+	}
+
+	rec = postTransform(`{"title": "Chocolate ration raised"}`)
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d for a body within the limit, want 200", rec.Code)
+	}
+}
+
+func TestTransformRejectsEmptyBody(t *testing.T) {
+	setupTest(t, upstreams(newFakeOpenAI("unused"), nil))
+
+	for _, body := range []string{"", "   "} {
+		rec := postTransform(body)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("body %q: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
+
+func TestValidateTransformInput(t *testing.T) {
+	valid := [][2]string{
+		{"Lift broken again", ""},
+		{"", "The lift in Victory Mansions is broken"},
+		{strings.Repeat("a", maxTransformTitleLength), strings.Repeat("é", maxTransformDescriptionLength)},
+	}
+	for _, input := range valid {
+		if err := validateTransformInput(input[0], input[1]); err != nil {
+			t.Errorf("validateTransformInput(%.20q, %.20q) = %v", input[0], input[1], err)
+		}
+	}
+
+	invalid := map[string][2]string{
+		"required":                 {"", ""},
+		"'title' must be at most":  {strings.Repeat("a", maxTransformTitleLength+1), ""},
+		"'description' must be at": {"Lift", strings.Repeat("a", maxTransformDescriptionLength+1)},
+	}
+	for want, input := range invalid {
+		if err := validateTransformInput(input[0], input[1]); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("validateTransformInput(%.20q, %.20q) = %v, want it to mention %q", input[0], input[1], err, want)
+		}
+	}
+}
+
+func TestTransformValidatesFields(t *testing.T) {
+	openAI := newFakeOpenAI("unused")
+	setupTest(t, upstreams(openAI, nil))
+
+	for body, want := range map[string]string{
+		`{"title": "  ", "description": "\t"}`: "required",
+		`{}`:                                   "required",
+		`{"title": "` + strings.Repeat("a", 501) + `"}`:        "'title' must be at most 500 characters",
+		`{"description": "` + strings.Repeat("a", 2001) + `"}`: "'description' must be at most 2000 characters",
+	} {
+		rec := postTransform(body)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("body %.40q: status = %d, body %q; want 400 mentioning %q", body, rec.Code, rec.Body.String(), want)
+		}
+	}
+	if openAI.Calls() != 0 {
+		t.Error("invalid input reached the model")
+	}
+}
+
+// Fake OpenAI streaming tokens for stream requests and completing with the
+// tokens joined otherwise
+func streamingOpenAI(tokens ...string) *fakeOpenAI {
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		if req.Stream {
+			writeCompletionStream(w, tokens...)
+			return
+		}
+		writeCompletion(w, req.Model, strings.Join(tokens, ""), "stop")
+	}
+	return openAI
+}
+
+func TestStreamTransform(t *testing.T) {
+	openAI := streamingOpenAI("Big", " Brother", " is", " pleased")
+	setupTest(t, upstreams(openAI, nil), "TRANSFORM_MAX_CHARS", "0")
+
+	rec := do(newRequest(http.MethodGet, "/api/transform/stream?title=Lift+broken+again", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	events := sseEvents(rec.Body.String())
+	if len(events) == 0 || events[len(events)-1] != "[DONE]" {
+		t.Fatalf("events = %q, want them to end with [DONE]", events)
+	}
+	if got := strings.Join(events[:len(events)-1], ""); got != "Big Brother is pleased" {
+		t.Errorf("streamed text = %q", got)
+	}
+	if requests := openAI.Requests(); len(requests) != 1 || !requests[0].Stream {
+		t.Errorf("upstream requests = %+v, want one streamed request", requests)
+	}
+}
+
+func TestStreamTransformMaxChars(t *testing.T) {
+	openAI := streamingOpenAI("Big", " Brother", " is", " pleased")
+	setupTest(t, upstreams(openAI, nil), "TRANSFORM_MAX_CHARS", "12")
+
+	events := sseEvents(do(newRequest(http.MethodGet, "/api/transform/stream?title=Lift+broken+again", "")).Body.String())
+	if len(events) == 0 || events[len(events)-1] != "[DONE]" {
+		t.Fatalf("events = %q, want them to end with [DONE]", events)
+	}
+	if got := strings.Join(events[:len(events)-1], ""); got != "Big Brother…" {
+		t.Errorf("streamed text = %q, want it trimmed to TRANSFORM_MAX_CHARS", got)
+	}
+}
+
+func TestStreamTransformRequiresInput(t *testing.T) {
+	openAI := streamingOpenAI("unused")
+	setupTest(t, upstreams(openAI, nil))
+
+	rec := do(newRequest(http.MethodGet, "/api/transform/stream", ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+	if openAI.Calls() != 0 {
+		t.Error("request without input reached the model")
+	}
+}
+
+func TestStreamTransformReportsUpstreamErrors(t *testing.T) {
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		http.Error(w, "overloaded", http.StatusInternalServerError)
+	}
+	setupTest(t, upstreams(openAI, nil))
+
+	rec := do(newRequest(http.MethodGet, "/api/transform/stream?title=Lift+broken+again", ""))
+	events := sseEvents(rec.Body.String())
+	if len(events) != 1 || !strings.HasPrefix(events[0], "error: ") {
+		t.Errorf("events = %q, want a single error event", events)
+	}
+}
+
+func TestStreamTransformProfanityModes(t *testing.T) {
+	t.Run("mask", func(t *testing.T) {
+		setupTest(t, upstreams(streamingOpenAI("Goldstein is a bas", "tard"), nil), "PROFANITY_MODE", "mask")
+
+		events := sseEvents(do(newRequest(http.MethodGet, "/api/transform/stream?title=Lift+broken+again", "")).Body.String())
+		if len(events) != 2 || events[0] != "Goldstein is a *******" || events[1] != "[DONE]" {
+			t.Errorf("events = %q, want the masked text as one event", events)
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		setupTest(t, upstreams(streamingOpenAI("Goldstein is a bas", "tard"), nil), "PROFANITY_MODE", "reject")
+
+		body := do(newRequest(http.MethodGet, "/api/transform/stream?title=Lift+broken+again", "")).Body.String()
+		if events := sseEvents(body); len(events) != 1 || !strings.HasPrefix(events[0], "error: ") || strings.Contains(body, "tard") {
+			t.Errorf("events = %q, want a single error event without the text", events)
+		}
+	})
+}
+
+func TestTransformFallsBackLocally(t *testing.T) {
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		http.Error(w, "overloaded", http.StatusInternalServerError)
+	}
+	setupTest(t, upstreams(openAI, nil), "TRANSFORM_FALLBACK", "true")
+
+	rec := postTransform(`{"title": "Government faces criticism"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	var response TransformResponse
+	decodeJSON(t, rec, &response)
+	if response.Source != "fallback" || !strings.Contains(response.TransformedContent, "The Party faces crimethink") {
+		t.Errorf("response = %+v, want a local fallback transform", response)
+	}
+}
+
+func TestTransformWithoutFallbackFails(t *testing.T) {
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		http.Error(w, "overloaded", http.StatusInternalServerError)
+	}
+	setupTest(t, upstreams(openAI, nil))
+
+	rec := postTransform(`{"title": "Government faces criticism"}`)
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", rec.Code)
+	}
+}
+
+func TestCheckCategory(t *testing.T) {
+	for _, category := range newsCategories {
+		if err := checkCategory(category, nil); err != nil {
+			t.Errorf("checkCategory(%q) = %v", category, err)
+		}
+	}
+	if err := checkCategory("gossip", nil); err == nil || !strings.Contains(err.Error(), "allowed values") {
+		t.Errorf("checkCategory(gossip) = %v, want the allowed values listed", err)
+	}
+}
+
+func TestHeadlinesValidatesCategory(t *testing.T) {
+	var categories []string
+	news := func(w http.ResponseWriter, r *http.Request) {
+		categories = append(categories, r.URL.Query().Get("category"))
+		newsHandler(Article{Title: "Lift broken again"})(w, r)
+	}
+	setupTest(t, upstreams(nil, http.HandlerFunc(news)))
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines?category=gossip", ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d for an unknown category, want 400", rec.Code)
+	}
+	if len(categories) != 0 {
+		t.Fatal("invalid category reached NewsAPI")
+	}
+
+	// Categories are matched case-insensitively and passed on lowercased
+	rec = do(newRequest(http.MethodGet, "/api/news/headlines?category=Science", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if len(categories) != 1 || categories[0] != "science" {
+		t.Errorf("NewsAPI categories = %q, want [science]", categories)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	const etag = `"abc"`
+	tests := map[string]bool{
+		"":             false,
+		`"abc"`:        true,
+		`W/"abc"`:      true,
+		`"xyz", "abc"`: true,
+		"*":            true,
+		`"xyz"`:        false,
+		`abc`:          false,
+	}
+	for header, want := range tests {
+		if got := etagMatches(header, etag); got != want {
+			t.Errorf("etagMatches(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestHeadlinesETag(t *testing.T) {
+	setupTest(t, upstreams(nil, newsHandler(Article{Title: "Lift broken again"})))
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines", ""))
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status = %d, ETag %q", rec.Code, etag)
+	}
+
+	rec = do(newRequest(http.MethodGet, "/api/news/headlines", "", "If-None-Match", etag))
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("status = %d, body %q; want an empty 304", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("ETag"); got != etag {
+		t.Errorf("304 ETag = %q, want %q", got, etag)
+	}
+
+	rec = do(newRequest(http.MethodGet, "/api/news/headlines", "", "If-None-Match", `"stale"`))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d for a stale ETag, want 200", rec.Code)
+	}
+}
+
+func TestResponseFormat(t *testing.T) {
+	tests := []struct {
+		target, accept, want string
+	}{
+		{"/", "", "json"},
+		{"/?format=XML", "", "xml"},
+		{"/?format=json", "application/xml", "json"},
+		{"/", "text/xml", "xml"},
+		{"/", "application/xml, application/json", "json"},
+	}
+	for _, test := range tests {
+		got, err := responseFormat(newRequest(http.MethodGet, test.target, "", "Accept", test.accept))
+		if err != nil || got != test.want {
+			t.Errorf("responseFormat(%s, Accept %q) = %q, %v; want %q", test.target, test.accept, got, err, test.want)
+		}
+	}
+	if _, err := responseFormat(newRequest(http.MethodGet, "/?format=yaml", "")); err == nil {
+		t.Error("format=yaml accepted, want an error")
+	}
+}
+
+func TestHeadlinesAsXML(t *testing.T) {
+	setupTest(t, upstreams(nil, newsHandler(Article{Title: "Lift broken again"})))
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines", "", "Accept", "application/xml"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/xml; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	var response NewsResponse
+	if err := xml.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("body isn't XML: %v", err)
+	}
+	if response.Status != "ok" || len(response.Articles) != 1 || response.Articles[0].Title != "Lift broken again" {
+		t.Errorf("response = %+v", response)
+	}
+
+	rec = do(newRequest(http.MethodGet, "/api/news/headlines?format=yaml", ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d for format=yaml, want 400", rec.Code)
+	}
+}
+
+func TestNewServerTimeouts(t *testing.T) {
+	cfg := setupTest(t, nil, "SERVER_READ_TIMEOUT", "3s", "SERVER_WRITE_TIMEOUT", "20s", "SERVER_IDLE_TIMEOUT", "2m")
+
+	server := newServer(cfg, http.NotFoundHandler())
+	if server.ReadTimeout != 3*time.Second || server.WriteTimeout != 20*time.Second || server.IdleTimeout != 2*time.Minute {
+		t.Errorf("timeouts = %v/%v/%v, want 3s/20s/2m", server.ReadTimeout, server.WriteTimeout, server.IdleTimeout)
+	}
+	if server.Addr != cfg.ListenAddr {
+		t.Errorf("Addr = %q, want %q", server.Addr, cfg.ListenAddr)
+	}
+}
+
+func TestServerTimeoutDefaults(t *testing.T) {
+	cfg := setupTest(t, nil)
+
+	server := newServer(cfg, http.NotFoundHandler())
+	if server.ReadTimeout != 5*time.Second || server.WriteTimeout != 15*time.Second || server.IdleTimeout != 60*time.Second {
+		t.Errorf("timeouts = %v/%v/%v, want 5s/15s/60s", server.ReadTimeout, server.WriteTimeout, server.IdleTimeout)
+	}
+}
+
+func TestLoadConfigRejectsBadServerTimeout(t *testing.T) {
+	t.Setenv("NEWS_API_KEY", "test-news-key")
+	t.Setenv("OPENAI_API_KEY", "test-openai-key")
+	t.Setenv("SERVER_READ_TIMEOUT", "soon")
+
+	if _, err := loadConfig(); err == nil {
+		t.Error("SERVER_READ_TIMEOUT=soon accepted, want an error")
+	}
+}
+
+func TestListenAddress(t *testing.T) {
+	tests := []struct {
+		bindAddr, port, want string
+	}{
+		{"", "8080", ":8080"},
+		{"127.0.0.1", "3000", "127.0.0.1:3000"},
+		{"::1", "3000", "[::1]:3000"},
+		{"[::1]", "3000", "[::1]:3000"},
+		{"api.internal", "443", "api.internal:443"},
+	}
+	for _, test := range tests {
+		got, err := listenAddress(test.bindAddr, test.port)
+		if err != nil || got != test.want {
+			t.Errorf("listenAddress(%q, %q) = %q, %v; want %q", test.bindAddr, test.port, got, err, test.want)
+		}
+	}
+
+	for _, bad := range [][2]string{{"", "0"}, {"", "http"}, {"", "70000"}, {"not an address", "8080"}, {"-bad-.host", "8080"}} {
+		if _, err := listenAddress(bad[0], bad[1]); err == nil {
+			t.Errorf("listenAddress(%q, %q) succeeded, want an error", bad[0], bad[1])
+		}
+	}
+}
+
+func TestListenAddressFromEnv(t *testing.T) {
+	cfg := setupTest(t, nil, "BIND_ADDR", "127.0.0.1", "PORT", "9090")
+	if cfg.ListenAddr != "127.0.0.1:9090" {
+		t.Errorf("ListenAddr = %q, want 127.0.0.1:9090", cfg.ListenAddr)
+	}
+}
+
+func TestLoadConfigReportsEveryProblem(t *testing.T) {
+	t.Setenv("NEWS_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("NEWS_CACHE_TTL", "forever")
+	t.Setenv("BREAKER_THRESHOLD", "-1")
+	t.Setenv("WEBHOOK_URL", "hooks.example.com")
+	t.Setenv("TRANSFORM_FALLBACK", "maybe")
+
+	_, err := loadConfig()
+	if err == nil {
+		t.Fatal("loadConfig succeeded, want an error")
+	}
+	if !strings.HasPrefix(err.Error(), "6 configuration problems: ") {
+		t.Errorf("error = %q, want all 6 problems counted", err)
+	}
+	for _, name := range []string{"NEWS_API_KEY", "OPENAI_API_KEY", "NEWS_CACHE_TTL", "BREAKER_THRESHOLD", "WEBHOOK_URL", "TRANSFORM_FALLBACK"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error = %q, want %s reported", err, name)
+		}
+	}
+}
+
+func TestConfigErrorsSingleProblem(t *testing.T) {
+	var problems configErrors
+	problems.add(nil)
+	problems.add(errors.New("PORT must be a number"))
+	if len(problems) != 1 || problems.Error() != "PORT must be a number" {
+		t.Errorf("problems = %v, want the one message as-is", problems)
+	}
+}
+
+func TestTransformTimeout(t *testing.T) {
+	if got, err := transformTimeout(1500, 30*time.Second); err != nil || got != 1500*time.Millisecond {
+		t.Errorf("transformTimeout(1500) = %v, %v", got, err)
+	}
+	if got, err := transformTimeout(60000, 30*time.Second); err != nil || got != 30*time.Second {
+		t.Errorf("transformTimeout(60000) = %v, %v; want clamped to 30s", got, err)
+	}
+	for _, ms := range []int64{0, -5} {
+		if _, err := transformTimeout(ms, 30*time.Second); err == nil {
+			t.Errorf("transformTimeout(%d) succeeded, want an error", ms)
+		}
+	}
+}
+
+func TestTransformTimeoutMs(t *testing.T) {
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		time.Sleep(200 * time.Millisecond)
+		writeCompletion(w, req.Model, "Lift repaired ahead of schedule", "stop")
+	}
+	setupTest(t, upstreams(openAI, nil))
+
+	rec := postTransform(`{"title": "Lift broken again", "timeoutMs": 0}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d for timeoutMs 0, want 400", rec.Code)
+	}
+
+	rec = postTransform(`{"title": "Lift broken again", "timeoutMs": 20}`)
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", rec.Code)
+	}
+
+	rec = postTransform(`{"title": "Rations cut", "timeoutMs": 5000}`)
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d with a generous timeoutMs, want 200", rec.Code)
+	}
+}
+
+func TestRouterErrorsAreJSON(t *testing.T) {
+	setupTest(t, nil)
+
+	tests := []struct {
+		method, path string
+		status       int
+		message      string
+	}{
+		{http.MethodGet, "/api/ministry-of-love", http.StatusNotFound, "Not found"},
+		{http.MethodDelete, "/api/transform", http.StatusMethodNotAllowed, "Method DELETE not allowed"},
+		{http.MethodPost, "/api/health", http.StatusMethodNotAllowed, "Method POST not allowed"},
+	}
+	for _, test := range tests {
+		rec := do(newRequest(test.method, test.path, ""))
+		if rec.Code != test.status || rec.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%s %s: status = %d, Content-Type %q", test.method, test.path, rec.Code, rec.Header().Get("Content-Type"))
+			continue
+		}
+		var response ErrorResponse
+		decodeJSON(t, rec, &response)
+		if response != (ErrorResponse{Error: test.message, Status: test.status}) {
+			t.Errorf("%s %s: body = %+v", test.method, test.path, response)
+		}
+	}
+}
+
+// Write a self-signed certificate for 127.0.0.1 and its key to a temp dir
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ministry-of-truth test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile
+}
+
+func TestValidateTLSFiles(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+	otherCert, _ := writeSelfSignedCert(t)
+
+	if err := validateTLSFiles("", ""); err != nil {
+		t.Errorf("no TLS: %v", err)
+	}
+	if err := validateTLSFiles(certFile, keyFile); err != nil {
+		t.Errorf("valid pair: %v", err)
+	}
+
+	invalid := map[string][2]string{
+		"must be set together":   {certFile, ""},
+		"TLS_KEY_FILE":           {certFile, filepath.Join(t.TempDir(), "missing.pem")},
+		"TLS_CERT_FILE":          {filepath.Join(t.TempDir(), "missing.pem"), keyFile},
+		"don't form a valid key": {otherCert, keyFile},
+	}
+	for want, files := range invalid {
+		if err := validateTLSFiles(files[0], files[1]); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("validateTLSFiles(%q, %q) = %v, want it to mention %q", files[0], files[1], err, want)
+		}
+	}
+
+	t.Setenv("NEWS_API_KEY", "test-news-key")
+	t.Setenv("OPENAI_API_KEY", "test-openai-key")
+	t.Setenv("TLS_CERT_FILE", certFile)
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "TLS_KEY_FILE") {
+		t.Errorf("loadConfig with only a certificate: %v", err)
+	}
+}
+
+// Start serve on a free local port and return its address
+func startServe(t *testing.T, cfg *Config) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	server := &http.Server{Addr: addr, Handler: newRouter()}
+	done := make(chan error, 1)
+	go func() { done <- serve(server, cfg) }()
+	t.Cleanup(func() {
+		server.Close()
+		if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+			t.Errorf("serve = %v", err)
+		}
+	})
+
+	// Wait for the listener
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			return addr
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server never listened: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestServeTLS(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+	cfg := setupTest(t, nil, "TLS_CERT_FILE", certFile, "TLS_KEY_FILE", keyFile)
+	addr := startServe(t, cfg)
+
+	pool := x509.NewCertPool()
+	certPEM, _ := os.ReadFile(certFile)
+	pool.AppendCertsFromPEM(certPEM)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}, ForceAttemptHTTP2: true}}
+
+	resp, err := client.Get("https://" + addr + "/api/health")
+	if err != nil {
+		t.Fatalf("GET over TLS: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.TLS == nil || resp.ProtoMajor != 2 {
+		t.Errorf("status %d over %s, TLS %v; want HTTP/2 over TLS", resp.StatusCode, resp.Proto, resp.TLS != nil)
+	}
+
+	// Plain HTTP isn't answered on the TLS port
+	if resp, err := http.Get("http://" + addr + "/api/health"); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Error("plain HTTP served on the TLS port")
+		}
+	}
+}
+
+func TestServePlainHTTP(t *testing.T) {
+	cfg := setupTest(t, nil)
+	addr := startServe(t, cfg)
+
+	resp, err := http.Get("http://" + addr + "/api/health")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.TLS != nil {
+		t.Errorf("status %d, TLS %v; want plain HTTP", resp.StatusCode, resp.TLS != nil)
+	}
+}
@@ -0,0 +1,147 @@
+package ministry
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// How a request was served: cache lookups, time spent waiting on upstream
+// APIs and the provider that answered
+type responseMeta struct {
+	mu          sync.Mutex
+	cacheHits   int
+	cacheMisses int
+	upstream    time.Duration
+	provider    string
+	envelope    bool // wrap JSON bodies in an Envelope, from ENVELOPE
+}
+
+type responseMetaKey struct{}
+
+func withResponseMeta(ctx context.Context, meta *responseMeta) context.Context {
+	return context.WithValue(ctx, responseMetaKey{}, meta)
+}
+
+// The request's metadata, or nil outside a request
+func responseMetaFrom(ctx context.Context) *responseMeta {
+	meta, _ := ctx.Value(responseMetaKey{}).(*responseMeta)
+	return meta
+}
+
+// Record a news or transform cache lookup. A hit names the provider that
+// originally answered.
+func recordCacheLookup(ctx context.Context, provider string, hit bool) {
+	meta := responseMetaFrom(ctx)
+	if meta == nil {
+		return
+	}
+	meta.mu.Lock()
+	defer meta.mu.Unlock()
+	if hit {
+		meta.cacheHits++
+		if meta.provider == "" {
+			meta.provider = provider
+		}
+	} else {
+		meta.cacheMisses++
+	}
+}
+
+// Record a call to an upstream API and how long it took
+func recordUpstream(ctx context.Context, provider string, elapsed time.Duration) {
+	meta := responseMetaFrom(ctx)
+	if meta == nil {
+		return
+	}
+	meta.mu.Lock()
+	defer meta.mu.Unlock()
+	meta.upstream += elapsed
+	meta.provider = provider
+}
+
+// Metadata reported with ENVELOPE=true
+type ResponseMeta struct {
+	Cache             string `json:"cache,omitempty"`
+	UpstreamLatencyMs int64  `json:"upstreamLatencyMs"`
+	Provider          string `json:"provider,omitempty"`
+}
+
+// HIT when every lookup hit, MISS when any missed, empty with no lookups
+func (m *responseMeta) snapshot() ResponseMeta {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := ResponseMeta{UpstreamLatencyMs: m.upstream.Milliseconds(), Provider: m.provider}
+	switch {
+	case m.cacheMisses > 0:
+		snapshot.Cache = "MISS"
+	case m.cacheHits > 0:
+		snapshot.Cache = "HIT"
+	}
+	return snapshot
+}
+
+// A response wrapped with its metadata
+type Envelope struct {
+	Data any          `json:"data"`
+	Meta ResponseMeta `json:"meta"`
+}
+
+// v wrapped in an Envelope when ENVELOPE=true, otherwise v unchanged
+func enveloped(ctx context.Context, v any) any {
+	meta := responseMetaFrom(ctx)
+	if meta == nil || !meta.envelope {
+		return v
+	}
+	return Envelope{Data: v, Meta: meta.snapshot()}
+}
+
+// Adds X-Cache, X-Upstream-Latency-Ms and X-Provider just before the headers go out
+type responseMetaWriter struct {
+	http.ResponseWriter
+	meta        *responseMeta
+	wroteHeader bool
+}
+
+func (m *responseMetaWriter) WriteHeader(status int) {
+	if !m.wroteHeader {
+		m.wroteHeader = true
+		snapshot := m.meta.snapshot()
+		if snapshot.Cache != "" {
+			m.Header().Set("X-Cache", snapshot.Cache)
+		}
+		if snapshot.Provider != "" {
+			m.Header().Set("X-Upstream-Latency-Ms", strconv.FormatInt(snapshot.UpstreamLatencyMs, 10))
+			m.Header().Set("X-Provider", snapshot.Provider)
+		}
+	}
+	m.ResponseWriter.WriteHeader(status)
+}
+
+func (m *responseMetaWriter) Write(b []byte) (int, error) {
+	if !m.wroteHeader {
+		m.WriteHeader(http.StatusOK)
+	}
+	return m.ResponseWriter.Write(b)
+}
+
+func (m *responseMetaWriter) Flush() {
+	if flusher, ok := m.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (m *responseMetaWriter) Unwrap() http.ResponseWriter {
+	return m.ResponseWriter
+}
+
+// Track cache use and upstream latency per request and report them in headers
+func responseMetaMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		meta := &responseMeta{envelope: currentConfig().Envelope}
+		next.ServeHTTP(&responseMetaWriter{ResponseWriter: w, meta: meta}, r.WithContext(withResponseMeta(r.Context(), meta)))
+	})
+}
@@ -0,0 +1,102 @@
+package ministry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestResponseMetaSnapshot(t *testing.T) {
+	meta := &responseMeta{}
+	ctx := withResponseMeta(context.Background(), meta)
+	if snapshot := meta.snapshot(); snapshot.Cache != "" || snapshot.Provider != "" {
+		t.Errorf("before any lookup: %+v", snapshot)
+	}
+
+	recordCacheLookup(ctx, "newsapi", true)
+	if snapshot := meta.snapshot(); snapshot.Cache != "HIT" || snapshot.Provider != "newsapi" {
+		t.Errorf("after a hit: %+v", snapshot)
+	}
+
+	// Any miss makes the whole response a miss
+	recordCacheLookup(ctx, "openai", false)
+	recordUpstream(ctx, "openai", 1500*time.Millisecond)
+	recordUpstream(ctx, "openai", 250*time.Millisecond)
+	if snapshot := meta.snapshot(); snapshot.Cache != "MISS" || snapshot.Provider != "openai" || snapshot.UpstreamLatencyMs != 1750 {
+		t.Errorf("after a miss: %+v", snapshot)
+	}
+
+	// Outside a request nothing is recorded
+	recordCacheLookup(context.Background(), "newsapi", true)
+	recordUpstream(context.Background(), "newsapi", time.Second)
+}
+
+func TestResponseMetaHeaders(t *testing.T) {
+	setupTest(t, upstreams(newFakeOpenAI("Rations are plentiful"), newFakeNews(Article{Title: "Lift broken again"})))
+
+	for _, test := range []struct {
+		name     string
+		send     func() *http.Response
+		provider string
+	}{
+		{"headlines", func() *http.Response { return do(newRequest(http.MethodGet, "/api/news/headlines", "")).Result() }, "newsapi"},
+		{"transform", func() *http.Response { return postTransform(`{"title": "Lift broken again"}`).Result() }, "openai"},
+	} {
+		miss := test.send()
+		if miss.Header.Get("X-Cache") != "MISS" || miss.Header.Get("X-Provider") != test.provider {
+			t.Errorf("%s first call: X-Cache %q, X-Provider %q; want MISS from %s", test.name, miss.Header.Get("X-Cache"), miss.Header.Get("X-Provider"), test.provider)
+		}
+		if _, err := strconv.Atoi(miss.Header.Get("X-Upstream-Latency-Ms")); err != nil {
+			t.Errorf("%s X-Upstream-Latency-Ms = %q", test.name, miss.Header.Get("X-Upstream-Latency-Ms"))
+		}
+
+		hit := test.send()
+		if hit.Header.Get("X-Cache") != "HIT" || hit.Header.Get("X-Provider") != test.provider || hit.Header.Get("X-Upstream-Latency-Ms") != "0" {
+			t.Errorf("%s repeat: X-Cache %q, X-Provider %q, latency %q; want a HIT from %s", test.name, hit.Header.Get("X-Cache"), hit.Header.Get("X-Provider"), hit.Header.Get("X-Upstream-Latency-Ms"), test.provider)
+		}
+	}
+
+	// Nothing to report for routes that don't touch a cache or upstream
+	if rec := do(newRequest(http.MethodGet, "/api/health", "")); rec.Header().Get("X-Cache") != "" || rec.Header().Get("X-Provider") != "" {
+		t.Errorf("health headers = %v", rec.Header())
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	setupTest(t, upstreams(newFakeOpenAI("Rations are plentiful"), newFakeNews(Article{Title: "Lift broken again"})), "ENVELOPE", "true")
+
+	var envelope struct {
+		Data NewsResponse `json:"data"`
+		Meta ResponseMeta `json:"meta"`
+	}
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/news/headlines", "")), &envelope)
+	if len(envelope.Data.Articles) != 1 || envelope.Meta.Cache != "MISS" || envelope.Meta.Provider != "newsapi" {
+		t.Errorf("envelope = %+v", envelope)
+	}
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/news/headlines", "")), &envelope)
+	if envelope.Meta.Cache != "HIT" {
+		t.Errorf("repeat meta = %+v, want a HIT", envelope.Meta)
+	}
+
+	var transform struct {
+		Data TransformResponse `json:"data"`
+		Meta ResponseMeta      `json:"meta"`
+	}
+	decodeJSON(t, postTransform(`{"title": "Lift broken again"}`), &transform)
+	if transform.Data.TransformedContent != "Rations are plentiful" || transform.Meta.Cache != "MISS" || transform.Meta.Provider != "openai" {
+		t.Errorf("transform envelope = %+v", transform)
+	}
+}
+
+func TestNoEnvelopeByDefault(t *testing.T) {
+	setupTest(t, upstreams(nil, newFakeNews(Article{Title: "Lift broken again"})))
+
+	var body map[string]json.RawMessage
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/news/headlines", "")), &body)
+	if _, wrapped := body["data"]; wrapped || body["articles"] == nil {
+		t.Errorf("body keys = %v, want the bare response", body)
+	}
+}
@@ -0,0 +1,67 @@
+package ministry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Numeric circuit breaker state for metrics
+var breakerStateValues = map[string]int{breakerClosed: 0, breakerHalfOpen: 1, breakerOpen: 2}
+
+// Prometheus text exposition of service metrics
+func getMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	if llmBreaker != nil {
+		state, failures := llmBreaker.State()
+		fmt.Fprintln(w, "# HELP ministry_llm_circuit_state LLM circuit breaker state (0=closed, 1=half-open, 2=open)")
+		fmt.Fprintln(w, "# TYPE ministry_llm_circuit_state gauge")
+		fmt.Fprintf(w, "ministry_llm_circuit_state %d\n", breakerStateValues[state])
+		fmt.Fprintln(w, "# HELP ministry_llm_circuit_consecutive_failures Consecutive LLM failures counted by the circuit breaker")
+		fmt.Fprintln(w, "# TYPE ministry_llm_circuit_consecutive_failures gauge")
+		fmt.Fprintf(w, "ministry_llm_circuit_consecutive_failures %d\n", failures)
+	}
+
+	if transformQueue != nil {
+		stats := transformQueue.Stats()
+		fmt.Fprintln(w, "# HELP ministry_transform_in_flight Transform requests currently running")
+		fmt.Fprintln(w, "# TYPE ministry_transform_in_flight gauge")
+		fmt.Fprintf(w, "ministry_transform_in_flight %d\n", stats.InFlight)
+		fmt.Fprintln(w, "# HELP ministry_transform_queue_depth Transform requests waiting in the queue")
+		fmt.Fprintln(w, "# TYPE ministry_transform_queue_depth gauge")
+		fmt.Fprintf(w, "ministry_transform_queue_depth %d\n", stats.Queued)
+		fmt.Fprintln(w, "# HELP ministry_transform_queue_rejected_total Transform requests turned away with the queue full")
+		fmt.Fprintln(w, "# TYPE ministry_transform_queue_rejected_total counter")
+		fmt.Fprintf(w, "ministry_transform_queue_rejected_total %d\n", stats.Rejected)
+	}
+
+	summary := usageTracker.Summary()
+	fmt.Fprintln(w, "# HELP ministry_llm_requests_total LLM calls made")
+	fmt.Fprintln(w, "# TYPE ministry_llm_requests_total counter")
+	fmt.Fprintf(w, "ministry_llm_requests_total %d\n", summary.Calls)
+	fmt.Fprintln(w, "# HELP ministry_llm_tokens_total LLM tokens used")
+	fmt.Fprintln(w, "# TYPE ministry_llm_tokens_total counter")
+	fmt.Fprintf(w, "ministry_llm_tokens_total %d\n", summary.TotalTokens)
+}
+
+type ReadyResponse struct {
+	Status  string `json:"status"`
+	Circuit string `json:"circuit"`
+}
+
+// Readiness: not ready while the LLM circuit breaker is open
+func readyCheck(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	response := ReadyResponse{Status: "ready", Circuit: "disabled"}
+	if llmBreaker != nil {
+		response.Circuit, _ = llmBreaker.State()
+	}
+
+	if response.Circuit == breakerOpen {
+		response.Status = "not ready"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(response)
+}
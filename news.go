@@ -0,0 +1,432 @@
+package ministry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Filters for a top headlines request
+type HeadlinesQuery struct {
+	Country  string
+	Category string
+	Sources  []string
+	PageSize int // 0 leaves it to the provider
+
+	// Category came from DEFAULT_CATEGORY rather than the request
+	CategoryDefaulted bool
+
+	// Fewest articles wanted; fetchTopHeadlines tops up a category short of
+	// it from the general feed. Not sent to the provider.
+	MinArticles int
+}
+
+// Filters and position for a search request
+type SearchQuery struct {
+	Q              string
+	Domains        []string
+	ExcludeDomains []string
+	Language       string
+	SortBy         string
+	From           string // ISO 8601 date or timestamp
+	To             string
+	Page           int
+	PageSize       int
+}
+
+// Where articles come from; handlers only talk to this
+type NewsProvider interface {
+	TopHeadlines(ctx context.Context, query HeadlinesQuery) (*NewsResponse, error)
+	Search(ctx context.Context, query SearchQuery) (*NewsResponse, error)
+}
+
+// Returned when a query uses a filter the configured provider can't apply
+var ErrUnsupportedQuery = errors.New("not supported by the configured news provider")
+
+// Returned when NewsAPI refuses dates outside the plan's window (about a
+// month back on the free tier)
+var ErrNewsDateRestricted = errors.New("the NewsAPI plan in use does not allow searching articles this old")
+
+// Active news provider, selected by NEWS_PROVIDER and rebuilt on config reload
+var activeNewsProvider atomic.Pointer[NewsProvider]
+
+func currentNewsProvider() NewsProvider {
+	return *activeNewsProvider.Load()
+}
+
+func setNewsProvider(provider NewsProvider) {
+	activeNewsProvider.Store(&provider)
+}
+
+// Pick the news provider implementation for the configured provider name
+func newNewsProvider(cfg *Config, cache Cache) NewsProvider {
+	switch cfg.NewsProvider {
+	case "gnews":
+		return &GNewsProvider{
+			APIKey:      cfg.GNewsAPIKey,
+			Client:      &http.Client{Timeout: 10 * time.Second, Transport: upstreamTransport},
+			Cache:       cache,
+			CacheTTL:    cfg.NewsCacheTTL,
+			MaxArticles: cfg.MaxArticles,
+			UserAgent:   cfg.UserAgent,
+		}
+	default:
+		return NewsAPIProvider{}
+	}
+}
+
+// HTTP status for a failed provider call: 400 for unsupported filters, 422
+// for dates beyond the plan's window, else 500
+func newsErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrUnsupportedQuery):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNewsDateRestricted):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Search query for the parameters produced by searchPosition
+func searchQueryFrom(params url.Values) SearchQuery {
+	page, _ := strconv.Atoi(params.Get("page"))
+	pageSize, _ := strconv.Atoi(params.Get("pageSize"))
+	return SearchQuery{
+		Q:              params.Get("q"),
+		Domains:        splitList(params.Get("domains")),
+		ExcludeDomains: splitList(params.Get("excludeDomains")),
+		Language:       params.Get("language"),
+		SortBy:         params.Get("sortBy"),
+		From:           params.Get("from"),
+		To:             params.Get("to"),
+		Page:           page,
+		PageSize:       pageSize,
+	}
+}
+
+// Non-empty, trimmed entries of a comma-separated list
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// NewsProvider backed by NewsAPI.org
+type NewsAPIProvider struct{}
+
+func (NewsAPIProvider) TopHeadlines(ctx context.Context, query HeadlinesQuery) (*NewsResponse, error) {
+	return fetchNews(ctx, newsAPIHeadlinesEndpoint(query))
+}
+
+func (NewsAPIProvider) Search(ctx context.Context, query SearchQuery) (*NewsResponse, error) {
+	return fetchNews(ctx, newsAPISearchEndpoint(query))
+}
+
+// Returned when the news provider answers with a server error or rate limit
+var ErrNewsUnavailable = errors.New("news provider unavailable")
+
+// Whether a NewsAPI failure means the service is down or unreachable,
+// rather than the request itself being bad
+func newsUpstreamDown(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, ErrNewsUnavailable)
+}
+
+// err with key masked in the request URL that a failed request's error
+// quotes, since both news APIs take their key as a query parameter
+func redactURLError(err error, key string) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+	return &url.Error{Op: urlErr.Op, URL: redactSecrets(urlErr.URL, []string{key}), Err: urlErr.Err}
+}
+
+// News cache key for the long-lived copy served while NewsAPI is down
+func staleNewsKey(endpoint string) string {
+	return "stale:" + endpoint
+}
+
+// Error body NewsAPI sends with a non-200 status
+type newsAPIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error for a non-200 NewsAPI response, recognizing the free-tier date restriction
+func newsAPIStatusError(status int, body []byte) error {
+	var apiErr newsAPIError
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Code == "parameterInvalid" && strings.Contains(apiErr.Message, "too far in the past") {
+		return fmt.Errorf("%w: %s", ErrNewsDateRestricted, apiErr.Message)
+	}
+	if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+		return fmt.Errorf("%w: NewsAPI returned status %d", ErrNewsUnavailable, status)
+	}
+	return fmt.Errorf("NewsAPI returned status %d", status)
+}
+
+// Best-effort decode of a malformed NewsAPI body: reads the top-level object
+// token by token and keeps every article decoded before the first bad one.
+// Reports whether the articles array was reached at all.
+func salvageNewsResponse(body []byte) (NewsResponse, bool) {
+	var newsResponse NewsResponse
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return newsResponse, false
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return newsResponse, false
+		}
+
+		var fieldErr error
+		switch tok {
+		case "status":
+			fieldErr = dec.Decode(&newsResponse.Status)
+		case "totalResults":
+			fieldErr = dec.Decode(&newsResponse.TotalResults)
+		case "articles":
+			if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+				return newsResponse, false
+			}
+			for dec.More() {
+				var article Article
+				if err := dec.Decode(&article); err != nil {
+					break
+				}
+				newsResponse.Articles = append(newsResponse.Articles, article)
+			}
+			return newsResponse, true
+		default:
+			var skipped json.RawMessage
+			fieldErr = dec.Decode(&skipped)
+		}
+		if fieldErr != nil {
+			return newsResponse, false
+		}
+	}
+	return newsResponse, false
+}
+
+// NewsAPI /top-headlines endpoint for a query
+func newsAPIHeadlinesEndpoint(query HeadlinesQuery) string {
+	params := url.Values{}
+	if len(query.Sources) > 0 {
+		params.Set("sources", strings.Join(query.Sources, ","))
+	} else {
+		if query.Country != "" {
+			params.Set("country", query.Country)
+		}
+		if query.Category != "" {
+			params.Set("category", query.Category)
+		}
+	}
+	if query.PageSize > 0 {
+		params.Set("pageSize", strconv.Itoa(query.PageSize))
+	}
+	return "/top-headlines?" + params.Encode()
+}
+
+// Search query as NewsAPI /everything parameters, which search cursors reuse
+func searchValues(query SearchQuery) url.Values {
+	params := url.Values{}
+	params.Set("q", query.Q)
+	if len(query.Domains) > 0 {
+		params.Set("domains", strings.Join(query.Domains, ","))
+	}
+	if len(query.ExcludeDomains) > 0 {
+		params.Set("excludeDomains", strings.Join(query.ExcludeDomains, ","))
+	}
+	for name, value := range map[string]string{"language": query.Language, "sortBy": query.SortBy, "from": query.From, "to": query.To} {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	if query.Page > 0 {
+		params.Set("page", strconv.Itoa(query.Page))
+	}
+	if query.PageSize > 0 {
+		params.Set("pageSize", strconv.Itoa(query.PageSize))
+	}
+	return params
+}
+
+// NewsAPI /everything endpoint for a query
+func newsAPISearchEndpoint(query SearchQuery) string {
+	return "/everything?" + searchValues(query).Encode()
+}
+
+// NewsProvider backed by the GNews API (gnews.io). Source and domain
+// filters have no GNews equivalent and are rejected.
+type GNewsProvider struct {
+	APIKey      string
+	BaseURL     string // defaults to https://gnews.io/api/v4
+	Client      *http.Client
+	Cache       Cache
+	CacheTTL    time.Duration
+	MaxArticles int
+	UserAgent   string
+}
+
+// GNews article list, as returned by both /top-headlines and /search
+type gnewsResponse struct {
+	TotalArticles int `json:"totalArticles"`
+	Articles      []struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Content     string `json:"content"`
+		URL         string `json:"url"`
+		Image       string `json:"image"`
+		PublishedAt string `json:"publishedAt"`
+		Source      struct {
+			Name string `json:"name"`
+		} `json:"source"`
+	} `json:"articles"`
+}
+
+func (p *GNewsProvider) TopHeadlines(ctx context.Context, query HeadlinesQuery) (*NewsResponse, error) {
+	if len(query.Sources) > 0 {
+		return nil, fmt.Errorf("sources filter %w", ErrUnsupportedQuery)
+	}
+
+	params := url.Values{}
+	params.Set("lang", "en")
+	if query.Country != "" {
+		params.Set("country", query.Country)
+	}
+	if query.Category != "" {
+		params.Set("category", query.Category)
+	}
+	if query.PageSize > 0 {
+		params.Set("max", strconv.Itoa(query.PageSize))
+	}
+	return p.fetch(ctx, "/top-headlines", params)
+}
+
+func (p *GNewsProvider) Search(ctx context.Context, query SearchQuery) (*NewsResponse, error) {
+	if len(query.Domains) > 0 || len(query.ExcludeDomains) > 0 {
+		return nil, fmt.Errorf("domain filters %w", ErrUnsupportedQuery)
+	}
+
+	if query.SortBy == "popularity" {
+		return nil, fmt.Errorf("sortBy=popularity %w", ErrUnsupportedQuery)
+	}
+
+	params := url.Values{}
+	params.Set("q", query.Q)
+	params.Set("lang", "en")
+	if query.Language != "" {
+		params.Set("lang", query.Language)
+	}
+	switch query.SortBy {
+	case "publishedAt":
+		params.Set("sortby", "publishedAt")
+	case "relevancy":
+		params.Set("sortby", "relevance")
+	}
+	if query.From != "" {
+		params.Set("from", query.From)
+	}
+	if query.To != "" {
+		params.Set("to", query.To)
+	}
+	if query.Page > 0 {
+		params.Set("page", strconv.Itoa(query.Page))
+	}
+	if query.PageSize > 0 {
+		params.Set("max", strconv.Itoa(query.PageSize))
+	}
+	return p.fetch(ctx, "/search", params)
+}
+
+// GET a GNews endpoint through the news cache and convert it to a NewsResponse
+func (p *GNewsProvider) fetch(ctx context.Context, path string, params url.Values) (*NewsResponse, error) {
+	cacheKey := "gnews:" + path + "?" + params.Encode()
+	body, ok, err := p.Cache.Get(ctx, cacheKey)
+	if err != nil {
+		slog.WarnContext(ctx, "news cache get failed", "error", err)
+	}
+	recordCacheLookup(ctx, "gnews", ok)
+
+	if !ok {
+		baseURL := p.BaseURL
+		if baseURL == "" {
+			baseURL = "https://gnews.io/api/v4"
+		}
+		slog.DebugContext(ctx, "making GNews request", "path", path, "params", params.Encode())
+
+		params.Set("apikey", p.APIKey)
+		req, err := http.NewRequestWithContext(ctx, "GET", baseURL+path+"?"+params.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %v", err)
+		}
+		setUserAgent(req, p.UserAgent)
+
+		start := time.Now()
+		resp, err := p.Client.Do(req)
+		if err != nil {
+			err = fmt.Errorf("failed to fetch news: %w", redactURLError(err, p.APIKey))
+			recordNewsHealth(err)
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		body, err = io.ReadAll(resp.Body)
+		recordUpstream(ctx, "gnews", time.Since(start))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			slog.WarnContext(ctx, "GNews error", "status", resp.StatusCode)
+			err := fmt.Errorf("GNews returned status %d", resp.StatusCode)
+			if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+				err = fmt.Errorf("%w: GNews returned status %d", ErrNewsUnavailable, resp.StatusCode)
+			}
+			recordNewsHealth(err)
+			return nil, err
+		}
+		recordNewsHealth(nil)
+
+		if err := p.Cache.Set(ctx, cacheKey, body, p.CacheTTL); err != nil {
+			slog.WarnContext(ctx, "news cache set failed", "error", err)
+		}
+	}
+
+	var parsed gnewsResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %v", err)
+	}
+
+	newsResponse := &NewsResponse{Status: "ok", TotalResults: parsed.TotalArticles, Articles: make([]Article, 0, len(parsed.Articles))}
+	for _, article := range parsed.Articles {
+		newsResponse.Articles = append(newsResponse.Articles, Article{
+			Source:      Source{Name: article.Source.Name},
+			Title:       article.Title,
+			Description: article.Description,
+			URL:         article.URL,
+			URLToImage:  article.Image,
+			PublishedAt: article.PublishedAt,
+			Content:     article.Content,
+		})
+	}
+	truncateArticles(newsResponse, p.MaxArticles)
+	return newsResponse, nil
+}
@@ -0,0 +1,716 @@
+package ministry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHeadlinesBySources(t *testing.T) {
+	news := newFakeNews(Article{Title: "Lift broken again"})
+	setupTest(t, upstreams(nil, news))
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines?sources=bbc-news,%20reuters", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	query := news.Queries()[0]
+	if query.Get("sources") != "bbc-news,reuters" || query.Has("country") || query.Has("category") {
+		t.Errorf("NewsAPI query = %v, want sources alone", query)
+	}
+
+	rec = do(newRequest(http.MethodGet, "/api/news/headlines", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	query = news.Queries()[1]
+	if query.Get("country") != "us" || query.Has("sources") {
+		t.Errorf("default NewsAPI query = %v, want country=us", query)
+	}
+}
+
+func TestHeadlinesSourcesConflictWithCategory(t *testing.T) {
+	news := newFakeNews()
+	setupTest(t, upstreams(nil, news))
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines?sources=bbc-news&category=science", ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+	if len(news.Queries()) != 0 {
+		t.Error("conflicting request reached NewsAPI")
+	}
+}
+
+func TestParseDomains(t *testing.T) {
+	got, err := parseDomains("domains", []string{" BBC.co.uk", "", "reuters.com "})
+	if err != nil || !slices.Equal(got, []string{"bbc.co.uk", "reuters.com"}) {
+		t.Errorf("parseDomains = %q, %v", got, err)
+	}
+	for _, junk := range []string{"localhost", "bbc .co.uk", "https://bbc.co.uk", "-bad-.com"} {
+		if _, err := parseDomains("domains", []string{junk}); err == nil {
+			t.Errorf("parseDomains(%q) succeeded, want an error", junk)
+		}
+	}
+}
+
+func TestSearchDomainFilters(t *testing.T) {
+	news := newFakeNews(Article{Title: "Lift broken again"})
+	setupTest(t, upstreams(nil, news))
+
+	rec := do(newRequest(http.MethodGet, "/api/news/search?q=lift&domains=BBC.co.uk,reuters.com&excludeDomains=example.com", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	query := news.Queries()[0]
+	if query.Get("domains") != "bbc.co.uk,reuters.com" || query.Get("excludeDomains") != "example.com" {
+		t.Errorf("NewsAPI query = %v", query)
+	}
+
+	rec = do(newRequest(http.MethodGet, "/api/news/search?q=lift&excludeDomains=not_a_host", ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d for a junk domain, want 400", rec.Code)
+	}
+	if len(news.Queries()) != 1 {
+		t.Error("junk domain reached NewsAPI")
+	}
+}
+
+func TestNormalizeSearchQuery(t *testing.T) {
+	valid := map[string]string{
+		`lift`: `lift`,
+		`  "Ministry of Plenty"   AND  (rations OR  chocolate) NOT   Goldstein `: `"Ministry of Plenty" AND (rations OR chocolate) NOT Goldstein`,
+		`+victory -defeat`:        `+victory -defeat`,
+		`"(not a group" AND lift`: `"(not a group" AND lift`,
+		`NOT Goldstein`:           `NOT Goldstein`,
+	}
+	for q, want := range valid {
+		if got, err := normalizeSearchQuery(q); err != nil || got != want {
+			t.Errorf("normalizeSearchQuery(%q) = %q, %v; want %q", q, got, err, want)
+		}
+	}
+
+	invalid := map[string]string{
+		`"Ministry of Plenty`:    "double quotes",
+		`(rations OR chocolate`:  "without a matching ')'",
+		`rations OR chocolate)`:  "without a matching '('",
+		`lift AND ()`:            "empty parentheses",
+		`AND lift`:               "AND needs a term before it",
+		`lift OR`:                "OR needs a term after it",
+		`lift NOT`:               "NOT needs a term after it",
+		strings.Repeat("a", 501): "at most 500 characters",
+	}
+	for q, want := range invalid {
+		if _, err := normalizeSearchQuery(q); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("normalizeSearchQuery(%.20q) error = %v, want it to mention %q", q, err, want)
+		}
+	}
+}
+
+func TestSearchBooleanQuery(t *testing.T) {
+	news := newFakeNews(Article{Title: "Lift broken again"})
+	setupTest(t, upstreams(nil, news))
+
+	q := `"Ministry of Plenty" AND (rations OR chocolate) NOT Goldstein`
+	rec := do(newRequest(http.MethodGet, "/api/news/search?q="+url.QueryEscape(q), ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if got := news.Queries()[0].Get("q"); got != q {
+		t.Errorf("NewsAPI q = %q, want the operators preserved", got)
+	}
+
+	rec = do(newRequest(http.MethodGet, "/api/news/search?q="+url.QueryEscape(`(rations OR chocolate`), ""))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "')'") {
+		t.Errorf("status = %d, body %q; want 400 naming the missing ')'", rec.Code, rec.Body.String())
+	}
+	if len(news.Queries()) != 1 {
+		t.Error("malformed query reached NewsAPI")
+	}
+}
+
+func TestTruncateArticles(t *testing.T) {
+	response := &NewsResponse{TotalResults: 3, Articles: make([]Article, 3)}
+	truncateArticles(response, 5)
+	if len(response.Articles) != 3 || response.Truncated {
+		t.Errorf("under the cap: %d articles, truncated %v", len(response.Articles), response.Truncated)
+	}
+	truncateArticles(response, 2)
+	if len(response.Articles) != 2 || !response.Truncated || response.TotalResults != 3 {
+		t.Errorf("over the cap: %d articles, truncated %v, totalResults %d", len(response.Articles), response.Truncated, response.TotalResults)
+	}
+}
+
+func TestHeadlinesPageSizeAndMaxArticles(t *testing.T) {
+	news := newFakeNews(Article{Title: "one"}, Article{Title: "two"}, Article{Title: "three"})
+	setupTest(t, upstreams(nil, news), "DEFAULT_PAGE_SIZE", "1", "MAX_ARTICLES", "2")
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines", ""))
+	var response NewsResponse
+	decodeJSON(t, rec, &response)
+	if len(response.Articles) != 2 || !response.Truncated || response.TotalResults != 3 {
+		t.Errorf("response = %d articles, truncated %v, totalResults %d; want 2 of 3, truncated", len(response.Articles), response.Truncated, response.TotalResults)
+	}
+	if got := news.Queries()[0].Get("pageSize"); got != "1" {
+		t.Errorf("NewsAPI pageSize = %q, want DEFAULT_PAGE_SIZE", got)
+	}
+
+	// Larger requested pages are capped at MAX_ARTICLES
+	do(newRequest(http.MethodGet, "/api/news/headlines?category=science&pageSize=50", ""))
+	if got := news.Queries()[1].Get("pageSize"); got != "2" {
+		t.Errorf("NewsAPI pageSize = %q, want MAX_ARTICLES", got)
+	}
+}
+
+func TestPageSizeParam(t *testing.T) {
+	cfg := &Config{DefaultPageSize: 20, MaxArticles: 100}
+	tests := map[string]int{"": 20, "1": 1, "100": 100}
+	for raw, want := range tests {
+		if got, err := pageSizeParam(url.Values{"pageSize": {raw}}, cfg); err != nil || got != want {
+			t.Errorf("pageSizeParam(%q) = %d, %v; want %d", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"101", "0", "-5", "ten", "2.5"} {
+		if _, err := pageSizeParam(url.Values{"pageSize": {raw}}, cfg); err == nil || !strings.Contains(err.Error(), "NewsAPI returns at most 100") {
+			t.Errorf("pageSizeParam(%q) error = %v, want it to name NewsAPI's cap", raw, err)
+		}
+	}
+}
+
+func TestPageSizeCap(t *testing.T) {
+	news := newFakeNews(Article{Title: "Lift broken again"})
+	setupTest(t, upstreams(nil, news))
+
+	do(newRequest(http.MethodGet, "/api/news/headlines?pageSize=100", ""))
+	if got := news.Queries()[0].Get("pageSize"); got != "100" {
+		t.Errorf("NewsAPI pageSize = %q, want 100", got)
+	}
+
+	for _, req := range []*http.Request{
+		newRequest(http.MethodGet, "/api/news/headlines?pageSize=101", ""),
+		newRequest(http.MethodGet, "/api/news/search?q=lift&pageSize=101", ""),
+		newRequest(http.MethodPost, "/api/news/search", `{"query": "lift", "pageSize": 101}`),
+	} {
+		rec := do(req)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "at most 100 articles per page") {
+			t.Errorf("%s %s: status = %d, body %q; want 400 naming the cap", req.Method, req.URL, rec.Code, rec.Body.String())
+		}
+	}
+	if len(news.Queries()) != 1 {
+		t.Errorf("pageSize over the cap reached NewsAPI: %v", news.Queries()[1:])
+	}
+}
+
+func TestCheckCategoryAllowlist(t *testing.T) {
+	allowed := []string{"technology", "science"}
+	if err := checkCategory("science", allowed); err != nil {
+		t.Errorf("allowed category: %v", err)
+	}
+	if err := checkCategory("sports", nil); err != nil {
+		t.Errorf("unrestricted: %v", err)
+	}
+	err := checkCategory("sports", allowed)
+	if !errors.Is(err, ErrCategoryNotAllowed) || queryErrorStatus(err) != http.StatusForbidden || !strings.Contains(err.Error(), "technology, science") {
+		t.Errorf("disallowed category: %v", err)
+	}
+	err = checkCategory("gossip", allowed)
+	if errors.Is(err, ErrCategoryNotAllowed) || queryErrorStatus(err) != http.StatusBadRequest {
+		t.Errorf("invalid category: %v, want a 400 rather than a 403", err)
+	}
+}
+
+func TestAllowedCategories(t *testing.T) {
+	news := newFakeNews(Article{Title: "Lift broken again", URL: "https://example.com/lift"})
+	setupTest(t, upstreams(nil, news), "ALLOWED_CATEGORIES", "Technology, science")
+
+	if rec := do(newRequest(http.MethodGet, "/api/news/headlines?category=science", "")); rec.Code != http.StatusOK {
+		t.Errorf("allowed category: status = %d", rec.Code)
+	}
+	for _, target := range []string{
+		"/api/news/headlines?category=sports",
+		"/api/news/headlines/aggregate?categories=science,sports",
+		"/api/news/combined?category=sports&q=lift",
+	} {
+		rec := do(newRequest(http.MethodGet, target, ""))
+		if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "Category not allowed") {
+			t.Errorf("%s: status = %d, body %q; want 403", target, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := do(newRequest(http.MethodGet, "/api/news/headlines?category=gossip", "")); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid category: status = %d, want 400", rec.Code)
+	}
+	if len(news.Queries()) != 1 {
+		t.Errorf("NewsAPI got %d requests, want only the allowed one", len(news.Queries()))
+	}
+}
+
+func TestAllowedCategoriesUnrestricted(t *testing.T) {
+	setupTest(t, upstreams(nil, newFakeNews(Article{Title: "Lift broken again"})))
+
+	if rec := do(newRequest(http.MethodGet, "/api/news/headlines?category=sports", "")); rec.Code != http.StatusOK {
+		t.Errorf("status = %d without ALLOWED_CATEGORIES, want 200", rec.Code)
+	}
+}
+
+func TestAllowedCategoriesConfig(t *testing.T) {
+	setupTest(t, nil)
+	for _, env := range [][2]string{{"ALLOWED_CATEGORIES", "science,gossip"}, {"DEFAULT_CATEGORY", "sports"}} {
+		t.Run(env[0], func(t *testing.T) {
+			t.Setenv("ALLOWED_CATEGORIES", "science")
+			t.Setenv(env[0], env[1])
+			if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), env[0]) {
+				t.Errorf("loadConfig error = %v, want it to name %s", err, env[0])
+			}
+		})
+	}
+}
+
+// NewsProvider returning canned articles and recording the queries it gets
+type fakeProvider struct {
+	Articles []Article
+	Err      error
+
+	mu        sync.Mutex
+	headlines []HeadlinesQuery
+	searches  []SearchQuery
+}
+
+func (p *fakeProvider) TopHeadlines(ctx context.Context, query HeadlinesQuery) (*NewsResponse, error) {
+	p.mu.Lock()
+	p.headlines = append(p.headlines, query)
+	p.mu.Unlock()
+	return p.response()
+}
+
+func (p *fakeProvider) Search(ctx context.Context, query SearchQuery) (*NewsResponse, error) {
+	p.mu.Lock()
+	p.searches = append(p.searches, query)
+	p.mu.Unlock()
+	return p.response()
+}
+
+func (p *fakeProvider) response() (*NewsResponse, error) {
+	if p.Err != nil {
+		return nil, p.Err
+	}
+	return &NewsResponse{Status: "ok", TotalResults: len(p.Articles), Articles: slices.Clone(p.Articles)}, nil
+}
+
+func (p *fakeProvider) Searches() []SearchQuery {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.searches)
+}
+
+func (p *fakeProvider) Headlines() []HeadlinesQuery {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.headlines)
+}
+
+// Swap in provider for the rest of the test
+func useNewsProvider(t *testing.T, provider NewsProvider) {
+	t.Helper()
+	previous := currentNewsProvider()
+	setNewsProvider(provider)
+	t.Cleanup(func() { setNewsProvider(previous) })
+}
+
+// Provider with separate headlines per category; "" is the general feed
+type categoryProvider struct {
+	fakeProvider
+	byCategory map[string][]Article
+}
+
+func (p *categoryProvider) TopHeadlines(ctx context.Context, query HeadlinesQuery) (*NewsResponse, error) {
+	p.mu.Lock()
+	p.headlines = append(p.headlines, query)
+	p.mu.Unlock()
+	if p.Err != nil {
+		return nil, p.Err
+	}
+	articles := slices.Clone(p.byCategory[query.Category])
+	return &NewsResponse{Status: "ok", TotalResults: len(articles), Articles: articles}, nil
+}
+
+// Categories asked for, in order
+func (p *categoryProvider) Categories() []string {
+	var categories []string
+	for _, query := range p.Headlines() {
+		categories = append(categories, query.Category)
+	}
+	return categories
+}
+
+func TestHeadlinesQueryDefaultCategory(t *testing.T) {
+	cfg := &Config{DefaultPageSize: 20, MaxArticles: 100, DefaultCategory: "science"}
+
+	query, _ := headlinesQuery(url.Values{}, cfg)
+	if query.Category != "science" || !query.CategoryDefaulted || query.Country != "us" {
+		t.Errorf("without a category: %+v, want DEFAULT_CATEGORY", query)
+	}
+	query, _ = headlinesQuery(url.Values{"category": {"sports"}}, cfg)
+	if query.Category != "sports" || query.CategoryDefaulted {
+		t.Errorf("with a category: %+v, want the requested one", query)
+	}
+	query, _ = headlinesQuery(url.Values{"sources": {"bbc-news"}}, cfg)
+	if query.Category != "" || query.CategoryDefaulted {
+		t.Errorf("with sources: %+v, want no category", query)
+	}
+	query, _ = headlinesQuery(url.Values{}, &Config{DefaultPageSize: 20, MaxArticles: 100})
+	if query.Category != "" || query.CategoryDefaulted {
+		t.Errorf("without DEFAULT_CATEGORY: %+v, want no category", query)
+	}
+}
+
+func TestDefaultCategory(t *testing.T) {
+	setupTest(t, nil, "DEFAULT_CATEGORY", "Science")
+
+	provider := &categoryProvider{byCategory: map[string][]Article{
+		"science": {{Title: "Ministry of Plenty discovers new grammes"}},
+		"":        {{Title: "Lift broken again"}},
+	}}
+	useNewsProvider(t, provider)
+
+	var response NewsResponse
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/news/headlines", "")), &response)
+	if len(response.Articles) != 1 || response.Articles[0].Title != "Ministry of Plenty discovers new grammes" {
+		t.Errorf("articles = %+v, want the default category's", response.Articles)
+	}
+	if got := provider.Categories(); !slices.Equal(got, []string{"science"}) {
+		t.Errorf("categories fetched = %q, want [science]", got)
+	}
+}
+
+func TestDefaultCategoryFallsBackWhenEmpty(t *testing.T) {
+	setupTest(t, nil, "DEFAULT_CATEGORY", "science")
+
+	provider := &categoryProvider{byCategory: map[string][]Article{"": {{Title: "Lift broken again"}}}}
+	useNewsProvider(t, provider)
+
+	var response NewsResponse
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/news/headlines", "")), &response)
+	if len(response.Articles) != 1 || response.Articles[0].Title != "Lift broken again" {
+		t.Errorf("articles = %+v, want the general feed", response.Articles)
+	}
+	if got := provider.Categories(); !slices.Equal(got, []string{"science", ""}) {
+		t.Errorf("categories fetched = %q, want science then the general feed", got)
+	}
+
+	// A category the client asked for is answered as is, even when empty
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/news/headlines?category=science", "")), &response)
+	if len(response.Articles) != 0 || len(provider.Headlines()) != 3 {
+		t.Errorf("explicit category: %d articles after %d fetches, want an empty answer without a fallback", len(response.Articles), len(provider.Headlines()))
+	}
+}
+
+func TestDefaultCategoryConfig(t *testing.T) {
+	setupTest(t, nil)
+	t.Setenv("DEFAULT_CATEGORY", "gossip")
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "DEFAULT_CATEGORY") {
+		t.Errorf("loadConfig error = %v, want it to name DEFAULT_CATEGORY", err)
+	}
+}
+
+func TestNewNewsProviderPicksProvider(t *testing.T) {
+	cache := NewMemoryCache()
+	if _, ok := newNewsProvider(&Config{NewsProvider: "newsapi"}, cache).(NewsAPIProvider); !ok {
+		t.Error("NEWS_PROVIDER=newsapi didn't build a NewsAPIProvider")
+	}
+	if _, ok := newNewsProvider(&Config{NewsProvider: "gnews"}, cache).(*GNewsProvider); !ok {
+		t.Error("NEWS_PROVIDER=gnews didn't build a GNewsProvider")
+	}
+}
+
+func TestLoadConfigNewsProvider(t *testing.T) {
+	t.Setenv("NEWS_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "test-openai-key")
+
+	t.Setenv("NEWS_PROVIDER", "gnews")
+	t.Setenv("GNEWS_API_KEY", "")
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "GNEWS_API_KEY") {
+		t.Errorf("gnews without a key: error = %v", err)
+	}
+	t.Setenv("GNEWS_API_KEY", "test-gnews-key")
+	if cfg, err := loadConfig(); err != nil || cfg.NewsProvider != "gnews" {
+		t.Errorf("gnews with a key: %v", err)
+	}
+
+	t.Setenv("NEWS_PROVIDER", "mediastack")
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "NEWS_PROVIDER") {
+		t.Errorf("NEWS_PROVIDER=mediastack: error = %v", err)
+	}
+}
+
+func TestHandlersUseNewsProvider(t *testing.T) {
+	setupTest(t, nil)
+	provider := &fakeProvider{Articles: []Article{{Title: "Chocolate ration raised", URL: "https://minitrue.example/1"}}}
+	useNewsProvider(t, provider)
+
+	var response NewsResponse
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/news/headlines?category=science", "")), &response)
+	if len(response.Articles) != 1 || response.Articles[0].Title != "Chocolate ration raised" {
+		t.Errorf("headlines = %+v", response)
+	}
+	if got := provider.Headlines(); len(got) != 1 || got[0].Category != "science" {
+		t.Errorf("provider headline queries = %+v", got)
+	}
+
+	rec := do(newRequest(http.MethodPost, "/api/news/search", `{"query": "chocolate", "language": "en"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("search status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if got := provider.Searches(); len(got) != 1 || got[0].Q != "chocolate" || got[0].Language != "en" {
+		t.Errorf("provider searches = %+v", got)
+	}
+
+	provider.Err = fmt.Errorf("sources filter %w", ErrUnsupportedQuery)
+	if rec := do(newRequest(http.MethodGet, "/api/news/headlines?sources=bbc-news", "")); rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d for an unsupported filter, want 400", rec.Code)
+	}
+	provider.Err = errors.New("provider down")
+	if rec := do(newRequest(http.MethodGet, "/api/news/headlines?category=health", "")); rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d for a provider failure, want 500", rec.Code)
+	}
+}
+
+func TestGNewsProvider(t *testing.T) {
+	var mu sync.Mutex
+	var queries []url.Values
+	upstream := http.NewServeMux()
+	gnews := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries = append(queries, r.URL.Query())
+		mu.Unlock()
+		w.Write([]byte(`{"totalArticles": 1, "articles": [{"title": "Lift broken again", "url": "https://gnews.example/1", "image": "https://gnews.example/1.jpg", "publishedAt": "1984-04-04T12:00:00Z", "source": {"name": "The Times"}}]}`))
+	}
+	upstream.HandleFunc("gnews.io/api/v4/top-headlines", gnews)
+	upstream.HandleFunc("gnews.io/api/v4/search", gnews)
+	setupTest(t, upstream, "NEWS_PROVIDER", "gnews", "GNEWS_API_KEY", "test-gnews-key")
+
+	var response NewsResponse
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/news/headlines?category=science", "")), &response)
+	if len(response.Articles) != 1 {
+		t.Fatalf("headlines = %+v", response)
+	}
+	article := response.Articles[0]
+	if article.Title != "Lift broken again" || article.URLToImage != "https://gnews.example/1.jpg" || article.Source.Name != "The Times" {
+		t.Errorf("article = %+v", article)
+	}
+	if query := queries[0]; query.Get("apikey") != "test-gnews-key" || query.Get("category") != "science" {
+		t.Errorf("GNews query = %v", query)
+	}
+
+	rec := do(newRequest(http.MethodPost, "/api/news/search", `{"query": "lift", "sortBy": "relevancy"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("search status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if query := queries[len(queries)-1]; query.Get("q") != "lift" || query.Get("sortby") != "relevance" {
+		t.Errorf("GNews search query = %v", query)
+	}
+
+	// Filters with no GNews equivalent are refused before any request
+	calls := len(queries)
+	for _, req := range []*http.Request{
+		newRequest(http.MethodGet, "/api/news/headlines?sources=bbc-news", ""),
+		newRequest(http.MethodGet, "/api/news/search?q=lift&domains=bbc.co.uk", ""),
+		newRequest(http.MethodPost, "/api/news/search", `{"query": "lift", "sortBy": "popularity"}`),
+	} {
+		if rec := do(req); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: status = %d, want 400", req.Method, req.URL, rec.Code)
+		}
+	}
+	if len(queries) != calls {
+		t.Error("unsupported filter reached GNews")
+	}
+}
+
+func TestNewsAPIStatusError(t *testing.T) {
+	restricted := []byte(`{"status": "error", "code": "parameterInvalid", "message": "You are trying to request results too far in the past. Your plan permits you to request articles as far back as 2024-04-01."}`)
+	if err := newsAPIStatusError(http.StatusBadRequest, restricted); !errors.Is(err, ErrNewsDateRestricted) || newsErrorStatus(err) != http.StatusUnprocessableEntity {
+		t.Errorf("date restriction: %v", err)
+	}
+
+	tests := []struct {
+		status int
+		body   string
+		down   bool
+	}{
+		{http.StatusBadRequest, `{"status": "error", "code": "parameterInvalid", "message": "The q parameter is missing"}`, false},
+		{http.StatusUnauthorized, `{"status": "error", "code": "apiKeyInvalid"}`, false},
+		{http.StatusTooManyRequests, `{"status": "error", "code": "rateLimited"}`, true},
+		{http.StatusBadGateway, `<html>bad gateway</html>`, true},
+	}
+	for _, test := range tests {
+		err := newsAPIStatusError(test.status, []byte(test.body))
+		if errors.Is(err, ErrNewsDateRestricted) || newsUpstreamDown(err) != test.down {
+			t.Errorf("status %d %s: %v", test.status, test.body, err)
+		}
+	}
+}
+
+func TestSearchDateRestricted(t *testing.T) {
+	upstream := http.NewServeMux()
+	upstream.HandleFunc(newsSearchPath, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status": "error", "code": "parameterInvalid", "message": "You are trying to request results too far in the past. Your plan permits you to request articles as far back as 2024-04-01."}`))
+	})
+	setupTest(t, upstream)
+
+	rec := do(newRequest(http.MethodPost, "/api/news/search", `{"query": "lift", "from": "2020-01-01"}`))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "does not allow searching articles this old") || !strings.Contains(body, "2024-04-01") {
+		t.Errorf("body = %q, want the restriction explained", body)
+	}
+}
+
+// NewsAPI stand-in serving articles until Fail is set, then answering 503
+type flakyNews struct {
+	news *fakeNews
+	fail atomic.Bool
+}
+
+func (f *flakyNews) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.fail.Load() {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+		return
+	}
+	f.news.ServeHTTP(w, r)
+}
+
+func TestServeStaleOnError(t *testing.T) {
+	news := &flakyNews{news: newFakeNews(Article{Title: "Lift broken again"})}
+	setupTest(t, upstreams(nil, news), "SERVE_STALE_ON_ERROR", "true", "NEWS_CACHE_TTL", "1ms")
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines", ""))
+	if rec.Code != http.StatusOK || rec.Header().Get("Warning") != "" {
+		t.Fatalf("status = %d, Warning %q", rec.Code, rec.Header().Get("Warning"))
+	}
+
+	// The fresh copy expires, then NewsAPI goes down
+	time.Sleep(5 * time.Millisecond)
+	news.fail.Store(true)
+
+	rec = do(newRequest(http.MethodGet, "/api/news/headlines", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d while NewsAPI is down, want the stale copy", rec.Code)
+	}
+	if !strings.Contains(rec.Header().Get("Warning"), "Response is Stale") {
+		t.Errorf("Warning = %q", rec.Header().Get("Warning"))
+	}
+	var response NewsResponse
+	decodeJSON(t, rec, &response)
+	if !response.Stale || len(response.Articles) != 1 || response.Articles[0].Title != "Lift broken again" {
+		t.Errorf("response = %+v, want the stale articles flagged", response)
+	}
+
+	// Nothing cached for this query, so the failure shows
+	if rec := do(newRequest(http.MethodGet, "/api/news/headlines?category=science", "")); rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d with no prior copy, want 500", rec.Code)
+	}
+}
+
+func TestStaleNewsNeedsFlag(t *testing.T) {
+	news := &flakyNews{news: newFakeNews(Article{Title: "Lift broken again"})}
+	setupTest(t, upstreams(nil, news), "NEWS_CACHE_TTL", "1ms")
+
+	do(newRequest(http.MethodGet, "/api/news/headlines", ""))
+	time.Sleep(5 * time.Millisecond)
+	news.fail.Store(true)
+
+	if rec := do(newRequest(http.MethodGet, "/api/news/headlines", "")); rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d without SERVE_STALE_ON_ERROR, want 500", rec.Code)
+	}
+}
+
+// NewsAPI body cut off partway through the second article
+const truncatedNewsBody = `{"status": "ok", "totalResults": 3, "articles": [` +
+	`{"title": "Lift broken again", "url": "https://example.com/lift", "source": {"name": "Times"}},` +
+	`{"title": "Rations cut", "descr`
+
+func TestSalvageNewsResponse(t *testing.T) {
+	response, ok := salvageNewsResponse([]byte(truncatedNewsBody))
+	if !ok || response.Status != "ok" || response.TotalResults != 3 || len(response.Articles) != 1 || response.Articles[0].Title != "Lift broken again" {
+		t.Errorf("salvageNewsResponse = %+v, %v; want the first article", response, ok)
+	}
+
+	for _, body := range []string{"", "<html>Bad gateway</html>", `["not", "an", "object"]`, `{"status": "ok", "totalRes`, `{"articles": {"title": "x"}}`} {
+		if _, ok := salvageNewsResponse([]byte(body)); ok {
+			t.Errorf("salvageNewsResponse(%q) reported articles", body)
+		}
+	}
+}
+
+func TestLenientParse(t *testing.T) {
+	var calls atomic.Int32
+	news := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(truncatedNewsBody))
+	})
+
+	t.Run("off", func(t *testing.T) {
+		setupTest(t, upstreams(nil, news))
+		if rec := do(newRequest(http.MethodGet, "/api/news/headlines", "")); rec.Code == http.StatusOK {
+			t.Errorf("status = %d, want a truncated body to fail", rec.Code)
+		}
+	})
+
+	t.Run("on", func(t *testing.T) {
+		setupTest(t, upstreams(nil, news), "LENIENT_PARSE", "true")
+		logs := captureLogs(t, slog.LevelWarn)
+
+		rec := do(newRequest(http.MethodGet, "/api/news/headlines", ""))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+		}
+		var response NewsResponse
+		decodeJSON(t, rec, &response)
+		if len(response.Articles) != 1 || response.Articles[0].Title != "Lift broken again" {
+			t.Errorf("articles = %+v, want the one that came through", response.Articles)
+		}
+		if records := logRecords(t, logs, "malformed NewsAPI response, using salvaged articles"); len(records) != 1 {
+			t.Errorf("logged %d warnings, want 1", len(records))
+		}
+
+		// A broken body isn't cached
+		before := calls.Load()
+		do(newRequest(http.MethodGet, "/api/news/headlines", ""))
+		if calls.Load() != before+1 {
+			t.Error("the truncated body was served from the cache")
+		}
+	})
+}
+
+func TestNewsErrorsHideAPIKey(t *testing.T) {
+	for _, test := range []struct {
+		provider, key string
+	}{
+		{"newsapi", "test-news-key"},
+		{"gnews", "test-gnews-key"},
+	} {
+		t.Run(test.provider, func(t *testing.T) {
+			setupTest(t, nil, "NEWS_PROVIDER", test.provider, "GNEWS_API_KEY", "test-gnews-key")
+			upstreamTransport = roundTripFunc(func(*http.Request) (*http.Response, error) {
+				return nil, errors.New("connection refused")
+			})
+			useNewsProvider(t, newNewsProvider(currentConfig(), newsCache))
+
+			rec := do(newRequest(http.MethodGet, "/api/news/headlines", ""))
+			if body := rec.Body.String(); !strings.Contains(body, "connection refused") || strings.Contains(body, test.key) {
+				t.Errorf("status %d, body %q; want the error without the API key", rec.Code, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package ministry
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Default Oldspeak to Newspeak substitutions, shared with the serverless handler
+//
+//go:embed api/newspeak.json
+var defaultNewspeakJSON []byte
+
+// Word substitution map with a compiled whole-word matcher
+type NewspeakDictionary struct {
+	terms   map[string]string
+	pattern *regexp.Regexp
+}
+
+// Build a dictionary; terms are matched case-insensitively, longest first
+func newNewspeakDictionary(terms map[string]string) (*NewspeakDictionary, error) {
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("newspeak dictionary is empty")
+	}
+
+	normalized := make(map[string]string, len(terms))
+	quoted := make([]string, 0, len(terms))
+	for term, replacement := range terms {
+		term = strings.ToLower(strings.TrimSpace(term))
+		if term == "" {
+			return nil, fmt.Errorf("newspeak dictionary contains an empty term")
+		}
+		normalized[term] = replacement
+		quoted = append(quoted, regexp.QuoteMeta(term))
+	}
+
+	// Longer terms win when they overlap, e.g. "war crimes" before "war"
+	sort.Slice(quoted, func(i, j int) bool {
+		if len(quoted[i]) != len(quoted[j]) {
+			return len(quoted[i]) > len(quoted[j])
+		}
+		return quoted[i] < quoted[j]
+	})
+
+	return &NewspeakDictionary{
+		terms:   normalized,
+		pattern: regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`),
+	}, nil
+}
+
+// Load the dictionary from a JSON file, or the embedded default when path is empty
+func loadNewspeakDictionary(path string) (*NewspeakDictionary, error) {
+	data := defaultNewspeakJSON
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read newspeak dictionary: %v", err)
+		}
+	}
+
+	var terms map[string]string
+	if err := json.Unmarshal(data, &terms); err != nil {
+		return nil, fmt.Errorf("failed to parse newspeak dictionary: %v", err)
+	}
+	return newNewspeakDictionary(terms)
+}
+
+// Replace dictionary words in text, preserving case; returns the replacement count
+func (d *NewspeakDictionary) Apply(text string) (string, int) {
+	count := 0
+	result := d.pattern.ReplaceAllStringFunc(text, func(word string) string {
+		count++
+		return matchCase(word, d.terms[strings.ToLower(word)])
+	})
+	return result, count
+}
+
+// Apply the casing of the original word to its replacement
+func matchCase(original, replacement string) string {
+	if strings.ToUpper(original) == original && strings.ToLower(original) != original {
+		return strings.ToUpper(replacement)
+	}
+
+	first, _ := utf8.DecodeRuneInString(original)
+	if unicode.IsUpper(first) {
+		r, size := utf8.DecodeRuneInString(replacement)
+		return string(unicode.ToUpper(r)) + replacement[size:]
+	}
+	return replacement
+}
+
+// Canned Big Brother framing for locally transformed news
+var newspeakPrefixes = []string{
+	"Big Brother is watching:",
+	"The Ministry of Truth confirms:",
+	"By order of the Party:",
+	"Telescreen bulletin:",
+}
+
+var newspeakSuffixes = []string{
+	"War is Peace.",
+	"Freedom is Slavery.",
+	"Ignorance is Strength.",
+	"Report all thoughtcrime.",
+}
+
+// Deterministic offline transformer using word substitution and canned phrasing
+type LocalTransformer struct {
+	Dictionary *NewspeakDictionary
+}
+
+func (t LocalTransformer) Transform(ctx context.Context, title, description string) (string, error) {
+	h := fnv.New32a()
+	h.Write([]byte(title + "\n" + description))
+	seed := h.Sum32()
+
+	prefix := newspeakPrefixes[seed%uint32(len(newspeakPrefixes))]
+	suffix := newspeakSuffixes[(seed/7)%uint32(len(newspeakSuffixes))]
+
+	subject := strings.TrimSpace(title)
+	if subject == "" {
+		subject = strings.TrimSpace(description)
+	}
+	subject, _ = t.Dictionary.Apply(subject)
+	subject = strings.TrimRight(subject, ".!? ")
+
+	return fmt.Sprintf("%s %s. %s", prefix, subject, suffix), nil
+}
+
+// Newspeak dictionary endpoint
+func newspeakText(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var requestData struct {
+		Text string `json:"text"`
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, currentConfig().MaxBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("Request body exceeds %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if requestData.Text == "" {
+		http.Error(w, "Field 'text' is required", http.StatusBadRequest)
+		return
+	}
+
+	text, replacements := currentConfig().Newspeak.Apply(requestData.Text)
+	slog.DebugContext(r.Context(), "applied newspeak dictionary", "replacements", replacements)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"text":         text,
+		"replacements": replacements,
+	})
+}
@@ -0,0 +1,89 @@
+package ministry
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLocalTransformerIsDeterministic(t *testing.T) {
+	dictionary, err := loadNewspeakDictionary("")
+	if err != nil {
+		t.Fatalf("loadNewspeakDictionary: %v", err)
+	}
+	transformer := LocalTransformer{Dictionary: dictionary}
+
+	first, err := transformer.Transform(context.Background(), "Government faces criticism", "")
+	if err != nil {
+		t.Fatalf("Transform: %v", err)
+	}
+	second, _ := transformer.Transform(context.Background(), "Government faces criticism", "")
+	if first != second {
+		t.Errorf("Transform gave %q then %q for the same input", first, second)
+	}
+	if !strings.Contains(first, "The Party faces crimethink") {
+		t.Errorf("Transform = %q, want the dictionary applied to the title", first)
+	}
+
+	// Without a title the description is the subject
+	fromDescription, _ := transformer.Transform(context.Background(), "", "Inflation rises.")
+	if !strings.Contains(fromDescription, "Abundance rises.") {
+		t.Errorf("Transform = %q, want the description used", fromDescription)
+	}
+}
+
+func TestNewspeakDictionaryApply(t *testing.T) {
+	dictionary, err := newNewspeakDictionary(map[string]string{"war": "peace", "war crimes": "liberation", "freedom": "slavery"})
+	if err != nil {
+		t.Fatalf("newNewspeakDictionary: %v", err)
+	}
+
+	tests := []struct {
+		in, want string
+		count    int
+	}{
+		{"War is over", "Peace is over", 1},
+		{"FREEDOM now", "SLAVERY now", 1},
+		{"war crimes and war", "liberation and peace", 2},
+		{"warfare continues", "warfare continues", 0},
+	}
+	for _, tt := range tests {
+		got, count := dictionary.Apply(tt.in)
+		if got != tt.want || count != tt.count {
+			t.Errorf("Apply(%q) = %q, %d; want %q, %d", tt.in, got, count, tt.want, tt.count)
+		}
+	}
+}
+
+func TestNewNewspeakDictionaryRejectsEmpty(t *testing.T) {
+	if _, err := newNewspeakDictionary(nil); err == nil {
+		t.Error("empty dictionary accepted")
+	}
+	if _, err := newNewspeakDictionary(map[string]string{" ": "blank"}); err == nil {
+		t.Error("dictionary with an empty term accepted")
+	}
+}
+
+func TestNewspeakEndpoint(t *testing.T) {
+	setupTest(t, nil)
+
+	rec := do(newRequest(http.MethodPost, "/api/newspeak", `{"text": "The government is bad"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var response struct {
+		Text         string `json:"text"`
+		Replacements int    `json:"replacements"`
+	}
+	decodeJSON(t, rec, &response)
+	if response.Text != "The the Party is ungood" || response.Replacements != 2 {
+		t.Errorf("response = %+v", response)
+	}
+
+	for _, body := range []string{`{}`, `not json`} {
+		if rec := do(newRequest(http.MethodPost, "/api/newspeak", body)); rec.Code != http.StatusBadRequest {
+			t.Errorf("body %s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
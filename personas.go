@@ -0,0 +1,74 @@
+package ministry
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// System prompts keyed by persona name, shared with the serverless handler
+//
+//go:embed api/personas.json
+var personasJSON []byte
+
+var personas = mustParsePersonas(personasJSON)
+
+// Persona used when a request doesn't ask for one
+const defaultPersona = "ministry"
+
+func mustParsePersonas(data []byte) map[string]string {
+	var prompts map[string]string
+	if err := json.Unmarshal(data, &prompts); err != nil {
+		panic(fmt.Sprintf("invalid embedded personas: %v", err))
+	}
+	if _, ok := prompts[defaultPersona]; !ok {
+		panic(fmt.Sprintf("embedded personas are missing %q", defaultPersona))
+	}
+	return prompts
+}
+
+// Persona prompts in other languages, keyed by primary language tag and then
+// persona name; English is personas itself
+//
+//go:embed api/localized_personas.json
+var localizedPersonasJSON []byte
+
+var localizedPersonas = mustParseLocalizedPersonas(localizedPersonasJSON)
+
+func mustParseLocalizedPersonas(data []byte) map[string]map[string]string {
+	var prompts map[string]map[string]string
+	if err := json.Unmarshal(data, &prompts); err != nil {
+		panic(fmt.Sprintf("invalid embedded localized personas: %v", err))
+	}
+	return prompts
+}
+
+// Prompt language for an Accept-Language header: the most preferred primary
+// tag with localized prompts, or "" for English and anything unsupported
+func promptLanguage(acceptLanguage string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		language, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if language == "en" {
+			language = ""
+		} else if _, ok := localizedPersonas[language]; !ok {
+			continue
+		}
+		if q > bestQ {
+			best, bestQ = language, q
+		}
+	}
+	return best
+}
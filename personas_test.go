@@ -0,0 +1,102 @@
+package ministry
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedPersonas(t *testing.T) {
+	for _, name := range []string{defaultPersona, "obrien", "newsreader", "telescreen"} {
+		if strings.TrimSpace(personas[name]) == "" {
+			t.Errorf("persona %q has no prompt", name)
+		}
+	}
+}
+
+func TestTransformPersona(t *testing.T) {
+	openAI := newFakeOpenAI("Lift repaired ahead of schedule")
+	setupTest(t, upstreams(openAI, nil))
+
+	for _, persona := range []string{"obrien", "telescreen"} {
+		rec := postTransform(`{"title": "Lift broken again", "persona": "` + persona + `"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("persona %s: status = %d, body %q", persona, rec.Code, rec.Body.String())
+		}
+		system := openAI.Requests()[openAI.Calls()-1].Messages[0].Content
+		if !strings.HasPrefix(system, personas[persona]) {
+			t.Errorf("persona %s: system prompt = %q", persona, system)
+		}
+	}
+
+	postTransform(`{"title": "Rations cut"}`)
+	if system := openAI.Requests()[openAI.Calls()-1].Messages[0].Content; !strings.HasPrefix(system, personas[defaultPersona]) {
+		t.Errorf("default system prompt = %q, want the ministry persona", system)
+	}
+}
+
+func TestTransformRejectsUnknownPersona(t *testing.T) {
+	openAI := newFakeOpenAI("unused")
+	setupTest(t, upstreams(openAI, nil))
+
+	rec := postTransform(`{"title": "Lift broken again", "persona": "goldstein"}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "obrien") {
+		t.Errorf("status = %d, body %q; want 400 listing the personas", rec.Code, rec.Body.String())
+	}
+	if openAI.Calls() != 0 {
+		t.Error("unknown persona reached the model")
+	}
+}
+
+func TestEmbeddedLocalizedPersonas(t *testing.T) {
+	for language, prompts := range localizedPersonas {
+		for name := range personas {
+			if strings.TrimSpace(prompts[name]) == "" {
+				t.Errorf("%s has no %q prompt", language, name)
+			}
+		}
+	}
+}
+
+func TestPromptLanguage(t *testing.T) {
+	tests := map[string]string{
+		"":                           "",
+		"fr":                         "fr",
+		"fr-CA":                      "fr",
+		"FR-fr":                      "fr",
+		"en-US,en;q=0.9":             "",
+		"ja,fr;q=0.5":                "fr",
+		"de;q=0.3,es;q=0.8,en;q=0.5": "es",
+		"en,fr;q=0.9":                "",
+		"xx,zz-ZZ":                   "",
+		"fr;q=bad,de;q=0.2":          "de",
+		"*":                          "",
+	}
+	for header, want := range tests {
+		if got := promptLanguage(header); got != want {
+			t.Errorf("promptLanguage(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestTransformAcceptLanguage(t *testing.T) {
+	openAI := newFakeOpenAI("Les rations sont abondantes")
+	setupTest(t, upstreams(openAI, nil))
+
+	rec := postTransform(`{"title": "Rations cut", "persona": "obrien"}`, "Accept-Language", "fr-FR,fr;q=0.9,en;q=0.8")
+	if vary := strings.Join(rec.Header().Values("Vary"), ", "); rec.Code != http.StatusOK || !strings.Contains(vary, "Accept-Language") {
+		t.Fatalf("status = %d, Vary %q", rec.Code, vary)
+	}
+	if system := openAI.Requests()[0].Messages[0].Content; !strings.HasPrefix(system, localizedPersonas["fr"]["obrien"]) {
+		t.Errorf("system prompt = %q, want the French O'Brien prompt", system)
+	}
+
+	// Unknown languages get the English prompt, and aren't cached with the French one
+	postTransform(`{"title": "Rations cut", "persona": "obrien"}`, "Accept-Language", "tlh")
+	if openAI.Calls() != 2 {
+		t.Fatalf("model got %d calls, want the French result kept apart", openAI.Calls())
+	}
+	if system := openAI.Requests()[1].Messages[0].Content; !strings.HasPrefix(system, personas["obrien"]) {
+		t.Errorf("system prompt = %q, want the English O'Brien prompt", system)
+	}
+}
@@ -0,0 +1,40 @@
+package ministry
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Whether a transform should be answered with the bare text: Accept asks
+// for text/plain and not JSON
+func wantsPlainText(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "text/plain") && !strings.Contains(accept, "application/json")
+}
+
+// Write just the transformed content as text/plain
+func writePlainTransform(w http.ResponseWriter, content string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(content))
+}
+
+// Transformed content of a stored JSON transform response, enveloped or not
+func storedTransformContent(body []byte) (string, bool) {
+	var stored struct {
+		TransformedContent *string `json:"transformedContent"`
+		Data               struct {
+			TransformedContent *string `json:"transformedContent"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &stored); err != nil {
+		return "", false
+	}
+	switch {
+	case stored.TransformedContent != nil:
+		return *stored.TransformedContent, true
+	case stored.Data.TransformedContent != nil:
+		return *stored.Data.TransformedContent, true
+	}
+	return "", false
+}
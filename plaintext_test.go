@@ -0,0 +1,76 @@
+package ministry
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWantsPlainText(t *testing.T) {
+	tests := map[string]bool{
+		"":                                  false,
+		"text/plain":                        true,
+		"text/plain; charset=utf-8":         true,
+		"text/html, text/plain;q=0.9":       true,
+		"application/json":                  false,
+		"application/json, text/plain, */*": false,
+		"*/*":                               false,
+	}
+	for accept, want := range tests {
+		req := newRequest(http.MethodPost, "/api/transform", "", "Accept", accept)
+		if got := wantsPlainText(req); got != want {
+			t.Errorf("wantsPlainText(Accept: %q) = %v, want %v", accept, got, want)
+		}
+	}
+}
+
+func TestStoredTransformContent(t *testing.T) {
+	tests := map[string]string{
+		`{"transformedContent": "Rations are plentiful", "source": "openai"}`:   "Rations are plentiful",
+		`{"data": {"transformedContent": "Rations are plentiful"}, "meta": {}}`: "Rations are plentiful",
+		`{"transformedContent": ""}`: "",
+	}
+	for body, want := range tests {
+		if got, ok := storedTransformContent([]byte(body)); !ok || got != want {
+			t.Errorf("storedTransformContent(%s) = %q, %v; want %q", body, got, ok, want)
+		}
+	}
+	for _, body := range []string{`{"error": "x"}`, `not json`} {
+		if _, ok := storedTransformContent([]byte(body)); ok {
+			t.Errorf("storedTransformContent(%s) found content", body)
+		}
+	}
+}
+
+func TestTransformPlainText(t *testing.T) {
+	setupTest(t, upstreams(newFakeOpenAI("Rations are plentiful"), nil))
+
+	rec := postTransform(`{"title": "Rations cut"}`, "Accept", "text/plain")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/plain; charset=utf-8" || rec.Body.String() != "Rations are plentiful" {
+		t.Errorf("status = %d, Content-Type %q, body %q; want the bare string", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+
+	// JSON stays the default
+	rec = postTransform(`{"title": "Rations cut"}`)
+	var response TransformResponse
+	decodeJSON(t, rec, &response)
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") || response.TransformedContent != "Rations are plentiful" {
+		t.Errorf("Content-Type %q, response %+v; want JSON", rec.Header().Get("Content-Type"), response)
+	}
+
+	// Errors are unaffected
+	if rec := postTransform(`{}`, "Accept", "text/plain"); rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d for an empty transform, want 400", rec.Code)
+	}
+}
+
+func TestTransformPlainTextReplay(t *testing.T) {
+	openAI := newFakeOpenAI("Rations are plentiful")
+	setupTest(t, upstreams(openAI, nil), "ENVELOPE", "true")
+
+	postTransform(`{"title": "Rations cut"}`, "Idempotency-Key", "plain-1")
+	rec := postTransform(`{"title": "Rations cut"}`, "Idempotency-Key", "plain-1", "Accept", "text/plain")
+	if rec.Header().Get("Idempotent-Replayed") != "true" || rec.Body.String() != "Rations are plentiful" || openAI.Calls() != 1 {
+		t.Errorf("replay = %q (replayed %q) after %d calls, want the stored content as text", rec.Body.String(), rec.Header().Get("Idempotent-Replayed"), openAI.Calls())
+	}
+}
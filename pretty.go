@@ -0,0 +1,104 @@
+package ministry
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Buffers a JSON response and writes it indented on Close. Other content
+// types, including event streams, pass straight through.
+type prettyJSONWriter struct {
+	http.ResponseWriter
+	status    int
+	decided   bool
+	buffering bool
+	buf       bytes.Buffer
+}
+
+// Choose between buffering and passthrough from the Content-Type set so far
+func (p *prettyJSONWriter) decide() {
+	if p.decided {
+		return
+	}
+	p.decided = true
+	if p.status == 0 {
+		p.status = http.StatusOK
+	}
+
+	p.buffering = strings.HasPrefix(p.Header().Get("Content-Type"), "application/json")
+	if p.buffering {
+		p.Header().Del("Content-Length")
+		return
+	}
+	p.ResponseWriter.WriteHeader(p.status)
+}
+
+func (p *prettyJSONWriter) WriteHeader(status int) {
+	if p.decided {
+		return
+	}
+	p.status = status
+	p.decide()
+}
+
+func (p *prettyJSONWriter) Write(b []byte) (int, error) {
+	p.decide()
+	if p.buffering {
+		return p.buf.Write(b)
+	}
+	return p.ResponseWriter.Write(b)
+}
+
+func (p *prettyJSONWriter) Flush() {
+	if p.buffering {
+		return
+	}
+	if flusher, ok := p.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (p *prettyJSONWriter) Unwrap() http.ResponseWriter {
+	return p.ResponseWriter
+}
+
+// Write the buffered JSON indented, or unchanged if it doesn't parse
+func (p *prettyJSONWriter) Close() error {
+	if !p.buffering {
+		return nil
+	}
+
+	body := p.buf.Bytes()
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, body, "", "  "); err == nil {
+		body = indented.Bytes()
+	}
+	p.ResponseWriter.WriteHeader(p.status)
+	_, err := p.ResponseWriter.Write(body)
+	return err
+}
+
+// Whether the request asked for indented JSON with ?pretty=true
+func wantsPrettyJSON(r *http.Request) bool {
+	pretty, _ := strconv.ParseBool(r.URL.Query().Get("pretty"))
+	return pretty
+}
+
+// ?pretty=true indents JSON responses for reading by hand; compact otherwise
+func prettyJSONMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !wantsPrettyJSON(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Not deferred: after a panic the buffered body is dropped, leaving
+		// the response to recoveryMiddleware
+		pw := &prettyJSONWriter{ResponseWriter: w}
+		next.ServeHTTP(pw, r)
+		pw.Close()
+	})
+}
@@ -0,0 +1,83 @@
+package ministry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPrettyJSON(t *testing.T) {
+	setupTest(t, upstreams(nil, newFakeNews(Article{Title: "Lift broken again"})))
+
+	compact := do(newRequest(http.MethodGet, "/api/news/headlines", ""))
+	if strings.Contains(compact.Body.String(), "\n  ") {
+		t.Errorf("default body is indented: %q", compact.Body.String())
+	}
+
+	for _, value := range []string{"true", "1"} {
+		rec := do(newRequest(http.MethodGet, "/api/news/headlines?pretty="+value, ""))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "\n  \"articles\": [") {
+			t.Errorf("pretty=%s: status = %d, body %q; want indented JSON", value, rec.Code, rec.Body.String())
+		}
+
+		// Same document either way
+		var want bytes.Buffer
+		json.Indent(&want, compact.Body.Bytes(), "", "  ")
+		if strings.TrimSpace(rec.Body.String()) != strings.TrimSpace(want.String()) {
+			t.Errorf("pretty=%s body = %q, want %q", value, rec.Body.String(), want.String())
+		}
+	}
+
+	if rec := do(newRequest(http.MethodGet, "/api/news/headlines?pretty=false", "")); strings.Contains(rec.Body.String(), "\n  ") {
+		t.Errorf("pretty=false body is indented: %q", rec.Body.String())
+	}
+}
+
+func TestPrettyJSONErrors(t *testing.T) {
+	setupTest(t, nil)
+
+	rec := do(newRequest(http.MethodGet, "/api/nowhere?pretty=true", ""))
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "\n  \"error\": \"Not found\"") {
+		t.Errorf("status = %d, body %q; want an indented 404", rec.Code, rec.Body.String())
+	}
+	rec = do(newRequest(http.MethodDelete, "/api/news/headlines?pretty=true", ""))
+	if rec.Code != http.StatusMethodNotAllowed || !strings.Contains(rec.Body.String(), "\n  \"error\": ") {
+		t.Errorf("status = %d, body %q; want an indented 405", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPrettyJSONLeavesOtherTypesAlone(t *testing.T) {
+	setupTest(t, upstreams(streamingOpenAI("Lift ", "repaired"), newFakeNews(Article{Title: "Lift broken again"})))
+
+	csv := do(newRequest(http.MethodGet, "/api/news/headlines.csv?pretty=true", ""))
+	plain := do(newRequest(http.MethodGet, "/api/news/headlines.csv", ""))
+	if csv.Body.String() != plain.Body.String() {
+		t.Errorf("CSV changed by pretty=true: %q", csv.Body.String())
+	}
+
+	stream := do(newRequest(http.MethodGet, "/api/transform/stream?title=Lift+broken+again&pretty=true", ""))
+	if !strings.HasPrefix(stream.Header().Get("Content-Type"), "text/event-stream") || len(sseEvents(stream.Body.String())) == 0 {
+		t.Errorf("stream = %q, want events passed through", stream.Body.String())
+	}
+}
+
+func TestPrettyJSONWithGzip(t *testing.T) {
+	setupTest(t, upstreams(nil, newFakeNews(numberedArticles(20)...)))
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines?pretty=true", "", "Accept-Encoding", "gzip"))
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q", rec.Header().Get("Content-Encoding"))
+	}
+	reader, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	body, _ := io.ReadAll(reader)
+	if !strings.Contains(string(body), "\n  \"articles\": [") {
+		t.Errorf("body = %q, want indented JSON inside the gzip", body)
+	}
+}
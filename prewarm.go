@@ -0,0 +1,92 @@
+package ministry
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Number of headlines transformed concurrently by the prewarm job
+const prewarmWorkers = 4
+
+// Pre-transform headlines now and on every tick until ctx is cancelled
+func runPrewarm(ctx context.Context, ticks <-chan time.Time) {
+	for {
+		transformed, err := prewarmHeadlines(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "prewarm failed", "error", err)
+		} else {
+			slog.InfoContext(ctx, "prewarm finished", "transformed", transformed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks:
+		}
+	}
+}
+
+// Transform the current top headlines, as a bare /api/news/headlines
+// request would fetch them, into the transform cache, skipping ones already
+// cached. Returns how many were transformed.
+func prewarmHeadlines(ctx context.Context) (int, error) {
+	query, err := headlinesQuery(url.Values{}, currentConfig())
+	if err != nil {
+		return 0, err
+	}
+	newsResponse, err := fetchTopHeadlines(ctx, currentNewsProvider(), query)
+	if err != nil {
+		return 0, err
+	}
+
+	// Same options the transform endpoint fills in for a bare request
+	options := TransformOptions{Intensity: defaultIntensity, Persona: defaultPersona}
+	ctx = withTransformOptions(ctx, options)
+
+	var transformed atomic.Int64
+	jobs := make(chan Article)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < prewarmWorkers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for article := range jobs {
+				key := transformCacheKey(currentConfig().LLMProvider, options, article.Title, article.Description)
+				if _, ok := getCachedTransform(ctx, key); ok {
+					continue
+				}
+
+				// Caches the result with its model, and joins a request already transforming it
+				content, err := transformOnce(ctx, key, article.Title, article.Description)
+				if err != nil {
+					slog.WarnContext(ctx, "prewarm transform failed", "url", article.URL, "error", err)
+					continue
+				}
+
+				notifyWebhook(ctx, newWebhookPayload(article.Title, article.Description, article.URL, content, currentConfig().LLMProvider))
+				transformed.Add(1)
+			}
+		}()
+	}
+
+feed:
+	for _, article := range newsResponse.Articles {
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- article:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return int(transformed.Load()), ctx.Err()
+}
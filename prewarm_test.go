@@ -0,0 +1,88 @@
+package ministry
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPrewarmHeadlinesPopulatesCache(t *testing.T) {
+	openAI := newFakeOpenAI("Lift repaired ahead of schedule")
+	news := newFakeNews(
+		Article{Title: "Lift broken again", URL: "https://example.com/lift"},
+		Article{Title: "Rations cut", Description: "Chocolate down to 20g", URL: "https://example.com/rations"},
+	)
+	setupTest(t, upstreams(openAI, news))
+
+	transformed, err := prewarmHeadlines(context.Background())
+	if err != nil || transformed != 2 {
+		t.Fatalf("prewarmHeadlines = %d, %v; want 2 transformed", transformed, err)
+	}
+
+	// A bare transform of a prewarmed headline is a cache hit
+	rec := postTransform(`{"title": "Rations cut", "description": "Chocolate down to 20g"}`)
+	if rec.Code != http.StatusOK || openAI.Calls() != 2 {
+		t.Errorf("status = %d after %d model calls, want the prewarmed result", rec.Code, openAI.Calls())
+	}
+
+	// The prewarmed result keeps the model that produced it
+	var response TransformResponse
+	decodeJSON(t, rec, &response)
+	if response.Model != "gpt-3.5-turbo" {
+		t.Errorf("model = %q, want the prewarmed result's", response.Model)
+	}
+
+	// An RSS feed of the same headlines is served from the cache too
+	if rec := do(newRequest(http.MethodGet, "/api/news/headlines/rss", "")); rec.Code != http.StatusOK || openAI.Calls() != 2 {
+		t.Errorf("RSS status = %d after %d model calls, want the prewarmed results", rec.Code, openAI.Calls())
+	}
+
+	// Cached headlines are skipped next time
+	if transformed, _ := prewarmHeadlines(context.Background()); transformed != 0 || openAI.Calls() != 2 {
+		t.Errorf("second prewarm transformed %d with %d model calls, want none", transformed, openAI.Calls())
+	}
+}
+
+func TestPrewarmHeadlinesUsesDefaultCategory(t *testing.T) {
+	news := newFakeNews(Article{Title: "Lift broken again", URL: "https://example.com/lift"})
+	setupTest(t, upstreams(newFakeOpenAI("Lift repaired ahead of schedule"), news), "DEFAULT_CATEGORY", "science")
+
+	if _, err := prewarmHeadlines(context.Background()); err != nil {
+		t.Fatalf("prewarmHeadlines: %v", err)
+	}
+	if queries := news.Queries(); len(queries) != 1 || queries[0].Get("category") != "science" {
+		t.Errorf("queries = %v, want the DEFAULT_CATEGORY feed", queries)
+	}
+}
+
+func TestRunPrewarmOnTicks(t *testing.T) {
+	news := newFakeNews()
+	setupTest(t, upstreams(newFakeOpenAI("unused"), news), "NEWS_CACHE_TTL", "1ns")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ticks := make(chan time.Time)
+	done := make(chan struct{})
+	go func() {
+		runPrewarm(ctx, ticks)
+		close(done)
+	}()
+
+	// Once at start and once per tick
+	ticks <- time.Now()
+	ticks <- time.Now()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(news.Queries()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("runPrewarm didn't stop on cancellation")
+	}
+
+	if calls := len(news.Queries()); calls != 3 {
+		t.Errorf("fetched headlines %d times, want 3", calls)
+	}
+}
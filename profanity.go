@@ -0,0 +1,108 @@
+package ministry
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Default blocked terms for output filtering, shared with the serverless handler
+//
+//go:embed api/blocklist.json
+var defaultBlocklistJSON []byte
+
+// What to do when transformed text contains a blocked term
+const (
+	profanityModeOff    = "off"
+	profanityModeMask   = "mask"
+	profanityModeReject = "reject"
+)
+
+// Returned when PROFANITY_MODE=reject and the output contains a blocked term
+var ErrBlockedContent = errors.New("transformed content contains blocked terms")
+
+// Whole-word, case-insensitive matcher for blocked terms
+type ProfanityFilter struct {
+	mode    string
+	pattern *regexp.Regexp
+}
+
+// Parse PROFANITY_MODE (mask, reject or off)
+func parseProfanityMode(value string) (string, error) {
+	switch mode := strings.ToLower(value); mode {
+	case "", profanityModeOff:
+		return profanityModeOff, nil
+	case profanityModeMask, profanityModeReject:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("PROFANITY_MODE must be one of mask, reject, off, got %q", value)
+	}
+}
+
+// Build the filter from a JSON array of terms in path, or the embedded default when path is empty
+func loadProfanityFilter(mode, path string) (*ProfanityFilter, error) {
+	if mode == profanityModeOff {
+		return &ProfanityFilter{mode: mode}, nil
+	}
+
+	data := defaultBlocklistJSON
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read profanity blocklist: %v", err)
+		}
+	}
+
+	var terms []string
+	if err := json.Unmarshal(data, &terms); err != nil {
+		return nil, fmt.Errorf("failed to parse profanity blocklist: %v", err)
+	}
+
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if term = strings.ToLower(strings.TrimSpace(term)); term != "" {
+			quoted = append(quoted, regexp.QuoteMeta(term))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil, fmt.Errorf("profanity blocklist is empty")
+	}
+
+	// Longer terms first so "motherfucker" masks whole rather than in part
+	sort.Slice(quoted, func(i, j int) bool {
+		if len(quoted[i]) != len(quoted[j]) {
+			return len(quoted[i]) > len(quoted[j])
+		}
+		return quoted[i] < quoted[j]
+	})
+
+	return &ProfanityFilter{
+		mode:    mode,
+		pattern: regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`),
+	}, nil
+}
+
+// Whether Apply can change or reject text
+func (f *ProfanityFilter) Active() bool {
+	return f != nil && f.mode != profanityModeOff
+}
+
+// Screen text according to the filter's mode: masked terms are replaced with
+// asterisks, and reject mode returns ErrBlockedContent on any match
+func (f *ProfanityFilter) Apply(text string) (string, error) {
+	if f == nil || f.mode == profanityModeOff || !f.pattern.MatchString(text) {
+		return text, nil
+	}
+	if f.mode == profanityModeReject {
+		return "", ErrBlockedContent
+	}
+	return f.pattern.ReplaceAllStringFunc(text, func(match string) string {
+		return strings.Repeat("*", utf8.RuneCountInString(match))
+	}), nil
+}
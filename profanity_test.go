@@ -0,0 +1,123 @@
+package ministry
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseProfanityMode(t *testing.T) {
+	tests := map[string]string{
+		"":       profanityModeOff,
+		"off":    profanityModeOff,
+		"MASK":   profanityModeMask,
+		"reject": profanityModeReject,
+	}
+	for value, want := range tests {
+		if got, err := parseProfanityMode(value); err != nil || got != want {
+			t.Errorf("parseProfanityMode(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	if _, err := parseProfanityMode("censor"); err == nil {
+		t.Error("parseProfanityMode(censor) succeeded, want an error")
+	}
+}
+
+func TestProfanityFilterApply(t *testing.T) {
+	mask, err := loadProfanityFilter(profanityModeMask, "")
+	if err != nil {
+		t.Fatalf("loadProfanityFilter: %v", err)
+	}
+
+	tests := map[string]string{
+		"Goldstein is a bastard":         "Goldstein is a *******",
+		"Goldstein is a BASTARD":         "Goldstein is a *******",
+		"What a motherfucker":            "What a ************",
+		"Scunthorpe and Arsenal rejoice": "Scunthorpe and Arsenal rejoice",
+		"Chocolate ration raised to 25g": "Chocolate ration raised to 25g",
+	}
+	for text, want := range tests {
+		if got, err := mask.Apply(text); err != nil || got != want {
+			t.Errorf("mask.Apply(%q) = %q, %v; want %q", text, got, err, want)
+		}
+	}
+
+	reject, _ := loadProfanityFilter(profanityModeReject, "")
+	if _, err := reject.Apply("Goldstein is a bastard"); !errors.Is(err, ErrBlockedContent) {
+		t.Errorf("reject.Apply error = %v, want ErrBlockedContent", err)
+	}
+	if got, err := reject.Apply("Goldstein is a traitor"); err != nil || got != "Goldstein is a traitor" {
+		t.Errorf("reject.Apply on clean text = %q, %v", got, err)
+	}
+
+	off, _ := loadProfanityFilter(profanityModeOff, "")
+	var unset *ProfanityFilter
+	for _, filter := range []*ProfanityFilter{off, unset} {
+		if got, _ := filter.Apply("Goldstein is a bastard"); got != "Goldstein is a bastard" {
+			t.Errorf("Apply with the filter off = %q", got)
+		}
+	}
+}
+
+func TestLoadProfanityFilterFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.json")
+	os.WriteFile(path, []byte(`["thoughtcrime", " "]`), 0o644)
+
+	filter, err := loadProfanityFilter(profanityModeMask, path)
+	if err != nil {
+		t.Fatalf("loadProfanityFilter: %v", err)
+	}
+	if got, _ := filter.Apply("No thoughtcrime, no bastard"); got != "No ************, no bastard" {
+		t.Errorf("Apply = %q, want only the file's terms masked", got)
+	}
+
+	for name, contents := range map[string]string{"not JSON": `thoughtcrime`, "empty": `[" "]`} {
+		os.WriteFile(path, []byte(contents), 0o644)
+		if _, err := loadProfanityFilter(profanityModeMask, path); err == nil {
+			t.Errorf("%s blocklist loaded, want an error", name)
+		}
+	}
+	if _, err := loadProfanityFilter(profanityModeMask, filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing blocklist loaded, want an error")
+	}
+}
+
+func TestTransformProfanityModes(t *testing.T) {
+	t.Run("mask", func(t *testing.T) {
+		setupTest(t, upstreams(newFakeOpenAI("Goldstein is a bastard"), nil), "PROFANITY_MODE", "mask")
+
+		var response TransformResponse
+		decodeJSON(t, postTransform(`{"title": "Lift broken again"}`), &response)
+		if response.TransformedContent != "Goldstein is a *******" {
+			t.Errorf("transformedContent = %q", response.TransformedContent)
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		setupTest(t, upstreams(newFakeOpenAI("Goldstein is a bastard"), nil), "PROFANITY_MODE", "reject")
+
+		rec := postTransform(`{"title": "Lift broken again"}`)
+		if rec.Code != http.StatusUnprocessableEntity || strings.Contains(rec.Body.String(), "bastard") {
+			t.Errorf("status = %d, body %q; want 422 without the term", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestLoadConfigRejectsBadProfanitySettings(t *testing.T) {
+	t.Setenv("NEWS_API_KEY", "test-news-key")
+	t.Setenv("OPENAI_API_KEY", "test-openai-key")
+
+	t.Setenv("PROFANITY_MODE", "censor")
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "PROFANITY_MODE") {
+		t.Errorf("PROFANITY_MODE=censor: error = %v", err)
+	}
+
+	t.Setenv("PROFANITY_MODE", "mask")
+	t.Setenv("PROFANITY_BLOCKLIST_FILE", filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "blocklist") {
+		t.Errorf("missing PROFANITY_BLOCKLIST_FILE: error = %v", err)
+	}
+}
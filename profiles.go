@@ -0,0 +1,126 @@
+package ministry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Limits applied to a single route
+type RouteProfile struct {
+	MaxBodyBytes int64
+	Timeout      time.Duration
+}
+
+// Profile key used for routes without their own entry
+const defaultRouteProfileKey = "*"
+
+// Built-in profiles keyed by route path; transform bodies default to MAX_BODY_BYTES
+func defaultRouteProfiles(transformMaxBody int64) map[string]RouteProfile {
+	return map[string]RouteProfile{
+		defaultRouteProfileKey:              {MaxBodyBytes: 1 << 20, Timeout: 30 * time.Second},
+		"/api/transform":                    {MaxBodyBytes: transformMaxBody, Timeout: 30 * time.Second},
+		"/api/transform/stream":             {MaxBodyBytes: 0, Timeout: 60 * time.Second},
+		"/api/transform/text":               {MaxBodyBytes: transformMaxBody, Timeout: 30 * time.Second},
+		"/api/article/transform":            {MaxBodyBytes: 256 << 10, Timeout: 45 * time.Second},
+		"/api/newspeak":                     {MaxBodyBytes: transformMaxBody, Timeout: 5 * time.Second},
+		"/api/slogans":                      {MaxBodyBytes: 0, Timeout: 5 * time.Second},
+		"/api/news/headlines":               {MaxBodyBytes: 0, Timeout: 10 * time.Second},
+		"/api/news/headlines.csv":           {MaxBodyBytes: 0, Timeout: 10 * time.Second},
+		"/api/news/headlines/rss":           {MaxBodyBytes: 0, Timeout: 60 * time.Second},
+		"/api/news/headlines/aggregate":     {MaxBodyBytes: 0, Timeout: 15 * time.Second},
+		"/api/news/combined":                {MaxBodyBytes: 0, Timeout: 15 * time.Second},
+		"/api/news/headlines/topics":        {MaxBodyBytes: 0, Timeout: 60 * time.Second},
+		"/api/news/headlines/transform-all": {MaxBodyBytes: 0, Timeout: 60 * time.Second},
+		"/api/news/search":                  {MaxBodyBytes: 16 << 10, Timeout: 10 * time.Second},
+		"/api/news/sources":                 {MaxBodyBytes: 0, Timeout: 10 * time.Second},
+		"/api/cache/purge":                  {MaxBodyBytes: 1 << 10, Timeout: 30 * time.Second},
+		"/api/cache/stats":                  {MaxBodyBytes: 0, Timeout: 5 * time.Second},
+		"/api/admin/reload":                 {MaxBodyBytes: 0, Timeout: 10 * time.Second},
+		"/api/health":                       {MaxBodyBytes: 0, Timeout: 5 * time.Second},
+		"/api/status":                       {MaxBodyBytes: 0, Timeout: 5 * time.Second},
+	}
+}
+
+// Parse ROUTE_PROFILES JSON on top of the built-in profiles, e.g.
+// {"/api/transform": {"maxBodyBytes": 131072, "timeout": "45s"}}
+func parseRouteProfiles(raw string, transformMaxBody int64) (map[string]RouteProfile, error) {
+	profiles := defaultRouteProfiles(transformMaxBody)
+	if raw == "" {
+		return profiles, nil
+	}
+
+	var overrides map[string]struct {
+		MaxBodyBytes *int64 `json:"maxBodyBytes"`
+		Timeout      string `json:"timeout"`
+	}
+	if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+		return nil, fmt.Errorf("ROUTE_PROFILES must be a JSON object: %v", err)
+	}
+
+	for route, override := range overrides {
+		profile, ok := profiles[route]
+		if !ok {
+			profile = profiles[defaultRouteProfileKey]
+		}
+
+		if override.MaxBodyBytes != nil {
+			if *override.MaxBodyBytes < 0 {
+				return nil, fmt.Errorf("ROUTE_PROFILES %s: maxBodyBytes must not be negative", route)
+			}
+			profile.MaxBodyBytes = *override.MaxBodyBytes
+		}
+
+		if override.Timeout != "" {
+			timeout, err := time.ParseDuration(override.Timeout)
+			if err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("ROUTE_PROFILES %s: invalid timeout %q", route, override.Timeout)
+			}
+			profile.Timeout = timeout
+		}
+
+		profiles[route] = profile
+	}
+
+	return profiles, nil
+}
+
+// Look up the profile for a route, falling back to the default entry
+func routeProfileFor(route string) RouteProfile {
+	if profile, ok := currentConfig().RouteProfiles[route]; ok {
+		return profile
+	}
+	return currentConfig().RouteProfiles[defaultRouteProfileKey]
+}
+
+// Enforce the matched route's body size and timeout
+func routeProfileMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		profile := routeProfileFor(route)
+		r.Body = http.MaxBytesReader(w, r.Body, profile.MaxBodyBytes)
+
+		// Routes allowed to run longer than SERVER_WRITE_TIMEOUT get a later write deadline
+		if currentConfig().WriteTimeout > 0 && profile.Timeout > currentConfig().WriteTimeout {
+			http.NewResponseController(w).SetWriteDeadline(time.Now().Add(profile.Timeout + time.Second))
+		}
+
+		if profile.Timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), profile.Timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,71 @@
+package ministry
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRouteProfiles(t *testing.T) {
+	profiles, err := parseRouteProfiles(`{"/api/transform": {"maxBodyBytes": 1024, "timeout": "45s"}, "/api/custom": {"timeout": "2s"}}`, 4096)
+	if err != nil {
+		t.Fatalf("parseRouteProfiles: %v", err)
+	}
+
+	if got := profiles["/api/transform"]; got != (RouteProfile{MaxBodyBytes: 1024, Timeout: 45 * time.Second}) {
+		t.Errorf("/api/transform profile = %+v", got)
+	}
+	// Unknown routes start from the default entry
+	if got := profiles["/api/custom"]; got != (RouteProfile{MaxBodyBytes: 1 << 20, Timeout: 2 * time.Second}) {
+		t.Errorf("/api/custom profile = %+v", got)
+	}
+	// Untouched routes keep their built-in limits, transform bodies defaulting to MAX_BODY_BYTES
+	if got := profiles["/api/transform/text"]; got.MaxBodyBytes != 4096 {
+		t.Errorf("/api/transform/text maxBodyBytes = %d, want 4096", got.MaxBodyBytes)
+	}
+}
+
+func TestParseRouteProfilesRejectsBadValues(t *testing.T) {
+	for _, raw := range []string{
+		`not json`,
+		`{"/api/transform": {"maxBodyBytes": -1}}`,
+		`{"/api/transform": {"timeout": "soon"}}`,
+		`{"/api/transform": {"timeout": "0s"}}`,
+	} {
+		if _, err := parseRouteProfiles(raw, 4096); err == nil {
+			t.Errorf("parseRouteProfiles(%s) succeeded, want an error", raw)
+		}
+	}
+}
+
+func TestRouteProfileLimitsBodySize(t *testing.T) {
+	setupTest(t, nil, "ROUTE_PROFILES", `{"/api/newspeak": {"maxBodyBytes": 16}}`)
+
+	rec := do(newRequest(http.MethodPost, "/api/newspeak", `{"text": "the war is going well"}`))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "16 bytes") {
+		t.Errorf("body = %q, want the route's limit named", rec.Body.String())
+	}
+
+	rec = do(newRequest(http.MethodPost, "/api/newspeak", `{"text": "war"}`))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d for a body within the limit, want 200", rec.Code)
+	}
+}
+
+func TestRouteProfileTimesOutSlowTransforms(t *testing.T) {
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		time.Sleep(500 * time.Millisecond)
+		writeCompletion(w, req.Model, "too late", "stop")
+	}
+	setupTest(t, upstreams(openAI, nil), "ROUTE_PROFILES", `{"/api/transform": {"timeout": "50ms"}}`)
+
+	rec := postTransform(`{"title": "Chocolate ration raised"}`)
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want 504", rec.Code)
+	}
+}
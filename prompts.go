@@ -0,0 +1,141 @@
+package ministry
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Persona prompts and intensity levels used to build system prompts
+type PromptTemplates struct {
+	Personas    map[string]string           `json:"personas"`
+	Intensities map[string]intensityProfile `json:"intensities"`
+}
+
+// How often PROMPTS_FILE is checked for changes
+const promptsReloadInterval = 5 * time.Second
+
+// Built-in templates, used until PROMPTS_FILE loads successfully
+var defaultPrompts = defaultPromptTemplates()
+
+// Templates in use; replaced as a whole on reload
+var promptTemplates atomic.Pointer[PromptTemplates]
+
+func currentPrompts() *PromptTemplates {
+	if templates := promptTemplates.Load(); templates != nil {
+		return templates
+	}
+	return defaultPrompts
+}
+
+// Copy of the embedded personas and built-in intensity levels
+func defaultPromptTemplates() *PromptTemplates {
+	templates := &PromptTemplates{
+		Personas:    make(map[string]string, len(personas)),
+		Intensities: make(map[string]intensityProfile, len(intensityProfiles)),
+	}
+	for name, prompt := range personas {
+		templates.Personas[name] = prompt
+	}
+	for name, profile := range intensityProfiles {
+		templates.Intensities[name] = profile
+	}
+	return templates
+}
+
+// Parse a prompts file, layering its entries over the built-in templates, e.g.
+// {"personas": {"ministry": "..."}, "intensities": {"low": {"instruction": "...", "temperature": 0.4}}}
+func parsePromptTemplates(data []byte) (*PromptTemplates, error) {
+	var file PromptTemplates
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("must be a JSON object: %v", err)
+	}
+
+	templates := defaultPromptTemplates()
+	for name, prompt := range file.Personas {
+		if strings.TrimSpace(prompt) == "" {
+			return nil, fmt.Errorf("persona %q has an empty prompt", name)
+		}
+		templates.Personas[strings.ToLower(name)] = prompt
+	}
+	for name, profile := range file.Intensities {
+		if profile.Temperature < 0 || profile.Temperature > 2 {
+			return nil, fmt.Errorf("intensity %q: temperature must be between 0 and 2", name)
+		}
+		templates.Intensities[strings.ToLower(name)] = profile
+	}
+
+	return templates, nil
+}
+
+func loadPromptTemplates(path string) (*PromptTemplates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PROMPTS_FILE: %v", err)
+	}
+
+	templates, err := parsePromptTemplates(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PROMPTS_FILE %s: %v", path, err)
+	}
+	return templates, nil
+}
+
+// Swap in the templates from path, keeping the current ones if it can't be loaded
+func reloadPromptTemplates(path string) {
+	templates, err := loadPromptTemplates(path)
+	if err != nil {
+		slog.Warn("keeping current prompt templates", "error", err)
+		return
+	}
+
+	promptTemplates.Store(templates)
+	slog.Info("loaded prompt templates", "path", path, "personas", len(templates.Personas), "intensities", len(templates.Intensities))
+}
+
+// Load PROMPTS_FILE now, then poll it in the background and reload on change
+func watchPromptsFile(path string, interval time.Duration) {
+	last, _ := os.Stat(path)
+	reloadPromptTemplates(path)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+				continue
+			}
+			last = info
+			reloadPromptTemplates(path)
+		}
+	}()
+}
+
+// Sorted persona names, for error messages
+func (t *PromptTemplates) PersonaNames() []string {
+	return sortedKeys(t.Personas)
+}
+
+// Sorted intensity names, for error messages
+func (t *PromptTemplates) IntensityNames() []string {
+	return sortedKeys(t.Intensities)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,82 @@
+package ministry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParsePromptTemplates(t *testing.T) {
+	templates, err := parsePromptTemplates([]byte(`{"personas": {"Goldstein": "You are Emmanuel Goldstein."}, "intensities": {"low": {"instruction": "Whisper.", "temperature": 0.2}}}`))
+	if err != nil {
+		t.Fatalf("parsePromptTemplates: %v", err)
+	}
+	if templates.Personas["goldstein"] != "You are Emmanuel Goldstein." {
+		t.Errorf("goldstein = %q", templates.Personas["goldstein"])
+	}
+	if templates.Intensities["low"] != (intensityProfile{Instruction: "Whisper.", Temperature: 0.2}) {
+		t.Errorf("low = %+v", templates.Intensities["low"])
+	}
+	// Entries the file doesn't mention keep their built-in values
+	if templates.Personas[defaultPersona] != personas[defaultPersona] || templates.Intensities["medium"] != intensityProfiles["medium"] {
+		t.Error("built-in templates weren't kept")
+	}
+
+	for _, raw := range []string{`[]`, `{"personas": {"blank": " "}}`, `{"intensities": {"hot": {"temperature": 3}}}`} {
+		if _, err := parsePromptTemplates([]byte(raw)); err == nil {
+			t.Errorf("parsePromptTemplates(%s) succeeded, want an error", raw)
+		}
+	}
+}
+
+// Write a prompts file and reset the active templates when the test ends
+func writePromptsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "prompts.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { promptTemplates.Store(nil) })
+	return path
+}
+
+func TestReloadPromptTemplatesKeepsGoodConfig(t *testing.T) {
+	path := writePromptsFile(t, `{"personas": {"goldstein": "You are Emmanuel Goldstein."}}`)
+
+	reloadPromptTemplates(path)
+	if currentPrompts().Personas["goldstein"] == "" {
+		t.Fatal("prompts file wasn't loaded")
+	}
+
+	os.WriteFile(path, []byte(`{"personas": `), 0o644)
+	reloadPromptTemplates(path)
+	if currentPrompts().Personas["goldstein"] == "" {
+		t.Error("malformed file replaced the last good templates")
+	}
+}
+
+func TestMissingPromptsFileUsesDefaults(t *testing.T) {
+	t.Cleanup(func() { promptTemplates.Store(nil) })
+	reloadPromptTemplates(filepath.Join(t.TempDir(), "missing.json"))
+	if currentPrompts() != defaultPrompts {
+		t.Error("missing prompts file didn't leave the built-in templates in use")
+	}
+}
+
+func TestWatchPromptsFileReloadsOnChange(t *testing.T) {
+	path := writePromptsFile(t, `{"personas": {"goldstein": "first"}}`)
+	watchPromptsFile(path, 10*time.Millisecond)
+	if got := currentPrompts().Personas["goldstein"]; got != "first" {
+		t.Fatalf("goldstein = %q after the initial load", got)
+	}
+
+	os.WriteFile(path, []byte(`{"personas": {"goldstein": "second, longer"}}`), 0o644)
+	deadline := time.Now().Add(2 * time.Second)
+	for currentPrompts().Personas["goldstein"] != "second, longer" {
+		if time.Now().After(deadline) {
+			t.Fatal("edited prompts file wasn't reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,36 @@
+package ministry
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// Log a panic with its stack and answer with the JSON 500, unless the
+// response has already started. Must be deferred directly so recover sees the
+// panic; http.ErrAbortHandler is re-raised so net/http can abort the response.
+func recoverPanic(w *statusRecorder, r *http.Request) {
+	p := recover()
+	if p == nil {
+		return
+	}
+	if p == http.ErrAbortHandler {
+		panic(p)
+	}
+
+	slog.ErrorContext(r.Context(), "panic serving request", "panic", fmt.Sprint(p), "stack", string(debug.Stack()))
+	if w.status != 0 {
+		return
+	}
+	writeJSONError(w, http.StatusInternalServerError, "Internal server error")
+}
+
+// Turn panics in the handlers below into a logged JSON 500
+func recoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w}
+		defer recoverPanic(rec, r)
+		next.ServeHTTP(rec, r)
+	})
+}
@@ -0,0 +1,104 @@
+package ministry
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// News provider whose headlines blow up
+type panickingProvider struct {
+	fakeProvider
+}
+
+func (p *panickingProvider) TopHeadlines(ctx context.Context, query HeadlinesQuery) (*NewsResponse, error) {
+	var response *NewsResponse
+	_ = response.Articles[0]
+	return nil, nil
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	setupTest(t, nil)
+	useNewsProvider(t, &panickingProvider{})
+	logs := captureLogs(t, slog.LevelError)
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines", "", "X-Request-ID", "panic-test-1"))
+	if rec.Code != http.StatusInternalServerError || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("status = %d, Content-Type %q; want a JSON 500", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var response ErrorResponse
+	decodeJSON(t, rec, &response)
+	if response.Status != http.StatusInternalServerError || response.Error != "Internal server error" {
+		t.Errorf("response = %+v", response)
+	}
+	// Security headers still apply to the 500
+	if rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Error("no nosniff header on the 500")
+	}
+
+	records := logRecords(t, logs, "panic serving request")
+	if len(records) != 1 {
+		t.Fatalf("logged %d panics, want 1", len(records))
+	}
+	stack, _ := records[0]["stack"].(string)
+	if records[0]["requestId"] != "panic-test-1" || !strings.Contains(records[0]["panic"].(string), "nil pointer") || !strings.Contains(stack, "panickingProvider") {
+		t.Errorf("record = %v, want the request ID, panic and stack", records[0])
+	}
+
+	// The server carries on
+	useNewsProvider(t, &fakeProvider{Articles: []Article{{Title: "Lift broken again"}}})
+	if rec := do(newRequest(http.MethodGet, "/api/news/headlines", "")); rec.Code != http.StatusOK {
+		t.Errorf("next request status = %d", rec.Code)
+	}
+}
+
+func TestRecoveryCoversOuterMiddleware(t *testing.T) {
+	setupTest(t, nil, "GZIP_MIN_BYTES", "1")
+	useNewsProvider(t, &panickingProvider{})
+	logs := captureLogs(t, slog.LevelError)
+
+	// Gzip and pretty-printing sit inside recovery, so their buffered output
+	// doesn't turn the panic into an empty 200
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines?pretty=true", "", "Accept-Encoding", "gzip"))
+	var response ErrorResponse
+	decodeJSON(t, rec, &response)
+	if rec.Code != http.StatusInternalServerError || response.Error != "Internal server error" || rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("status = %d, Content-Encoding %q, response %+v; want the plain JSON 500", rec.Code, rec.Header().Get("Content-Encoding"), response)
+	}
+
+	// The access log still records the request, as a 500
+	if records := logRecords(t, logs, "request completed"); len(records) != 1 || records[0]["status"] != float64(http.StatusInternalServerError) {
+		t.Errorf("access log = %v, want one 500", records)
+	}
+}
+
+func TestRecoveryAfterResponseStarted(t *testing.T) {
+	setupTest(t, nil)
+	captureLogs(t, slog.LevelError)
+
+	handler := recoveryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("partial"))
+		panic("mid-response")
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusAccepted || rec.Body.String() != "partial" {
+		t.Errorf("status = %d, body %q; want the started response left alone", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRecoveryRepanicsAbortHandler(t *testing.T) {
+	handler := recoveryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler passed on", p)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
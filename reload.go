@@ -0,0 +1,175 @@
+package ministry
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+)
+
+// Config fields whose values are masked in reload responses
+var secretConfigFields = map[string]bool{
+	"NewsAPIKey":      true,
+	"OpenAIAPIKey":    true,
+	"AnthropicAPIKey": true,
+	"GNewsAPIKey":     true,
+	"ServiceAPIKeys":  true,
+	"CursorSecret":    true,
+	"SigningSecret":   true,
+	"RedisURL":        true,
+	"WebhookURL":      true,
+}
+
+// Config fields only read at startup; a reload reports them but they keep
+// their old behaviour until the next restart
+var startupConfigFields = map[string]bool{
+	"Port":                          true,
+	"ListenAddr":                    true,
+	"ReadTimeout":                   true,
+	"WriteTimeout":                  true,
+	"IdleTimeout":                   true,
+	"CacheBackend":                  true,
+	"RedisURL":                      true,
+	"ArchiveDBPath":                 true,
+	"PromptsFile":                   true,
+	"WebhookURL":                    true,
+	"PrewarmEnabled":                true,
+	"PrewarmInterval":               true,
+	"BreakerThreshold":              true,
+	"BreakerCooldown":               true,
+	"OpenAIMaxConcurrency":          true,
+	"OpenAIConcurrencyWait":         true,
+	"TLSCertFile":                   true,
+	"TLSKeyFile":                    true,
+	"TransformMaxInFlight":          true,
+	"TransformQueueDepth":           true,
+	"UpstreamDialTimeout":           true,
+	"UpstreamTLSHandshakeTimeout":   true,
+	"UpstreamResponseHeaderTimeout": true,
+}
+
+// One field that differs between the running and the reloaded config. Old
+// and New are omitted for values that don't print usefully, such as the
+// parsed dictionaries.
+type ConfigChange struct {
+	Field           string `json:"field"`
+	Old             string `json:"old,omitempty"`
+	New             string `json:"new,omitempty"`
+	RestartRequired bool   `json:"restartRequired,omitempty"`
+}
+
+type ReloadResponse struct {
+	Changed []ConfigChange `json:"changed"`
+}
+
+// Mask a secret, keeping the last four characters of long values so a
+// rotated key can still be told apart
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) < 12 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
+
+// Printable form of a config value, or false for one that has none
+func configValue(v reflect.Value, secret bool) (string, bool) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return "", true
+		}
+		if v.Elem().Kind() == reflect.Struct {
+			return "", false
+		}
+		return configValue(v.Elem(), secret)
+	case reflect.Slice:
+		if secret && v.Type().Elem().Kind() == reflect.String {
+			masked := make([]string, v.Len())
+			for i := range masked {
+				masked[i] = maskSecret(v.Index(i).String())
+			}
+			return fmt.Sprint(masked), true
+		}
+		if secret {
+			return "****", true
+		}
+	case reflect.String:
+		if secret {
+			return maskSecret(v.String()), true
+		}
+	}
+	return fmt.Sprint(v.Interface()), true
+}
+
+// Fields that differ between two configs, secrets masked
+func changedConfigFields(old, next *Config) []ConfigChange {
+	changes := []ConfigChange{}
+	oldValue, nextValue := reflect.ValueOf(old).Elem(), reflect.ValueOf(next).Elem()
+	for i := range oldValue.NumField() {
+		field := oldValue.Type().Field(i).Name
+		if reflect.DeepEqual(oldValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			continue
+		}
+
+		change := ConfigChange{Field: field, RestartRequired: startupConfigFields[field]}
+		oldText, ok := configValue(oldValue.Field(i), secretConfigFields[field])
+		newText, _ := configValue(nextValue.Field(i), secretConfigFields[field])
+		if ok {
+			change.Old, change.New = oldText, newText
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// Serializes reloads, which touch the environment
+var reloadMu sync.Mutex
+
+// Re-read the .env file and environment and swap in the new config if it
+// validates. The transformer and news provider are rebuilt so rotated keys
+// take effect; requires SERVICE_API_KEYS so it is never left open.
+func reloadConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if len(currentConfig().ServiceAPIKeys) == 0 {
+		http.Error(w, "Config reload requires SERVICE_API_KEYS to be configured", http.StatusForbidden)
+		return
+	}
+
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	restoreEnv, err := reloadEnvFile(envFilePath())
+	if err != nil {
+		slog.WarnContext(r.Context(), "config reload failed", "error", err)
+		http.Error(w, fmt.Sprintf("Configuration not reloaded: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+	next, err := loadConfig()
+	if err != nil {
+		restoreEnv()
+		slog.WarnContext(r.Context(), "config reload failed", "error", err)
+		http.Error(w, fmt.Sprintf("Configuration not reloaded: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	changes := changedConfigFields(currentConfig(), next)
+	activeConfig.Store(next)
+	setTransformer(buildTransformer(next))
+	setNewsProvider(newNewsProvider(next, newsCache))
+	slog.SetDefault(newLogger(os.Stdout, next.LogLevel))
+
+	fields := make([]string, len(changes))
+	for i, change := range changes {
+		fields[i] = change.Field
+	}
+	slog.InfoContext(r.Context(), "configuration reloaded", "changed", fields)
+
+	json.NewEncoder(w).Encode(ReloadResponse{Changed: changes})
+}
@@ -0,0 +1,163 @@
+package ministry
+
+import (
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const reloadKey = "reload-test-service-key"
+
+// Set up with a service key and an empty .env, keeping the logger the
+// reload replaces
+func setupReload(t *testing.T, env ...string) {
+	t.Helper()
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	previousKeys := maps.Clone(envFileKeys)
+	t.Cleanup(func() { envFileKeys = previousKeys })
+
+	// A fixed cursor secret, so rotating the news key changes only the key
+	env = append([]string{"SERVICE_API_KEYS", reloadKey, "CURSOR_SECRET", "reload-test-cursor-secret", "ENV_FILE", filepath.Join(t.TempDir(), ".env")}, env...)
+	setupTest(t, nil, env...)
+}
+
+func TestMaskSecret(t *testing.T) {
+	tests := map[string]string{"": "", "short": "****", "sk-live-abcdefgh1234": "****1234"}
+	for secret, want := range tests {
+		if got := maskSecret(secret); got != want {
+			t.Errorf("maskSecret(%q) = %q, want %q", secret, got, want)
+		}
+	}
+}
+
+func TestChangedConfigFields(t *testing.T) {
+	old := &Config{NewsAPIKey: "news-key-old-1111", MaxArticles: 20, Port: "8080", ServiceAPIKeys: []string{"service-key-aaaa"}}
+	next := &Config{NewsAPIKey: "news-key-new-2222", MaxArticles: 30, Port: "9090", ServiceAPIKeys: []string{"service-key-bbbb"}}
+
+	changes := map[string]ConfigChange{}
+	for _, change := range changedConfigFields(old, next) {
+		changes[change.Field] = change
+	}
+	if len(changes) != 4 {
+		t.Errorf("changes = %+v, want 4", changes)
+	}
+	if change := changes["NewsAPIKey"]; change.Old != "****1111" || change.New != "****2222" {
+		t.Errorf("NewsAPIKey change = %+v, want masked values", change)
+	}
+	if change := changes["ServiceAPIKeys"]; change.Old != "[****aaaa]" || change.New != "[****bbbb]" {
+		t.Errorf("ServiceAPIKeys change = %+v, want masked values", change)
+	}
+	if change := changes["MaxArticles"]; change.Old != "20" || change.New != "30" || change.RestartRequired {
+		t.Errorf("MaxArticles change = %+v", change)
+	}
+	if change := changes["Port"]; !change.RestartRequired {
+		t.Errorf("Port change = %+v, want restartRequired", change)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	setupReload(t)
+	t.Setenv("NEWS_API_KEY", "rotated-news-key-9876")
+	t.Setenv("MAX_ARTICLES", "42")
+
+	rec := do(newRequest(http.MethodPost, "/api/admin/reload", "", "X-API-Key", reloadKey))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var response ReloadResponse
+	decodeJSON(t, rec, &response)
+	fields := map[string]ConfigChange{}
+	for _, change := range response.Changed {
+		fields[change.Field] = change
+	}
+	if len(fields) != 2 || fields["NewsAPIKey"].New != "****9876" || fields["MaxArticles"].New != "42" {
+		t.Errorf("changed = %+v, want NewsAPIKey and MaxArticles", response.Changed)
+	}
+	if strings.Contains(rec.Body.String(), "rotated-news-key") {
+		t.Errorf("response leaks the new key: %q", rec.Body.String())
+	}
+	if cfg := currentConfig(); cfg.NewsAPIKey != "rotated-news-key-9876" || cfg.MaxArticles != 42 {
+		t.Errorf("running config = %q, %d; want the reloaded values", cfg.NewsAPIKey, cfg.MaxArticles)
+	}
+
+	// Nothing else to change
+	rec = do(newRequest(http.MethodPost, "/api/admin/reload", "", "X-API-Key", reloadKey))
+	decodeJSON(t, rec, &response)
+	if len(response.Changed) != 0 {
+		t.Errorf("second reload changed %+v, want nothing", response.Changed)
+	}
+}
+
+func TestReloadFromEnvFile(t *testing.T) {
+	setupReload(t)
+	t.Setenv("TRANSFORM_MAX_CHARS", "")
+	os.Unsetenv("TRANSFORM_MAX_CHARS")
+	os.WriteFile(os.Getenv("ENV_FILE"), []byte("TRANSFORM_MAX_CHARS=120\n"), 0o600)
+
+	rec := do(newRequest(http.MethodPost, "/api/admin/reload", "", "X-API-Key", reloadKey))
+	if rec.Code != http.StatusOK || currentConfig().TransformMaxChars != 120 {
+		t.Errorf("status = %d, TransformMaxChars %d; want the .env value", rec.Code, currentConfig().TransformMaxChars)
+	}
+}
+
+func TestReloadInvalidConfigKeepsRunning(t *testing.T) {
+	setupReload(t)
+	before := currentConfig()
+	t.Setenv("NEWS_API_KEY", "rotated-news-key-9876")
+	t.Setenv("MAX_ARTICLES", "-1")
+
+	rec := do(newRequest(http.MethodPost, "/api/admin/reload", "", "X-API-Key", reloadKey))
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "MAX_ARTICLES") {
+		t.Errorf("status = %d, body %q; want 422 naming the bad setting", rec.Code, rec.Body.String())
+	}
+	if currentConfig() != before {
+		t.Error("a failed reload replaced the running config")
+	}
+
+	// A broken .env is refused the same way
+	t.Setenv("MAX_ARTICLES", "20")
+	os.WriteFile(os.Getenv("ENV_FILE"), []byte("this is not a setting\n"), 0o600)
+	if rec := do(newRequest(http.MethodPost, "/api/admin/reload", "", "X-API-Key", reloadKey)); rec.Code != http.StatusUnprocessableEntity || currentConfig() != before {
+		t.Errorf("status = %d with a broken .env, want 422 and the old config", rec.Code)
+	}
+}
+
+func TestReloadHidesWebhookURL(t *testing.T) {
+	setupReload(t)
+	t.Setenv("WEBHOOK_URL", "https://hooks.example.com/services/secret-hook-token")
+
+	rec := do(newRequest(http.MethodPost, "/api/admin/reload", "", "X-API-Key", reloadKey))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "secret-hook-token") {
+		t.Errorf("status = %d, body %q; want the webhook URL masked", rec.Code, rec.Body.String())
+	}
+
+	// A rejected URL isn't echoed back either
+	t.Setenv("WEBHOOK_URL", "ftp://hooks.example.com/services/other-hook-token")
+	rec = do(newRequest(http.MethodPost, "/api/admin/reload", "", "X-API-Key", reloadKey))
+	if rec.Code != http.StatusUnprocessableEntity || strings.Contains(rec.Body.String(), "other-hook-token") {
+		t.Errorf("status = %d, body %q; want 422 without the webhook URL", rec.Code, rec.Body.String())
+	}
+}
+
+func TestReloadRequiresAPIKey(t *testing.T) {
+	setupReload(t)
+	if rec := do(newRequest(http.MethodPost, "/api/admin/reload", "")); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without a key: status = %d, want 401", rec.Code)
+	}
+	if rec := do(newRequest(http.MethodPost, "/api/admin/reload", "", "X-API-Key", "wrong")); rec.Code != http.StatusUnauthorized {
+		t.Errorf("with a wrong key: status = %d, want 401", rec.Code)
+	}
+}
+
+func TestReloadDisabledWithoutServiceKeys(t *testing.T) {
+	setupTest(t, nil)
+	if rec := do(newRequest(http.MethodPost, "/api/admin/reload", "")); rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403 when SERVICE_API_KEYS is unset", rec.Code)
+	}
+}
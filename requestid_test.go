@@ -0,0 +1,88 @@
+package ministry
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var uuidV4 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewRequestIDIsUUIDv4(t *testing.T) {
+	seen := map[string]bool{}
+	for range 100 {
+		id := newRequestID()
+		if !uuidV4.MatchString(id) {
+			t.Fatalf("newRequestID() = %q, not a v4 UUID", id)
+		}
+		if seen[id] {
+			t.Fatalf("newRequestID() repeated %q", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	setupTest(t, nil)
+
+	rec := do(newRequest(http.MethodGet, "/api/health", "", "X-Request-ID", "client-id-123"))
+	if got := rec.Header().Get("X-Request-ID"); got != "client-id-123" {
+		t.Errorf("X-Request-ID = %q, want the client's ID echoed", got)
+	}
+
+	rec = do(newRequest(http.MethodGet, "/api/health", ""))
+	if got := rec.Header().Get("X-Request-ID"); !uuidV4.MatchString(got) {
+		t.Errorf("X-Request-ID = %q, want a generated UUID", got)
+	}
+
+	// Over-long or unprintable IDs are replaced rather than echoed
+	for _, id := range []string{strings.Repeat("a", 129), "has space"} {
+		rec = do(newRequest(http.MethodGet, "/api/health", "", "X-Request-ID", id))
+		if got := rec.Header().Get("X-Request-ID"); !uuidV4.MatchString(got) {
+			t.Errorf("X-Request-ID for %q = %q, want a generated UUID", id, got)
+		}
+	}
+}
+
+func TestRequestIDInLogs(t *testing.T) {
+	setupTest(t, nil)
+	logs := captureLogs(t, slog.LevelInfo)
+
+	do(newRequest(http.MethodGet, "/api/health", "", "X-Request-ID", "trace-me"))
+
+	records := logRecords(t, logs, "request completed")
+	if len(records) != 1 || records[0]["requestId"] != "trace-me" {
+		t.Errorf("request logs = %v, want requestId trace-me", records)
+	}
+}
+
+func TestUnmatchedRoutesGetRequestIDAndLog(t *testing.T) {
+	setupTest(t, nil)
+	logs := captureLogs(t, slog.LevelInfo)
+
+	tests := []struct {
+		method, path string
+		status       int
+	}{
+		{http.MethodGet, "/api/no-such-route", http.StatusNotFound},
+		{http.MethodDelete, "/api/health", http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		rec := do(newRequest(test.method, test.path, "", "X-Request-ID", "unmatched-"+test.method))
+		if rec.Code != test.status || rec.Header().Get("X-Request-ID") != "unmatched-"+test.method {
+			t.Errorf("%s %s: status = %d, X-Request-ID %q", test.method, test.path, rec.Code, rec.Header().Get("X-Request-ID"))
+		}
+	}
+
+	records := logRecords(t, logs, "request completed")
+	if len(records) != len(tests) {
+		t.Fatalf("got %d request logs, want %d: %q", len(records), len(tests), logs.String())
+	}
+	for i, record := range records {
+		if record["status"] != float64(tests[i].status) || record["requestId"] != "unmatched-"+tests[i].method {
+			t.Errorf("record = %v", record)
+		}
+	}
+}
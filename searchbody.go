@@ -0,0 +1,146 @@
+package ministry
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Languages NewsAPI can filter /everything by
+var searchLanguages = []string{"ar", "de", "en", "es", "fr", "he", "it", "nl", "no", "pt", "ru", "sv", "ud", "zh"}
+
+// Orderings NewsAPI supports for /everything
+var searchSortOrders = []string{"relevancy", "popularity", "publishedAt"}
+
+// JSON body for POST /api/news/search
+type SearchRequest struct {
+	Query    string   `json:"query"`
+	Language string   `json:"language"`
+	SortBy   string   `json:"sortBy"`
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Domains  []string `json:"domains"`
+	PageSize int      `json:"pageSize"`
+	Page     int      `json:"page"`
+}
+
+// Parse a from/to bound given as a date (2006-01-02) or an RFC 3339 timestamp
+func parseSearchDate(name, value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("Invalid %s %q; expected a date like 2024-05-01 or an RFC 3339 timestamp", name, value)
+}
+
+// Validate a search body and turn it into a provider query
+func searchRequestQuery(req SearchRequest, cfg *Config) (SearchQuery, error) {
+	if strings.TrimSpace(req.Query) == "" {
+		return SearchQuery{}, fmt.Errorf("Field 'query' is required")
+	}
+	q, err := normalizeSearchQuery(req.Query)
+	if err != nil {
+		return SearchQuery{}, err
+	}
+	query := SearchQuery{Q: q, SortBy: req.SortBy, From: req.From, To: req.To, Page: req.Page, PageSize: req.PageSize}
+
+	if query.Language = strings.ToLower(req.Language); query.Language != "" && !slices.Contains(searchLanguages, query.Language) {
+		return SearchQuery{}, fmt.Errorf("Invalid language %q; allowed values: %s", req.Language, strings.Join(searchLanguages, ", "))
+	}
+	if query.SortBy != "" && !slices.Contains(searchSortOrders, query.SortBy) {
+		return SearchQuery{}, fmt.Errorf("Invalid sortBy %q; allowed values: %s", query.SortBy, strings.Join(searchSortOrders, ", "))
+	}
+
+	var from, to time.Time
+	if query.From != "" {
+		if from, err = parseSearchDate("from", query.From); err != nil {
+			return SearchQuery{}, err
+		}
+	}
+	if query.To != "" {
+		if to, err = parseSearchDate("to", query.To); err != nil {
+			return SearchQuery{}, err
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return SearchQuery{}, fmt.Errorf("from must not be after to")
+	}
+
+	if query.Domains, err = parseDomains("domains", req.Domains); err != nil {
+		return SearchQuery{}, err
+	}
+
+	switch {
+	case query.PageSize == 0:
+		query.PageSize = cfg.DefaultPageSize
+	case query.PageSize < 1 || query.PageSize > maxPageSize:
+		return SearchQuery{}, pageSizeError()
+	default:
+		query.PageSize = min(query.PageSize, cfg.MaxArticles)
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	} else if query.Page < 1 {
+		return SearchQuery{}, fmt.Errorf("page must be at least 1")
+	}
+
+	return query, nil
+}
+
+// Search news with filters in a JSON body; same response as GET /api/news/search
+func searchNewsPost(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	format, err := responseFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fields, err := parseArticleFields(r.URL.Query().Get("fields"), format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := parseArticleSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var requestData SearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	query, err := searchRequestQuery(requestData, currentConfig())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	newsResponse, err := currentNewsProvider().Search(r.Context(), query)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error searching news", "error", err)
+		http.Error(w, fmt.Sprintf("Error searching news: %v", err), newsErrorStatus(err))
+		return
+	}
+	newsResponse.NextCursor = nextSearchCursor(searchValues(query), newsResponse.TotalResults, currentConfig().CursorSecret)
+
+	// ?inferCategory=true tags each article with a keyword-based category
+	if infer, _ := strconv.ParseBool(r.URL.Query().Get("inferCategory")); infer {
+		currentConfig().Categories.Tag(newsResponse.Articles)
+	}
+
+	sortArticles(newsResponse.Articles, order)
+	writeNewsResponse(w, r, format, fields, newsResponse)
+}
@@ -0,0 +1,117 @@
+package ministry
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseSearchDate(t *testing.T) {
+	for _, value := range []string{"2024-05-01", "2024-05-01T12:30:00Z", "2024-05-01T12:30:00+02:00"} {
+		if _, err := parseSearchDate("from", value); err != nil {
+			t.Errorf("parseSearchDate(%q) = %v", value, err)
+		}
+	}
+	for _, value := range []string{"yesterday", "01/05/2024", "2024-13-01"} {
+		if _, err := parseSearchDate("from", value); err == nil || !strings.Contains(err.Error(), "from") {
+			t.Errorf("parseSearchDate(%q) error = %v, want it to name the field", value, err)
+		}
+	}
+}
+
+func TestSearchRequestQuery(t *testing.T) {
+	cfg := &Config{DefaultPageSize: 20, MaxArticles: 50}
+
+	query, err := searchRequestQuery(SearchRequest{Query: "  lift  AND rations ", Language: "EN"}, cfg)
+	if err != nil {
+		t.Fatalf("searchRequestQuery: %v", err)
+	}
+	if query.Q != "lift AND rations" || query.Language != "en" || query.PageSize != 20 || query.Page != 1 {
+		t.Errorf("defaults = %+v", query)
+	}
+
+	query, _ = searchRequestQuery(SearchRequest{Query: "lift", PageSize: 80}, cfg)
+	if query.PageSize != 50 {
+		t.Errorf("pageSize = %d, want it capped at MAX_ARTICLES", query.PageSize)
+	}
+
+	invalid := map[string]SearchRequest{
+		"'query' is required":         {Query: " "},
+		"unbalanced double quotes":    {Query: `"lift`},
+		"Invalid language":            {Query: "lift", Language: "klingon"},
+		"Invalid sortBy":              {Query: "lift", SortBy: "newest"},
+		"Invalid from":                {Query: "lift", From: "yesterday"},
+		"Invalid to":                  {Query: "lift", To: "tomorrow"},
+		"from must not be after to":   {Query: "lift", From: "2024-05-02", To: "2024-05-01"},
+		"Invalid domains entry":       {Query: "lift", Domains: []string{"not_a_host"}},
+		"pageSize must be an integer": {Query: "lift", PageSize: 101},
+		"page must be at least 1":     {Query: "lift", Page: -1},
+	}
+	for want, req := range invalid {
+		if _, err := searchRequestQuery(req, cfg); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("searchRequestQuery(%+v) error = %v, want it to mention %q", req, err, want)
+		}
+	}
+}
+
+func TestSearchPostFullBody(t *testing.T) {
+	news := newFakeNews(Article{Title: "Lift broken again"})
+	setupTest(t, upstreams(nil, news))
+
+	rec := do(newRequest(http.MethodPost, "/api/news/search", `{
+		"query": "lift OR rations",
+		"language": "en",
+		"sortBy": "publishedAt",
+		"from": "2024-05-01",
+		"to": "2024-05-31T23:59:59Z",
+		"domains": ["BBC.co.uk", "reuters.com"],
+		"pageSize": 5,
+		"page": 2
+	}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var response NewsResponse
+	decodeJSON(t, rec, &response)
+	if len(response.Articles) != 1 {
+		t.Errorf("response = %+v", response)
+	}
+
+	query := news.Queries()[0]
+	want := map[string]string{
+		"q": "lift OR rations", "language": "en", "sortBy": "publishedAt", "from": "2024-05-01",
+		"to": "2024-05-31T23:59:59Z", "domains": "bbc.co.uk,reuters.com", "pageSize": "5", "page": "2",
+	}
+	for name, value := range want {
+		if query.Get(name) != value {
+			t.Errorf("NewsAPI %s = %q, want %q", name, query.Get(name), value)
+		}
+	}
+
+	// The GET form still works
+	rec = do(newRequest(http.MethodGet, "/api/news/search?q=lift", ""))
+	if rec.Code != http.StatusOK || news.Queries()[1].Get("q") != "lift" {
+		t.Errorf("GET status = %d, queries %v", rec.Code, news.Queries())
+	}
+}
+
+func TestSearchPostValidation(t *testing.T) {
+	news := newFakeNews()
+	setupTest(t, upstreams(nil, news))
+
+	for _, body := range []string{
+		`not json`,
+		`{"language": "en"}`,
+		`{"query": "lift", "sortBy": "newest"}`,
+		`{"query": "lift", "from": "2024-05-02", "to": "2024-05-01"}`,
+		`{"query": "lift", "pageSize": 0.5}`,
+	} {
+		rec := do(newRequest(http.MethodPost, "/api/news/search", body))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("body %s: status = %d, want 400", body, rec.Code)
+		}
+	}
+	if queries := news.Queries(); len(queries) != 0 {
+		t.Errorf("invalid searches reached NewsAPI: %v", queries)
+	}
+}
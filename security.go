@@ -0,0 +1,54 @@
+package ministry
+
+import (
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Default Content-Security-Policy, loose enough for the bundled frontend's
+// inline scripts, Google Fonts and remote article images
+const defaultContentSecurityPolicy = "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline' https://fonts.googleapis.com; font-src 'self' https://fonts.gstatic.com; img-src * data:; connect-src *; frame-ancestors 'none'"
+
+// Security headers and the environment variables that override them; set a
+// variable to "off" to drop that header
+var securityHeaderEnv = []struct {
+	Header       string
+	Env          string
+	DefaultValue string
+}{
+	{"X-Content-Type-Options", "X_CONTENT_TYPE_OPTIONS", "nosniff"},
+	{"X-Frame-Options", "X_FRAME_OPTIONS", "DENY"},
+	{"Referrer-Policy", "REFERRER_POLICY", "strict-origin-when-cross-origin"},
+	{"Content-Security-Policy", "CONTENT_SECURITY_POLICY", defaultContentSecurityPolicy},
+}
+
+// Security headers to send, keyed by header name
+func loadSecurityHeaders() map[string]string {
+	headers := make(map[string]string)
+	for _, entry := range securityHeaderEnv {
+		value := os.Getenv(entry.Env)
+		if value == "" {
+			value = entry.DefaultValue
+		}
+		if strings.EqualFold(value, "off") {
+			continue
+		}
+		headers[entry.Header] = value
+	}
+	return headers
+}
+
+func setSecurityHeaders(w http.ResponseWriter, headers map[string]string) {
+	for name, value := range headers {
+		w.Header().Set(name, value)
+	}
+}
+
+// Add the configured security headers to every response
+func securityHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setSecurityHeaders(w, currentConfig().SecurityHeaders)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,34 @@
+package ministry
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	setupTest(t, nil)
+
+	for _, path := range []string{"/api/health", "/api/missing"} {
+		rec := do(newRequest(http.MethodGet, path, ""))
+		for _, entry := range securityHeaderEnv {
+			if got := rec.Header().Get(entry.Header); got != entry.DefaultValue {
+				t.Errorf("%s %s = %q, want %q", path, entry.Header, got, entry.DefaultValue)
+			}
+		}
+	}
+}
+
+func TestSecurityHeadersOverrides(t *testing.T) {
+	setupTest(t, nil, "X_FRAME_OPTIONS", "SAMEORIGIN", "CONTENT_SECURITY_POLICY", "off")
+
+	rec := do(newRequest(http.MethodGet, "/api/health", ""))
+	if got := rec.Header().Get("X-Frame-Options"); got != "SAMEORIGIN" {
+		t.Errorf("X-Frame-Options = %q, want the override", got)
+	}
+	if _, ok := rec.Header()["Content-Security-Policy"]; ok {
+		t.Error("Content-Security-Policy sent after being turned off")
+	}
+	if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("X-Content-Type-Options = %q, want the default", got)
+	}
+}
@@ -0,0 +1,82 @@
+package ministry
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Default word sentiment weights, shared with the serverless handler
+//
+//go:embed api/sentiment.json
+var defaultSentimentJSON []byte
+
+// Smoothing constant for normalizing a summed score into -1..1
+const sentimentAlpha = 15
+
+// Word weights used for lexicon-based sentiment scoring
+type SentimentLexicon struct {
+	weights map[string]float64
+}
+
+// Original and transformed sentiment scores, each in -1..1
+type SentimentAnalysis struct {
+	Original    float64 `json:"original"`
+	Transformed float64 `json:"transformed"`
+}
+
+// Load the lexicon from a JSON file of word weights, or the embedded default when path is empty
+func loadSentimentLexicon(path string) (*SentimentLexicon, error) {
+	data := defaultSentimentJSON
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read sentiment lexicon: %v", err)
+		}
+	}
+
+	var weights map[string]float64
+	if err := json.Unmarshal(data, &weights); err != nil {
+		return nil, fmt.Errorf("failed to parse sentiment lexicon: %v", err)
+	}
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("sentiment lexicon is empty")
+	}
+
+	normalized := make(map[string]float64, len(weights))
+	for word, weight := range weights {
+		normalized[strings.ToLower(strings.TrimSpace(word))] = weight
+	}
+	return &SentimentLexicon{weights: normalized}, nil
+}
+
+// Sum the weights of known words in text and squash the total into -1..1
+// (0 when no word in text is in the lexicon)
+func (l *SentimentLexicon) Score(text string) float64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	var sum float64
+	for _, word := range words {
+		sum += l.weights[word]
+	}
+	if sum == 0 {
+		return 0
+	}
+
+	score := sum / math.Sqrt(sum*sum+sentimentAlpha)
+	return math.Round(score*1000) / 1000
+}
+
+// Score the original and transformed text side by side
+func (l *SentimentLexicon) Compare(original, transformed string) SentimentAnalysis {
+	return SentimentAnalysis{
+		Original:    l.Score(original),
+		Transformed: l.Score(transformed),
+	}
+}
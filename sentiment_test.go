@@ -0,0 +1,61 @@
+package ministry
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSentimentScoreDirection(t *testing.T) {
+	lexicon, err := loadSentimentLexicon("")
+	if err != nil {
+		t.Fatalf("loadSentimentLexicon: %v", err)
+	}
+
+	if score := lexicon.Score("Abundance and achievement bring the best benefits"); score <= 0 || score > 1 {
+		t.Errorf("positive text scored %v, want in (0, 1]", score)
+	}
+	if score := lexicon.Score("Angry protesters arrested after awful attack"); score >= 0 || score < -1 {
+		t.Errorf("negative text scored %v, want in [-1, 0)", score)
+	}
+	if score := lexicon.Score("The committee met on Tuesday"); score != 0 {
+		t.Errorf("neutral text scored %v, want 0", score)
+	}
+}
+
+func TestLoadSentimentLexiconFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lexicon.json")
+	os.WriteFile(path, []byte(`{"Doubleplusgood": 3, "ungood": -3}`), 0o644)
+
+	lexicon, err := loadSentimentLexicon(path)
+	if err != nil {
+		t.Fatalf("loadSentimentLexicon: %v", err)
+	}
+	if got := lexicon.Compare("ungood news", "doubleplusgood news"); got.Original >= 0 || got.Transformed <= 0 {
+		t.Errorf("Compare = %+v, want the file's weights used", got)
+	}
+
+	os.WriteFile(path, []byte(`{}`), 0o644)
+	if _, err := loadSentimentLexicon(path); err == nil {
+		t.Error("empty lexicon accepted, want an error")
+	}
+}
+
+func TestTransformAnalyze(t *testing.T) {
+	setupTest(t, upstreams(newFakeOpenAI("Abundance brings the best achievement"), nil))
+
+	rec := do(newRequest(http.MethodPost, "/api/transform?analyze=true", `{"title": "Angry protesters arrested after awful attack"}`))
+	var response TransformResponse
+	decodeJSON(t, rec, &response)
+	if response.Sentiment == nil || response.Sentiment.Original >= 0 || response.Sentiment.Transformed <= 0 {
+		t.Errorf("sentiment = %+v, want negative turned positive", response.Sentiment)
+	}
+
+	rec = postTransform(`{"title": "Angry protesters arrested after awful attack"}`)
+	response = TransformResponse{}
+	decodeJSON(t, rec, &response)
+	if response.Sentiment != nil {
+		t.Error("sentiment reported without analyze=true")
+	}
+}
@@ -0,0 +1,184 @@
+package ministry
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Returned for a result token whose signature doesn't match
+var ErrInvalidResultToken = errors.New("Invalid result token")
+
+// Returned for a correctly signed result token past its expiry
+var ErrResultTokenExpired = errors.New("Result token has expired")
+
+// Signed contents of a result token: the transform's cache key, which
+// hashes the input and options, and when the token stops working
+type resultTokenPayload struct {
+	Key       string `json:"key"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Token granting access to the cached transform under key until expiresAt
+func signResultToken(key string, expiresAt time.Time, secret []byte) string {
+	payload, _ := json.Marshal(resultTokenPayload{Key: key, ExpiresAt: expiresAt.Unix()})
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Cache key from a token made by signResultToken, rejecting altered tokens
+// with ErrInvalidResultToken and ones expired by now with ErrResultTokenExpired
+func verifyResultToken(token string, now time.Time, secret []byte) (string, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", ErrInvalidResultToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", ErrInvalidResultToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return "", ErrInvalidResultToken
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return "", ErrInvalidResultToken
+	}
+
+	var decoded resultTokenPayload
+	if err := json.Unmarshal(payload, &decoded); err != nil || decoded.Key == "" {
+		return "", ErrInvalidResultToken
+	}
+	if !now.Before(time.Unix(decoded.ExpiresAt, 0)) {
+		return "", ErrResultTokenExpired
+	}
+	return decoded.Key, nil
+}
+
+// Response to POST /api/transform/sign
+type SignedResultResponse struct {
+	Token     string `json:"token"`
+	URL       string `json:"url"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
+// A cached transform fetched with a result token
+type SignedResult struct {
+	TransformedContent string `json:"transformedContent"`
+	Source             string `json:"source"`
+	Model              string `json:"model,omitempty"`
+	FinishReason       string `json:"finishReason,omitempty"`
+	Truncated          bool   `json:"truncated"`
+}
+
+// Issue a short-lived token for an already cached transform, so it can be
+// shared or embedded without calling the API again. The body names the
+// transform the way POST /api/transform does.
+func signTransformResult(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	secret := currentConfig().SigningSecret
+	if len(secret) == 0 {
+		http.Error(w, "Signed results require SIGNING_SECRET to be configured", http.StatusNotImplemented)
+		return
+	}
+
+	var requestData struct {
+		Title            string `json:"title"`
+		Description      string `json:"description"`
+		Intensity        string `json:"intensity"`
+		Persona          string `json:"persona"`
+		PreserveEntities bool   `json:"preserveEntities"`
+		Seed             *int64 `json:"seed"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	title, description := strings.TrimSpace(requestData.Title), strings.TrimSpace(requestData.Description)
+	if err := validateTransformInput(title, description); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Same options, and so the same cache key, as the transform request
+	options, err := resolveTransformOptions(requestData.Intensity, requestData.Persona)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	options.PreserveEntities = requestData.PreserveEntities
+	options.Seed = requestData.Seed
+	if options.Seed == nil {
+		options.Seed = currentConfig().TransformSeed
+	}
+	options.Language = promptLanguage(r.Header.Get("Accept-Language"))
+
+	key := transformCacheKey(currentConfig().LLMProvider, options, title, description)
+	if _, ok := getCachedTransform(r.Context(), key); !ok {
+		http.Error(w, "No cached transform for this input; transform it first", http.StatusNotFound)
+		return
+	}
+
+	expiresAt := time.Now().Add(currentConfig().SignedResultTTL)
+	token := signResultToken(key, expiresAt, secret)
+	json.NewEncoder(w).Encode(SignedResultResponse{
+		Token:     token,
+		URL:       "/api/transform/result?token=" + url.QueryEscape(token),
+		ExpiresAt: expiresAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// Serve the cached transform a result token points at. The token is the
+// credential, so this route doesn't need an API key.
+func getTransformResult(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	secret := currentConfig().SigningSecret
+	if len(secret) == 0 {
+		http.Error(w, "Signed results require SIGNING_SECRET to be configured", http.StatusNotImplemented)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Query parameter 'token' is required", http.StatusBadRequest)
+		return
+	}
+	key, err := verifyResultToken(token, time.Now(), secret)
+	switch {
+	case errors.Is(err, ErrResultTokenExpired):
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	cached, ok := getCachedTransform(r.Context(), key)
+	if !ok {
+		http.Error(w, "Transform is no longer cached", http.StatusNotFound)
+		return
+	}
+
+	// The same output limits and screening as /api/transform
+	result := SignedResult{Source: cached.Source, Model: cached.Model, FinishReason: cached.FinishReason}
+	result.TransformedContent, result.Truncated = truncateText(cached.Content, currentConfig().TransformMaxChars)
+	if result.TransformedContent, err = currentConfig().Profanity.Apply(result.TransformedContent); err != nil {
+		slog.WarnContext(r.Context(), "signed result rejected by profanity filter")
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSONWithETag(w, r, result)
+}
@@ -0,0 +1,100 @@
+package ministry
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedTransformResult(t *testing.T) {
+	openAI := newFakeOpenAI("All is well in Oceania")
+	setupTest(t, openAI, "SIGNING_SECRET", "test-signing-secret", "SERVICE_API_KEYS", "alpha")
+
+	body := `{"title": "Rations cut", "description": "Chocolate down to 20g"}`
+	if rec := do(newRequest(http.MethodPost, "/api/transform/sign", body, "X-API-Key", "alpha")); rec.Code != http.StatusNotFound {
+		t.Fatalf("sign before transforming: status = %d, want 404", rec.Code)
+	}
+	if rec := postTransform(body, "X-API-Key", "alpha"); rec.Code != http.StatusOK {
+		t.Fatalf("transform status = %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := do(newRequest(http.MethodPost, "/api/transform/sign", body, "X-API-Key", "alpha"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("sign status = %d: %s", rec.Code, rec.Body)
+	}
+	var signed SignedResultResponse
+	decodeJSON(t, rec, &signed)
+	if signed.Token == "" || !strings.HasPrefix(signed.URL, "/api/transform/result?token=") {
+		t.Fatalf("signed = %+v, want a token and result URL", signed)
+	}
+
+	// The token stands in for the API key
+	rec = do(newRequest(http.MethodGet, signed.URL, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("result status = %d: %s", rec.Code, rec.Body)
+	}
+	var result SignedResult
+	decodeJSON(t, rec, &result)
+	if result.TransformedContent != "All is well in Oceania" || result.Source != "openai" {
+		t.Errorf("result = %+v, want the cached transform", result)
+	}
+	if calls := openAI.Calls(); calls != 1 {
+		t.Errorf("upstream calls = %d, want 1", calls)
+	}
+}
+
+func TestSignedTransformResultRejectsBadTokens(t *testing.T) {
+	setupTest(t, nil, "SIGNING_SECRET", "test-signing-secret")
+	secret := []byte("test-signing-secret")
+
+	valid := signResultToken("some-key", time.Now().Add(time.Minute), secret)
+	payload, signature, _ := strings.Cut(valid, ".")
+	tampered := payload + "." + strings.Map(func(r rune) rune {
+		if r == 'A' {
+			return 'B'
+		}
+		return 'A'
+	}, signature)
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"missing", "", http.StatusBadRequest},
+		{"expired", signResultToken("some-key", time.Now().Add(-time.Second), secret), http.StatusGone},
+		{"tampered signature", tampered, http.StatusForbidden},
+		{"other secret", signResultToken("some-key", time.Now().Add(time.Minute), []byte("other")), http.StatusForbidden},
+		{"malformed", "not-a-token", http.StatusForbidden},
+		{"valid but not cached", valid, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := do(newRequest(http.MethodGet, "/api/transform/result?token="+test.token, ""))
+			if rec.Code != test.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, test.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestVerifyResultToken(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Now()
+	token := signResultToken("cache-key", now.Add(time.Minute), secret)
+
+	if key, err := verifyResultToken(token, now, secret); err != nil || key != "cache-key" {
+		t.Errorf("verify = %q, %v; want cache-key", key, err)
+	}
+	if _, err := verifyResultToken(token, now.Add(time.Minute), secret); err != ErrResultTokenExpired {
+		t.Errorf("verify at expiry = %v, want ErrResultTokenExpired", err)
+	}
+}
+
+func TestSignedResultsNeedSigningSecret(t *testing.T) {
+	setupTest(t, nil)
+	if rec := do(newRequest(http.MethodGet, "/api/transform/result?token=x", "")); rec.Code != http.StatusNotImplemented {
+		t.Errorf("status = %d without SIGNING_SECRET, want 501", rec.Code)
+	}
+}
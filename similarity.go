@@ -0,0 +1,39 @@
+package ministry
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Split text into a set of lowercase word tokens
+func tokenSet(text string) map[string]struct{} {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	set := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		set[word] = struct{}{}
+	}
+	return set
+}
+
+// Jaccard similarity between the token sets of two texts (0 = disjoint, 1 = identical)
+func jaccardSimilarity(a, b string) float64 {
+	setA := tokenSet(a)
+	setB := tokenSet(b)
+
+	if len(setA) == 0 && len(setB) == 0 {
+		return 1
+	}
+
+	intersection := 0
+	for token := range setA {
+		if _, ok := setB[token]; ok {
+			intersection++
+		}
+	}
+
+	union := len(setA) + len(setB) - intersection
+	return float64(intersection) / float64(union)
+}
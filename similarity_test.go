@@ -0,0 +1,64 @@
+package ministry
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestJaccardSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"Big Brother is watching", "big brother, is WATCHING!", 1},
+		{"war is peace", "freedom is slavery", 0.2},
+		{"ignorance", "strength", 0},
+		{"", "", 1},
+	}
+	for _, tt := range tests {
+		if got := jaccardSimilarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("jaccardSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestTransformReportsSimilarity(t *testing.T) {
+	openAI := newFakeOpenAI("Chocolate ration raised")
+	setupTest(t, upstreams(openAI, nil), "SIMILARITY_CHECK", "true")
+
+	rec := postTransform(`{"title": "Chocolate ration raised"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	var response TransformResponse
+	decodeJSON(t, rec, &response)
+	if response.Similarity == nil || *response.Similarity != 1 {
+		t.Errorf("similarity = %v, want 1", response.Similarity)
+	}
+	if !response.TooSimilar {
+		t.Error("tooSimilar = false for an echoed title")
+	}
+}
+
+func TestTransformRetriesWhenTooSimilar(t *testing.T) {
+	openAI := newFakeOpenAI("Chocolate ration raised", "Plenty announces a glorious increase")
+	setupTest(t, upstreams(openAI, nil), "SIMILARITY_CHECK", "true", "SIMILARITY_RETRY", "true")
+
+	rec := postTransform(`{"title": "Chocolate ration raised"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	var response TransformResponse
+	decodeJSON(t, rec, &response)
+	if response.TransformedContent != "Plenty announces a glorious increase" {
+		t.Errorf("transformedContent = %q, want the retried transform", response.TransformedContent)
+	}
+	if response.TooSimilar {
+		t.Error("tooSimilar = true after a successful retry")
+	}
+	if calls := openAI.Calls(); calls != 2 {
+		t.Errorf("model calls = %d, want 2", calls)
+	}
+}
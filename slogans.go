@@ -0,0 +1,65 @@
+package ministry
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Party slogans, shared with the serverless handler
+//
+//go:embed api/slogans.json
+var slogansJSON []byte
+
+var slogans = mustParseSlogans(slogansJSON)
+
+const (
+	defaultSloganCount = 3
+	maxSloganCount     = 50
+)
+
+func mustParseSlogans(data []byte) []string {
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil || len(list) == 0 {
+		panic(fmt.Sprintf("invalid embedded slogans: %v", err))
+	}
+	return list
+}
+
+// Pick count slogans; no repeats until every slogan has been used once
+func pickSlogans(rng *rand.Rand, list []string, count int) []string {
+	picked := make([]string, 0, count)
+	for len(picked) < count {
+		for _, i := range rng.Perm(len(list)) {
+			if len(picked) == count {
+				break
+			}
+			picked = append(picked, list[i])
+		}
+	}
+	return picked
+}
+
+// Random slogans endpoint
+func getSlogans(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	count := defaultSloganCount
+	if value := r.URL.Query().Get("count"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxSloganCount {
+			http.Error(w, fmt.Sprintf("Query parameter 'count' must be between 1 and %d", maxSloganCount), http.StatusBadRequest)
+			return
+		}
+		count = parsed
+	}
+
+	seed := uint64(time.Now().UnixNano())
+	rng := rand.New(rand.NewPCG(seed, seed>>1))
+
+	json.NewEncoder(w).Encode(pickSlogans(rng, slogans, count))
+}
@@ -0,0 +1,49 @@
+package ministry
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"testing"
+)
+
+func TestPickSlogansAvoidsRepeats(t *testing.T) {
+	list := []string{"War is Peace", "Freedom is Slavery", "Ignorance is Strength"}
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	picked := pickSlogans(rng, list, 3)
+	seen := map[string]bool{}
+	for _, slogan := range picked {
+		if seen[slogan] {
+			t.Fatalf("pickSlogans repeated %q before using every slogan: %q", slogan, picked)
+		}
+		seen[slogan] = true
+	}
+
+	// Asking for more than there are cycles through the list again
+	if picked := pickSlogans(rng, list, 7); len(picked) != 7 {
+		t.Errorf("pickSlogans returned %d slogans, want 7", len(picked))
+	}
+}
+
+func TestSlogansEndpoint(t *testing.T) {
+	setupTest(t, nil)
+
+	rec := do(newRequest(http.MethodGet, "/api/slogans", ""))
+	var got []string
+	decodeJSON(t, rec, &got)
+	if len(got) != defaultSloganCount {
+		t.Errorf("got %d slogans, want %d", len(got), defaultSloganCount)
+	}
+
+	rec = do(newRequest(http.MethodGet, "/api/slogans?count=5", ""))
+	decodeJSON(t, rec, &got)
+	if len(got) != 5 {
+		t.Errorf("got %d slogans for count=5", len(got))
+	}
+
+	for _, count := range []string{"0", "51", "many"} {
+		if rec := do(newRequest(http.MethodGet, "/api/slogans?count="+count, "")); rec.Code != http.StatusBadRequest {
+			t.Errorf("count=%s: status = %d, want 400", count, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package ministry
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Orders accepted by the sort query parameter
+var articleSorts = []string{"publishedAt_desc", "publishedAt_asc", "source", "title"}
+
+// Validate the sort query parameter; empty keeps the provider's order
+func parseArticleSort(raw string) (string, error) {
+	if raw == "" || slices.Contains(articleSorts, raw) {
+		return raw, nil
+	}
+	return "", fmt.Errorf("Invalid sort %q; allowed values: %s", raw, strings.Join(articleSorts, ", "))
+}
+
+// Publication time of an article, if it has a parseable one
+func publishedTime(article Article) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, article.PublishedAt)
+	return t, err == nil
+}
+
+// Reorder articles in place. Sorting is stable, and articles without a
+// usable timestamp go last for both date orders.
+func sortArticles(articles []Article, order string) {
+	switch order {
+	case "publishedAt_desc", "publishedAt_asc":
+		slices.SortStableFunc(articles, func(a, b Article) int {
+			ta, okA := publishedTime(a)
+			tb, okB := publishedTime(b)
+			switch {
+			case !okA || !okB:
+				if okA == okB {
+					return 0
+				}
+				if okA {
+					return -1
+				}
+				return 1
+			case order == "publishedAt_desc":
+				return tb.Compare(ta)
+			default:
+				return ta.Compare(tb)
+			}
+		})
+	case "source":
+		slices.SortStableFunc(articles, func(a, b Article) int {
+			return cmp.Compare(strings.ToLower(a.Source.Name), strings.ToLower(b.Source.Name))
+		})
+	case "title":
+		slices.SortStableFunc(articles, func(a, b Article) int {
+			return cmp.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+		})
+	}
+}
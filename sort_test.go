@@ -0,0 +1,75 @@
+package ministry
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseArticleSort(t *testing.T) {
+	for _, raw := range append([]string{""}, articleSorts...) {
+		if got, err := parseArticleSort(raw); err != nil || got != raw {
+			t.Errorf("parseArticleSort(%q) = %q, %v", raw, got, err)
+		}
+	}
+	if _, err := parseArticleSort("newest"); err == nil || !strings.Contains(err.Error(), "publishedAt_desc") {
+		t.Errorf("parseArticleSort(newest) error = %v, want the allowed values", err)
+	}
+}
+
+func sortFixture() []Article {
+	return []Article{
+		{Title: "b undated", Source: Source{Name: "Times"}},
+		{Title: "Chocolate ration raised", PublishedAt: "2024-05-02T09:00:00Z", Source: Source{Name: "minitrue"}},
+		{Title: "a garbled date", PublishedAt: "yesterday", Source: Source{Name: "Times"}},
+		{Title: "Lift broken again", PublishedAt: "2024-05-03T09:00:00+02:00", Source: Source{Name: "Airstrip One Herald"}},
+		{Title: "anthem rewritten", PublishedAt: "2024-05-01T09:00:00Z", Source: Source{Name: "minitrue"}},
+	}
+}
+
+func titles(articles []Article) []string {
+	var out []string
+	for _, article := range articles {
+		out = append(out, article.Title)
+	}
+	return out
+}
+
+func TestSortArticles(t *testing.T) {
+	tests := map[string][]string{
+		// Undated articles go last in their original order
+		"publishedAt_desc": {"Lift broken again", "Chocolate ration raised", "anthem rewritten", "b undated", "a garbled date"},
+		"publishedAt_asc":  {"anthem rewritten", "Chocolate ration raised", "Lift broken again", "b undated", "a garbled date"},
+		// Case-insensitive, and stable within a source
+		"source": {"Lift broken again", "Chocolate ration raised", "anthem rewritten", "b undated", "a garbled date"},
+		"title":  {"a garbled date", "anthem rewritten", "b undated", "Chocolate ration raised", "Lift broken again"},
+		"":       titles(sortFixture()),
+	}
+	for order, want := range tests {
+		articles := sortFixture()
+		sortArticles(articles, order)
+		if got := titles(articles); !slices.Equal(got, want) {
+			t.Errorf("sortArticles(%q) = %q, want %q", order, got, want)
+		}
+	}
+}
+
+func TestHeadlinesSortParam(t *testing.T) {
+	news := newFakeNews(sortFixture()...)
+	setupTest(t, upstreams(nil, news))
+
+	var response NewsResponse
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/news/headlines?sort=title", "")), &response)
+	if got := titles(response.Articles); got[0] != "a garbled date" || got[4] != "Lift broken again" {
+		t.Errorf("titles = %q, want them sorted by title", got)
+	}
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines?sort=newest", ""))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "Invalid sort") {
+		t.Errorf("status = %d, body %q; want 400", rec.Code, rec.Body.String())
+	}
+	if len(news.Queries()) != 1 {
+		t.Errorf("an invalid sort reached NewsAPI")
+	}
+}
@@ -0,0 +1,88 @@
+package ministry
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// NewsAPI sources listing
+type SourcesResponse struct {
+	Status  string       `json:"status"`
+	Sources []NewsSource `json:"sources"`
+}
+
+// A publisher available through NewsAPI
+type NewsSource struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	Category    string `json:"category"`
+	Language    string `json:"language"`
+	Country     string `json:"country"`
+}
+
+// Build the NewsAPI sources endpoint from the category, language and country filters
+func sourcesEndpoint(query url.Values) (string, error) {
+	filters := url.Values{}
+
+	if category := strings.ToLower(query.Get("category")); category != "" {
+		if !validCategory(category) {
+			return "", fmt.Errorf("Invalid category %q; allowed values: %s", category, strings.Join(newsCategories, ", "))
+		}
+		filters.Set("category", category)
+	}
+
+	for _, key := range []string{"language", "country"} {
+		value := strings.ToLower(query.Get(key))
+		if value == "" {
+			continue
+		}
+		if len(value) != 2 || strings.Trim(value, "abcdefghijklmnopqrstuvwxyz") != "" {
+			return "", fmt.Errorf("Invalid %s %q; expected a two-letter code", key, value)
+		}
+		filters.Set(key, value)
+	}
+
+	if len(filters) == 0 {
+		return "/top-headlines/sources", nil
+	}
+	return "/top-headlines/sources?" + filters.Encode(), nil
+}
+
+// List the news sources NewsAPI offers
+func getSources(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if currentConfig().NewsProvider != "newsapi" {
+		http.Error(w, "Listing sources requires NEWS_PROVIDER=newsapi", http.StatusNotImplemented)
+		return
+	}
+
+	endpoint, err := sourcesEndpoint(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Cached per filter combination for SOURCES_CACHE_TTL
+	body, err := fetchNewsAPI(r.Context(), endpoint, currentConfig().SourcesCacheTTL)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error fetching sources", "error", err)
+		http.Error(w, fmt.Sprintf("Error fetching sources: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var sourcesResponse SourcesResponse
+	if err := json.Unmarshal(body, &sourcesResponse); err != nil {
+		slog.ErrorContext(r.Context(), "error parsing sources", "error", err)
+		http.Error(w, "Error parsing sources", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSONWithETag(w, r, sourcesResponse)
+}
@@ -0,0 +1,107 @@
+package ministry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSourcesEndpoint(t *testing.T) {
+	tests := map[string]string{
+		"":                                       "/top-headlines/sources",
+		"category=Science":                       "/top-headlines/sources?category=science",
+		"language=en&country=gb&category=health": "/top-headlines/sources?category=health&country=gb&language=en",
+	}
+	for raw, want := range tests {
+		query, _ := url.ParseQuery(raw)
+		if got, err := sourcesEndpoint(query); err != nil || got != want {
+			t.Errorf("sourcesEndpoint(%s) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+
+	for _, raw := range []string{"category=gossip", "language=klingon", "country=oceania"} {
+		query, _ := url.ParseQuery(raw)
+		if _, err := sourcesEndpoint(query); err == nil {
+			t.Errorf("sourcesEndpoint(%s) succeeded, want an error", raw)
+		}
+	}
+}
+
+// NewsAPI stand-in listing sources, counting the calls it gets
+func sourcesHandler(calls *atomic.Int32, sources ...NewsSource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		json.NewEncoder(w).Encode(SourcesResponse{Status: "ok", Sources: sources})
+	}
+}
+
+func TestSourcesListing(t *testing.T) {
+	var calls atomic.Int32
+	upstream := http.NewServeMux()
+	upstream.Handle(newsSourcesPath, sourcesHandler(&calls, NewsSource{ID: "the-times", Name: "The Times", Category: "general"}))
+	setupTest(t, upstream)
+
+	rec := do(newRequest(http.MethodGet, "/api/news/sources?category=general", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var response SourcesResponse
+	decodeJSON(t, rec, &response)
+	if response.Status != "ok" || len(response.Sources) != 1 || response.Sources[0].ID != "the-times" {
+		t.Errorf("response = %+v", response)
+	}
+
+	// The listing is served from the news cache the second time
+	do(newRequest(http.MethodGet, "/api/news/sources?category=general", ""))
+	if calls.Load() != 1 {
+		t.Errorf("NewsAPI got %d calls, want 1", calls.Load())
+	}
+
+	rec = do(newRequest(http.MethodGet, "/api/news/sources?country=oceania", ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d for an unknown country, want 400", rec.Code)
+	}
+}
+
+func TestSourcesCacheKeyedByFilters(t *testing.T) {
+	var calls atomic.Int32
+	upstream := http.NewServeMux()
+	upstream.Handle(newsSourcesPath, sourcesHandler(&calls, NewsSource{ID: "the-times", Name: "The Times"}))
+	setupTest(t, upstream)
+
+	for _, path := range []string{"/api/news/sources?category=general", "/api/news/sources?category=science", "/api/news/sources?category=general"} {
+		if rec := do(newRequest(http.MethodGet, path, "")); rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", path, rec.Code)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Errorf("NewsAPI got %d calls, want one per filter combination", calls.Load())
+	}
+}
+
+func TestSourcesCacheTTLIndependentOfNews(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       []string
+		wantCalls int32
+	}{
+		{"short news TTL", []string{"NEWS_CACHE_TTL", "1ns"}, 1},
+		{"short sources TTL", []string{"SOURCES_CACHE_TTL", "1ns"}, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls atomic.Int32
+			upstream := http.NewServeMux()
+			upstream.Handle(newsSourcesPath, sourcesHandler(&calls, NewsSource{ID: "the-times", Name: "The Times"}))
+			setupTest(t, upstream, test.env...)
+
+			do(newRequest(http.MethodGet, "/api/news/sources", ""))
+			do(newRequest(http.MethodGet, "/api/news/sources", ""))
+			if calls.Load() != test.wantCalls {
+				t.Errorf("NewsAPI got %d calls, want %d", calls.Load(), test.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package ministry
+
+import (
+	"net/http"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Cache-Control values for static responses
+const (
+	htmlCacheControl        = "no-cache"
+	assetCacheControl       = "public, max-age=3600"
+	hashedAssetCacheControl = "public, max-age=31536000, immutable"
+)
+
+// Fingerprinted asset names such as app.3f9a1c2b.js or logo-5d41402abc4b2a76.png
+var hashedAssetPattern = regexp.MustCompile(`[.-][0-9a-fA-F]{8,}\.[A-Za-z0-9]+$`)
+
+// Cache-Control for a static file path
+func staticCacheControl(urlPath string) string {
+	switch {
+	case strings.HasSuffix(urlPath, ".html"):
+		return htmlCacheControl
+	case hashedAssetPattern.MatchString(urlPath):
+		return hashedAssetCacheControl
+	default:
+		return assetCacheControl
+	}
+}
+
+// Whether a path belongs to the JSON API rather than the static site
+func isAPIPath(urlPath string) bool {
+	urlPath = path.Clean("/" + urlPath)
+	return urlPath == "/api" || strings.HasPrefix(urlPath, "/api/")
+}
+
+// Serve files from dir with cache headers, falling back to index.html for
+// client-side routes. API paths and /health are never rewritten.
+func staticHandler(dir string) http.Handler {
+	files := http.Dir(dir)
+	fileServer := http.FileServer(files)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		urlPath := path.Clean("/" + r.URL.Path)
+		if urlPath == "/health" || isAPIPath(urlPath) {
+			notFound(w, r)
+			return
+		}
+
+		if f, err := files.Open(urlPath); err == nil {
+			info, err := f.Stat()
+			f.Close()
+			if err == nil {
+				if info.IsDir() {
+					w.Header().Set("Cache-Control", htmlCacheControl)
+				} else {
+					w.Header().Set("Cache-Control", staticCacheControl(urlPath))
+				}
+				fileServer.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		// Missing files stay 404; anything without an extension is a deep link
+		if path.Ext(urlPath) != "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Cache-Control", htmlCacheControl)
+		http.ServeFile(w, r, filepath.Join(dir, "index.html"))
+	})
+}
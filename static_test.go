@@ -0,0 +1,62 @@
+package ministry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStaticCacheControl(t *testing.T) {
+	tests := map[string]string{
+		"/index.html":                htmlCacheControl,
+		"/app.3f9a1c2b.js":           hashedAssetCacheControl,
+		"/logo-5d41402abc4b2a76.png": hashedAssetCacheControl,
+		"/style.css":                 assetCacheControl,
+	}
+	for path, want := range tests {
+		if got := staticCacheControl(path); got != want {
+			t.Errorf("staticCacheControl(%s) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestIsAPIPath(t *testing.T) {
+	for path, want := range map[string]bool{"/api": true, "/api/news": true, "/api/../api/x": true, "/apiary": false, "/": false} {
+		if got := isAPIPath(path); got != want {
+			t.Errorf("isAPIPath(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestStaticHandler(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("<h1>Ministry of Truth</h1>"), 0o644)
+	os.WriteFile(filepath.Join(dir, "app.3f9a1c2b.js"), []byte("console.log('ingsoc')"), 0o644)
+	handler := staticHandler(dir)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newRequest(http.MethodGet, path, ""))
+		return rec
+	}
+
+	rec := get("/app.3f9a1c2b.js")
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != hashedAssetCacheControl || !strings.Contains(rec.Body.String(), "ingsoc") {
+		t.Errorf("asset: status %d, Cache-Control %q", rec.Code, rec.Header().Get("Cache-Control"))
+	}
+
+	// Deep links get the app shell
+	rec = get("/articles/1984")
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != htmlCacheControl || !strings.Contains(rec.Body.String(), "Ministry of Truth") {
+		t.Errorf("deep link: status %d, Cache-Control %q, body %q", rec.Code, rec.Header().Get("Cache-Control"), rec.Body.String())
+	}
+
+	for _, path := range []string{"/missing.js", "/api/unknown", "/health"} {
+		if rec := get(path); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", path, rec.Code)
+		}
+	}
+}
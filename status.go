@@ -0,0 +1,191 @@
+package ministry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// How far back errors count towards a dependency's recent error total
+const statusErrorWindow = 15 * time.Minute
+
+// Dependency health states reported by /api/status
+const (
+	healthUnknown   = "unknown"
+	healthHealthy   = "healthy"
+	healthDegraded  = "degraded"
+	healthUnhealthy = "unhealthy"
+)
+
+// Outcomes of the calls made to one upstream dependency. A nil
+// *DependencyHealth records nothing.
+type DependencyHealth struct {
+	mu          sync.Mutex
+	lastSuccess time.Time
+	lastError   time.Time
+	lastMessage string
+	errors      []time.Time // within statusErrorWindow, oldest first
+	now         func() time.Time
+}
+
+func NewDependencyHealth() *DependencyHealth {
+	return &DependencyHealth{now: time.Now}
+}
+
+// Record the outcome of a call; cancellation by the caller isn't counted.
+// The message is kept with upstream API keys redacted, since network errors
+// quote the request URL and /api/status shows it.
+func (d *DependencyHealth) Record(err error) {
+	if d == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	if err == nil {
+		d.lastSuccess = now
+		return
+	}
+	d.lastError = now
+	d.lastMessage = redactSecrets(err.Error(), upstreamKeys(currentConfig()))
+	d.errors = append(d.prune(now), now)
+}
+
+// Drop errors that have left the window; callers hold mu
+func (d *DependencyHealth) prune(now time.Time) []time.Time {
+	i := 0
+	for i < len(d.errors) && now.Sub(d.errors[i]) > statusErrorWindow {
+		i++
+	}
+	return d.errors[i:]
+}
+
+// Health of one dependency as shown by /api/status
+type DependencyStatus struct {
+	Status       string     `json:"status"`
+	Provider     string     `json:"provider"`
+	LastChecked  *time.Time `json:"lastChecked,omitempty"`
+	LastSuccess  *time.Time `json:"lastSuccess,omitempty"`
+	LastError    *time.Time `json:"lastError,omitempty"`
+	LastMessage  string     `json:"lastErrorMessage,omitempty"`
+	RecentErrors int        `json:"recentErrors"`
+	Circuit      string     `json:"circuit,omitempty"`
+}
+
+// Current status: unhealthy when the latest call failed, degraded when it
+// succeeded but errors remain in the window, unknown before any call
+func (d *DependencyHealth) Status() DependencyStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.errors = d.prune(d.now())
+	lastSuccess, lastError := d.lastSuccess, d.lastError
+	status := DependencyStatus{Status: healthUnknown, RecentErrors: len(d.errors), LastMessage: d.lastMessage}
+	if !lastSuccess.IsZero() {
+		status.LastSuccess = &lastSuccess
+		status.LastChecked = &lastSuccess
+		status.Status = healthHealthy
+		if status.RecentErrors > 0 {
+			status.Status = healthDegraded
+		}
+	}
+	if !lastError.IsZero() {
+		status.LastError = &lastError
+		if lastError.After(lastSuccess) {
+			status.LastChecked = &lastError
+			status.Status = healthUnhealthy
+		}
+	}
+	return status
+}
+
+// Call outcomes for the news provider, the model API and the cache backend
+var (
+	newsHealth  = NewDependencyHealth()
+	llmHealth   = NewDependencyHealth()
+	cacheHealth = NewDependencyHealth()
+)
+
+// Record a news provider outcome. Errors caused by the request itself, such
+// as bad parameters, still show the provider is up.
+func recordNewsHealth(err error) {
+	if err != nil && !newsUpstreamDown(err) {
+		err = nil
+	}
+	newsHealth.Record(err)
+}
+
+// Cache that reports backend errors to a DependencyHealth
+type healthCache struct {
+	Cache
+	Health *DependencyHealth
+}
+
+func (c healthCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, ok, err := c.Cache.Get(ctx, key)
+	c.Health.Record(err)
+	return value, ok, err
+}
+
+func (c healthCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	err := c.Cache.Set(ctx, key, value, ttl)
+	c.Health.Record(err)
+	return err
+}
+
+type StatusResponse struct {
+	Status       string                      `json:"status"`
+	Time         string                      `json:"time"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
+// Consolidated dependency health from recorded call outcomes and the circuit
+// breaker; nothing is probed. Overall status is degraded when any dependency
+// is unhealthy.
+func dependencyStatus(cfg *Config, breaker *CircuitBreaker) StatusResponse {
+	news := newsHealth.Status()
+	news.Provider = cfg.NewsProvider
+	cache := cacheHealth.Status()
+	cache.Provider = cfg.CacheBackend
+	llm := llmHealth.Status()
+	llm.Provider = cfg.LLMProvider
+	llm.Circuit = "disabled"
+	if breaker != nil {
+		state, _ := breaker.State()
+		llm.Circuit = state
+		switch state {
+		case breakerOpen:
+			llm.Status = healthUnhealthy
+		case breakerHalfOpen:
+			llm.Status = healthDegraded
+		}
+	}
+
+	response := StatusResponse{
+		Status: "ok",
+		Time:   time.Now().Format(time.RFC3339),
+		Dependencies: map[string]DependencyStatus{
+			"news":  news,
+			"llm":   llm,
+			"cache": cache,
+		},
+	}
+	for _, dependency := range response.Dependencies {
+		if dependency.Status == healthUnhealthy {
+			response.Status = "degraded"
+		}
+	}
+	return response
+}
+
+// Health summary of the news provider, the model API and the cache backend
+func getStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(dependencyStatus(currentConfig(), llmBreaker))
+}
@@ -0,0 +1,166 @@
+package ministry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Dependency health on a clock the test moves by hand
+func manualHealth() (*DependencyHealth, func(time.Duration)) {
+	now := time.Now()
+	health := NewDependencyHealth()
+	health.now = func() time.Time { return now }
+	return health, func(d time.Duration) { now = now.Add(d) }
+}
+
+// Start the test with no recorded outcomes; call before setupTest so the
+// cache wrappers pick up the fresh record
+func resetHealth(t *testing.T) {
+	t.Helper()
+	previous := [3]*DependencyHealth{newsHealth, llmHealth, cacheHealth}
+	newsHealth, llmHealth, cacheHealth = NewDependencyHealth(), NewDependencyHealth(), NewDependencyHealth()
+	t.Cleanup(func() { newsHealth, llmHealth, cacheHealth = previous[0], previous[1], previous[2] })
+}
+
+func TestDependencyHealth(t *testing.T) {
+	health, advance := manualHealth()
+	if status := health.Status(); status.Status != healthUnknown || status.LastChecked != nil {
+		t.Errorf("before any call: %+v", status)
+	}
+
+	health.Record(nil)
+	if status := health.Status(); status.Status != healthHealthy || status.LastSuccess == nil || status.RecentErrors != 0 {
+		t.Errorf("after a success: %+v", status)
+	}
+
+	advance(time.Minute)
+	health.Record(errors.New("connection refused"))
+	status := health.Status()
+	if status.Status != healthUnhealthy || status.RecentErrors != 1 || status.LastMessage != "connection refused" || !status.LastChecked.Equal(*status.LastError) {
+		t.Errorf("after a failure: %+v", status)
+	}
+
+	advance(time.Minute)
+	health.Record(nil)
+	if status := health.Status(); status.Status != healthDegraded || status.RecentErrors != 1 {
+		t.Errorf("after recovering: %+v", status)
+	}
+
+	// Old errors leave the window
+	advance(statusErrorWindow)
+	if status := health.Status(); status.Status != healthHealthy || status.RecentErrors != 0 {
+		t.Errorf("once the error has aged out: %+v", status)
+	}
+
+	// A caller cancelling isn't the dependency's fault
+	health.Record(fmt.Errorf("fetch: %w", context.Canceled))
+	if status := health.Status(); status.RecentErrors != 0 {
+		t.Errorf("cancellation counted: %+v", status)
+	}
+
+	var none *DependencyHealth
+	none.Record(errors.New("ignored"))
+}
+
+func TestRecordNewsHealthIgnoresBadRequests(t *testing.T) {
+	resetHealth(t)
+
+	recordNewsHealth(errors.New("NewsAPI returned status 400"))
+	if status := newsHealth.Status(); status.Status != healthHealthy {
+		t.Errorf("after a bad request: %+v, want healthy", status)
+	}
+	recordNewsHealth(fmt.Errorf("%w: NewsAPI returned status 503", ErrNewsUnavailable))
+	if status := newsHealth.Status(); status.Status != healthUnhealthy || status.RecentErrors != 1 {
+		t.Errorf("after a 503: %+v, want unhealthy", status)
+	}
+}
+
+func TestStatusEndpoint(t *testing.T) {
+	resetHealth(t)
+	setupTest(t, upstreams(newFakeOpenAI("Rations are plentiful"), newFakeNews(Article{Title: "Lift broken again"})))
+
+	postTransform(`{"title": "Lift broken again"}`)
+	do(newRequest(http.MethodGet, "/api/news/headlines", ""))
+
+	rec := do(newRequest(http.MethodGet, "/api/status", ""))
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("status = %d, Cache-Control %q", rec.Code, rec.Header().Get("Cache-Control"))
+	}
+	var response StatusResponse
+	decodeJSON(t, rec, &response)
+	if response.Status != "ok" || response.Time == "" || len(response.Dependencies) != 3 {
+		t.Fatalf("response = %+v", response)
+	}
+
+	want := map[string]string{"news": "newsapi", "llm": "openai", "cache": "memory"}
+	for name, provider := range want {
+		dependency, ok := response.Dependencies[name]
+		if !ok {
+			t.Errorf("no %s dependency", name)
+			continue
+		}
+		if dependency.Status != healthHealthy || dependency.Provider != provider || dependency.LastChecked == nil {
+			t.Errorf("%s = %+v, want healthy %s", name, dependency, provider)
+		}
+	}
+	if circuit := response.Dependencies["llm"].Circuit; circuit != breakerClosed {
+		t.Errorf("llm circuit = %q, want closed", circuit)
+	}
+}
+
+// RoundTripper from a function
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestStatusRedactsAPIKeys(t *testing.T) {
+	resetHealth(t)
+	setupTest(t, nil)
+	upstreamTransport = roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	do(newRequest(http.MethodGet, "/api/news/headlines", ""))
+	body := do(newRequest(http.MethodGet, "/api/status", "")).Body.String()
+	if !strings.Contains(body, "connection refused") || strings.Contains(body, "test-news-key") || !strings.Contains(body, "[REDACTED]") {
+		t.Errorf("status body = %s, want the error with the NewsAPI key redacted", body)
+	}
+}
+
+func TestStatusShowsTrippedBreaker(t *testing.T) {
+	resetHealth(t)
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		http.Error(w, "overloaded", http.StatusInternalServerError)
+	}
+	setupTest(t, upstreams(openAI, nil), "BREAKER_THRESHOLD", "2", "BREAKER_COOLDOWN", "1m")
+
+	for range 2 {
+		postTransform(`{"title": "Lift broken again"}`)
+	}
+
+	var response StatusResponse
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/status", "")), &response)
+	llm := response.Dependencies["llm"]
+	if llm.Status != healthUnhealthy || llm.Circuit != breakerOpen || llm.RecentErrors != 2 || llm.LastError == nil {
+		t.Errorf("llm = %+v, want unhealthy with the circuit open", llm)
+	}
+	if response.Status != "degraded" {
+		t.Errorf("overall status = %q, want degraded", response.Status)
+	}
+}
+
+func TestStatusWithoutBreaker(t *testing.T) {
+	resetHealth(t)
+	cfg := setupTest(t, nil, "BREAKER_THRESHOLD", "0")
+
+	status := dependencyStatus(cfg, nil)
+	if llm := status.Dependencies["llm"]; llm.Circuit != "disabled" || llm.Status != healthUnknown {
+		t.Errorf("llm = %+v, want an unknown status with the circuit disabled", llm)
+	}
+}
@@ -0,0 +1,61 @@
+package ministry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+)
+
+// Parse ?minArticles=, capped at MAX_ARTICLES; 0 when absent
+func minArticlesParam(query url.Values, cfg *Config) (int, error) {
+	raw := query.Get("minArticles")
+	if raw == "" {
+		return 0, nil
+	}
+	minArticles, err := strconv.Atoi(raw)
+	if err != nil || minArticles < 1 || minArticles > maxPageSize {
+		return 0, fmt.Errorf("minArticles must be an integer between 1 and %d", maxPageSize)
+	}
+	return min(minArticles, cfg.MaxArticles), nil
+}
+
+// Top up a category's headlines that came back with fewer than
+// query.MinArticles from the general feed, skipping articles already present
+// and tagging the added ones as supplemented. If the general feed can't be
+// fetched the category's own articles are returned as they are.
+func supplementHeadlines(ctx context.Context, provider NewsProvider, query HeadlinesQuery, newsResponse *NewsResponse) *NewsResponse {
+	if query.Category == "" || query.Category == "general" || len(newsResponse.Articles) >= query.MinArticles {
+		return newsResponse
+	}
+
+	general, err := provider.TopHeadlines(ctx, HeadlinesQuery{Country: query.Country, PageSize: maxPageSize})
+	if err != nil {
+		slog.WarnContext(ctx, "couldn't fetch general headlines to supplement category", "category", query.Category, "error", err)
+		return newsResponse
+	}
+
+	seen := make(map[string]bool, len(newsResponse.Articles))
+	for _, article := range newsResponse.Articles {
+		seen[article.URL] = true
+	}
+	added := 0
+	for _, article := range general.Articles {
+		if len(newsResponse.Articles) >= query.MinArticles {
+			break
+		}
+		if article.URL != "" && seen[article.URL] {
+			continue
+		}
+		seen[article.URL] = true
+		article.Supplemented = true
+		newsResponse.Articles = append(newsResponse.Articles, article)
+		added++
+	}
+
+	slog.InfoContext(ctx, "supplemented category headlines from general feed", "category", query.Category, "added", added)
+	newsResponse.TotalResults += added
+	newsResponse.Stale = newsResponse.Stale || general.Stale
+	return newsResponse
+}
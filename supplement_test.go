@@ -0,0 +1,97 @@
+package ministry
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// NewsAPI stand-in answering each category with its own articles, counting
+// requests per category ("" for the general feed)
+type categoryNews struct {
+	mu       sync.Mutex
+	articles map[string][]Article
+	calls    map[string]int
+}
+
+func (c *categoryNews) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	category := r.URL.Query().Get("category")
+	c.mu.Lock()
+	c.calls[category]++
+	c.mu.Unlock()
+	newsHandler(c.articles[category]...)(w, r)
+}
+
+func newCategoryNews() *categoryNews {
+	return &categoryNews{
+		calls: make(map[string]int),
+		articles: map[string][]Article{
+			"science": {
+				{Title: "Chocolate ration raised", URL: "https://minitrue.example/chocolate"},
+				{Title: "Synthetic gin improved", URL: "https://minitrue.example/gin"},
+			},
+			"": {
+				{Title: "Chocolate ration raised", URL: "https://minitrue.example/chocolate"},
+				{Title: "Lift repaired", URL: "https://minitrue.example/lift"},
+				{Title: "Victory in Malabar", URL: "https://minitrue.example/malabar"},
+				{Title: "Boot production up", URL: "https://minitrue.example/boots"},
+			},
+		},
+	}
+}
+
+func TestMinArticlesSupplementsFromGeneral(t *testing.T) {
+	news := newCategoryNews()
+	setupTest(t, upstreams(nil, news))
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines?category=science&minArticles=4", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var response NewsResponse
+	decodeJSON(t, rec, &response)
+
+	want := []struct {
+		title        string
+		supplemented bool
+	}{
+		{"Chocolate ration raised", false},
+		{"Synthetic gin improved", false},
+		{"Lift repaired", true},
+		{"Victory in Malabar", true},
+	}
+	if len(response.Articles) != len(want) {
+		t.Fatalf("articles = %+v, want %d", response.Articles, len(want))
+	}
+	for i, article := range response.Articles {
+		if article.Title != want[i].title || article.Supplemented != want[i].supplemented {
+			t.Errorf("article %d = %q (supplemented %v), want %q (%v)", i, article.Title, article.Supplemented, want[i].title, want[i].supplemented)
+		}
+	}
+
+	// The general feed has nothing more to give past its own four
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/news/headlines?category=science&minArticles=10", "")), &response)
+	if len(response.Articles) != 5 {
+		t.Errorf("%d articles, want every distinct one available", len(response.Articles))
+	}
+}
+
+func TestMinArticlesAlreadyMet(t *testing.T) {
+	news := newCategoryNews()
+	setupTest(t, upstreams(nil, news))
+
+	var response NewsResponse
+	decodeJSON(t, do(newRequest(http.MethodGet, "/api/news/headlines?category=science&minArticles=2", "")), &response)
+	if len(response.Articles) != 2 || response.Articles[0].Supplemented || response.Articles[1].Supplemented {
+		t.Errorf("articles = %+v, want the category's own two", response.Articles)
+	}
+	if news.calls[""] != 0 {
+		t.Errorf("general feed fetched %d times, want none", news.calls[""])
+	}
+
+	for _, bad := range []string{"0", "101", "lots"} {
+		if rec := do(newRequest(http.MethodGet, "/api/news/headlines?category=science&minArticles="+bad, "")); rec.Code != http.StatusBadRequest {
+			t.Errorf("minArticles=%s: status = %d, want 400", bad, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package ministry
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Upper bound for the ?limit= query parameter
+const maxTopicLimit = 100
+
+// Common English words never reported as topics
+var defaultTopicStopwords = []string{
+	"a", "about", "after", "all", "also", "an", "and", "any", "are", "as", "at",
+	"be", "been", "before", "but", "by", "can", "could", "did", "do", "does",
+	"for", "from", "had", "has", "have", "he", "her", "his", "how", "if", "in",
+	"into", "is", "it", "its", "more", "new", "no", "not", "now", "of", "on",
+	"or", "our", "over", "says", "she", "so", "than", "that", "the", "their",
+	"them", "there", "they", "this", "to", "up", "was", "we", "were", "what",
+	"when", "which", "who", "will", "with", "would", "you", "your",
+}
+
+// A keyword and how often it appeared
+type Topic struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+// Top keywords across a batch of transformed headlines
+type TopicsResponse struct {
+	Headlines int     `json:"headlines"`
+	Topics    []Topic `json:"topics"`
+}
+
+// Build the stopword set from the defaults plus comma-separated TOPIC_STOPWORDS
+func parseTopicStopwords(raw string) map[string]bool {
+	stopwords := make(map[string]bool, len(defaultTopicStopwords))
+	for _, word := range defaultTopicStopwords {
+		stopwords[word] = true
+	}
+	for _, word := range strings.Split(raw, ",") {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			stopwords[word] = true
+		}
+	}
+	return stopwords
+}
+
+// Count keywords across texts and return the top limit, most frequent first
+// with ties broken alphabetically. Stopwords, bare numbers and words shorter
+// than three letters are ignored.
+func extractTopics(texts []string, stopwords map[string]bool, limit int) []Topic {
+	counts := make(map[string]int)
+	for _, text := range texts {
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\''
+		})
+		for _, word := range words {
+			word = strings.TrimSuffix(strings.Trim(word, "'"), "'s")
+			if len([]rune(word)) < 3 || stopwords[word] {
+				continue
+			}
+			if _, err := strconv.Atoi(word); err == nil {
+				continue
+			}
+			counts[word]++
+		}
+	}
+
+	topics := make([]Topic, 0, len(counts))
+	for term, count := range counts {
+		topics = append(topics, Topic{Term: term, Count: count})
+	}
+	sort.Slice(topics, func(i, j int) bool {
+		if topics[i].Count != topics[j].Count {
+			return topics[i].Count > topics[j].Count
+		}
+		return topics[i].Term < topics[j].Term
+	})
+
+	if len(topics) > limit {
+		topics = topics[:limit]
+	}
+	return topics
+}
+
+// Parse ?limit= for the topics endpoint, defaulting to TOPICS_LIMIT
+func topicLimit(raw string, defaultLimit int) (int, error) {
+	if raw == "" {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > maxTopicLimit {
+		return 0, fmt.Errorf("limit must be an integer between 1 and %d", maxTopicLimit)
+	}
+	return limit, nil
+}
+
+// Most frequent keywords in the transformed top headlines
+func getHeadlineTopics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, err := topicLimit(r.URL.Query().Get("limit"), currentConfig().TopicLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	newsQuery, err := headlinesQuery(r.URL.Query(), currentConfig())
+	if err != nil {
+		http.Error(w, err.Error(), queryErrorStatus(err))
+		return
+	}
+
+	newsResponse, err := fetchTopHeadlines(r.Context(), currentNewsProvider(), newsQuery)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error fetching news", "error", err)
+		http.Error(w, fmt.Sprintf("Error fetching news: %v", err), newsErrorStatus(err))
+		return
+	}
+
+	headlines := transformArticles(r.Context(), newsResponse.Articles, currentConfig().MaxTransformArticles)
+
+	json.NewEncoder(w).Encode(TopicsResponse{
+		Headlines: len(headlines),
+		Topics:    extractTopics(headlines, currentConfig().TopicStopwords, limit),
+	})
+}
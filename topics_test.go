@@ -0,0 +1,59 @@
+package ministry
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestExtractTopics(t *testing.T) {
+	headlines := []string{
+		"Big Brother's plenty doubles chocolate ration",
+		"Chocolate ration celebrated by grateful proles",
+		"The Party announces 2024 plenty for proles",
+		"Big Brother thanks the proles",
+	}
+	got := extractTopics(headlines, parseTopicStopwords(""), 4)
+	want := []Topic{{"proles", 3}, {"big", 2}, {"brother", 2}, {"chocolate", 2}}
+	if !slices.Equal(got, want) {
+		t.Errorf("extractTopics = %+v, want %+v", got, want)
+	}
+
+	// Extra stopwords are dropped alongside the defaults
+	got = extractTopics(headlines, parseTopicStopwords("Proles, big"), 2)
+	want = []Topic{{"brother", 2}, {"chocolate", 2}}
+	if !slices.Equal(got, want) {
+		t.Errorf("extractTopics with TOPIC_STOPWORDS = %+v, want %+v", got, want)
+	}
+}
+
+func TestTopicLimit(t *testing.T) {
+	if limit, err := topicLimit("", 10); err != nil || limit != 10 {
+		t.Errorf("topicLimit(\"\") = %d, %v; want the default", limit, err)
+	}
+	for _, raw := range []string{"0", "101", "many"} {
+		if _, err := topicLimit(raw, 10); err == nil {
+			t.Errorf("topicLimit(%q) succeeded, want an error", raw)
+		}
+	}
+}
+
+func TestHeadlineTopicsEndpoint(t *testing.T) {
+	openAI := newFakeOpenAI("Plenty ration for grateful proles")
+	setupTest(t, upstreams(openAI, newsHandler(Article{Title: "Lift broken again"}, Article{Title: "Rations cut"})), "TOPICS_LIMIT", "2")
+
+	rec := do(newRequest(http.MethodGet, "/api/news/headlines/topics", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var response TopicsResponse
+	decodeJSON(t, rec, &response)
+	want := []Topic{{"grateful", 2}, {"plenty", 2}}
+	if response.Headlines != 2 || !slices.Equal(response.Topics, want) {
+		t.Errorf("response = %+v, want topics %+v", response, want)
+	}
+
+	if rec := do(newRequest(http.MethodGet, "/api/news/headlines/topics?limit=0", "")); rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d for limit=0, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,267 @@
+package ministry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Number of headlines transformed concurrently by transform-all
+const transformAllWorkers = 4
+
+// Per-article outcomes of a transform-all run
+const (
+	transformSucceeded = "succeeded"
+	transformFailed    = "failed"
+	transformSkipped   = "skipped"
+	transformLimited   = "limited"
+)
+
+// Outcome for one article of the feed
+type TransformAllItem struct {
+	URL                string `json:"url"`
+	Title              string `json:"title"`
+	Status             string `json:"status"`
+	Transformed        bool   `json:"transformed"`
+	TransformedContent string `json:"transformedContent,omitempty"`
+	OriginalContent    string `json:"originalContent,omitempty"`
+	Error              string `json:"error,omitempty"`
+}
+
+// Counts of each outcome across the feed
+type TransformAllSummary struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+	Limited   int `json:"limited"`
+}
+
+// Last line of an NDJSON transform-all stream
+type TransformAllSummaryLine struct {
+	Status  string              `json:"status"`
+	Summary TransformAllSummary `json:"summary"`
+}
+
+// Whether a transform-all client asked for results streamed as NDJSON
+func wantsNDJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/x-ndjson")
+}
+
+// Writes one JSON object per line, flushing each as it goes. Safe for
+// concurrent use; after a failed write (the client went away) the rest are dropped.
+type ndjsonStream struct {
+	mu     sync.Mutex
+	w      http.ResponseWriter
+	enc    *json.Encoder
+	failed bool
+}
+
+func newNDJSONStream(w http.ResponseWriter) *ndjsonStream {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	return &ndjsonStream{w: w, enc: json.NewEncoder(w)}
+}
+
+func (s *ndjsonStream) Send(v any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failed {
+		return
+	}
+	if err := s.enc.Encode(v); err != nil {
+		s.failed = true
+		return
+	}
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Result of transforming the whole feed; status is "completed" when every
+// article within the limit succeeded and "partial" otherwise
+type TransformAllResponse struct {
+	Status  string              `json:"status"`
+	Summary TransformAllSummary `json:"summary"`
+	Items   []TransformAllItem  `json:"items"`
+}
+
+// Transform the first limit articles (all of them when limit is 0) with a
+// bounded worker pool until ctx is done. Articles not started, or cut off,
+// before then are reported as skipped; those past the limit keep their
+// original content and are reported as limited. When report is set it gets
+// each item as soon as it finishes, and the skipped and limited ones at the end.
+func transformAll(ctx context.Context, articles []Article, limit int, report func(item TransformAllItem), transform func(ctx context.Context, article Article) (string, error)) TransformAllResponse {
+	if limit <= 0 || limit > len(articles) {
+		limit = len(articles)
+	}
+
+	items := make([]TransformAllItem, len(articles))
+	for i, article := range articles {
+		items[i] = TransformAllItem{URL: article.URL, Title: article.Title, Status: transformSkipped}
+		if i >= limit {
+			items[i].Status = transformLimited
+			items[i].OriginalContent = article.Description
+		}
+	}
+
+	reported := make([]bool, len(articles))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < transformAllWorkers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				content, err := transform(ctx, articles[i])
+				switch {
+				case err == nil:
+					items[i].Status = transformSucceeded
+					items[i].Transformed = true
+					items[i].TransformedContent = content
+				case ctx.Err() != nil:
+					// Ran out of budget mid-call; leave it skipped
+					continue
+				default:
+					slog.WarnContext(ctx, "transform-all item failed", "url", articles[i].URL, "error", err)
+					_, message := transformErrorStatus(err)
+					items[i].Status = transformFailed
+					items[i].Error = message
+				}
+				if report != nil {
+					report(items[i])
+					reported[i] = true
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range limit {
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, item := range items {
+		if report != nil && !reported[i] {
+			report(item)
+		}
+	}
+
+	response := TransformAllResponse{Status: "completed", Summary: TransformAllSummary{Total: len(items)}, Items: items}
+	for _, item := range items {
+		switch item.Status {
+		case transformSucceeded:
+			response.Summary.Succeeded++
+		case transformFailed:
+			response.Summary.Failed++
+		case transformLimited:
+			response.Summary.Limited++
+		default:
+			response.Summary.Skipped++
+		}
+	}
+	if response.Summary.Succeeded < response.Summary.Total-response.Summary.Limited {
+		response.Status = "partial"
+	}
+	return response
+}
+
+// Parse ?limit= for transform-all: a positive count capped at ceiling, or
+// the ceiling itself when absent (0 means no cap)
+func transformLimitParam(raw string, ceiling int) (int, error) {
+	if raw == "" {
+		return ceiling, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("limit must be a positive integer, got %q", raw)
+	}
+	if ceiling > 0 {
+		limit = min(limit, ceiling)
+	}
+	return limit, nil
+}
+
+// Transform the current top headlines in one go, within TRANSFORM_ALL_BUDGET
+func transformAllHeadlines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	newsQuery, err := headlinesQuery(r.URL.Query(), currentConfig())
+	if err != nil {
+		http.Error(w, err.Error(), queryErrorStatus(err))
+		return
+	}
+
+	limit, err := transformLimitParam(r.URL.Query().Get("limit"), currentConfig().MaxTransformArticles)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	newsResponse, err := fetchTopHeadlines(r.Context(), currentNewsProvider(), newsQuery)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error fetching news", "error", err)
+		http.Error(w, fmt.Sprintf("Error fetching news: %v", err), newsErrorStatus(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), currentConfig().TransformAllBudget)
+	defer cancel()
+
+	// Same options the transform endpoint fills in for a bare request
+	options := TransformOptions{Intensity: defaultIntensity, Persona: defaultPersona}
+	ctx = withTransformOptions(ctx, options)
+
+	// Accept: application/x-ndjson streams each article as it finishes
+	var stream *ndjsonStream
+	var report func(item TransformAllItem)
+	if wantsNDJSON(r) {
+		stream = newNDJSONStream(w)
+		report = func(item TransformAllItem) { stream.Send(item) }
+	}
+
+	response := transformAll(ctx, newsResponse.Articles, limit, report, func(ctx context.Context, article Article) (string, error) {
+		key := transformCacheKey(currentConfig().LLMProvider, options, article.Title, article.Description)
+		cached, ok := getCachedTransform(ctx, key)
+		content := cached.Content
+		if !ok {
+			recorder := &usageRecorder{}
+			var err error
+			content, err = transformOnce(withUsageRecorder(ctx, recorder), key, article.Title, article.Description)
+			trackUsage(recorder, currentConfig().ModelPricing)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		// The same output limits and screening as /api/transform; a rejected
+		// transform fails its article
+		content, _ = truncateText(content, currentConfig().TransformMaxChars)
+		return currentConfig().Profanity.Apply(content)
+	})
+
+	slog.InfoContext(r.Context(), "transform-all finished", "succeeded", response.Summary.Succeeded, "failed", response.Summary.Failed, "skipped", response.Summary.Skipped)
+	if stream != nil {
+		stream.Send(TransformAllSummaryLine{Status: response.Status, Summary: response.Summary})
+		return
+	}
+	json.NewEncoder(w).Encode(response)
+}
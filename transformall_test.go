@@ -0,0 +1,406 @@
+package ministry
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Articles titled "Headline 0" to "Headline n-1"
+func numberedArticles(n int) []Article {
+	articles := make([]Article, n)
+	for i := range articles {
+		articles[i] = Article{Title: fmt.Sprintf("Headline %d", i), Description: fmt.Sprintf("Story %d", i), URL: fmt.Sprintf("https://minitrue.example/%d", i)}
+	}
+	return articles
+}
+
+func TestTransformAll(t *testing.T) {
+	articles := numberedArticles(10)
+	var inFlight, peak atomic.Int32
+	response := transformAll(context.Background(), articles, 0, nil, func(ctx context.Context, article Article) (string, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return "Glorious " + article.Title, nil
+	})
+
+	if response.Status != "completed" || response.Summary != (TransformAllSummary{Total: 10, Succeeded: 10}) {
+		t.Errorf("status %s, summary %+v", response.Status, response.Summary)
+	}
+	for i, item := range response.Items {
+		if item.Status != transformSucceeded || !item.Transformed || item.TransformedContent != "Glorious "+articles[i].Title || item.URL != articles[i].URL {
+			t.Errorf("item %d = %+v", i, item)
+		}
+	}
+	if peak.Load() > transformAllWorkers {
+		t.Errorf("%d transforms ran at once, want at most %d", peak.Load(), transformAllWorkers)
+	}
+}
+
+func TestTransformAllPartialFailure(t *testing.T) {
+	response := transformAll(context.Background(), numberedArticles(4), 0, nil, func(ctx context.Context, article Article) (string, error) {
+		if article.Title == "Headline 2" {
+			return "", fmt.Errorf("OpenAI: %w", ErrNoCompletion)
+		}
+		return "Glorious", nil
+	})
+
+	if response.Status != "partial" || response.Summary != (TransformAllSummary{Total: 4, Succeeded: 3, Failed: 1}) {
+		t.Errorf("status %s, summary %+v", response.Status, response.Summary)
+	}
+	if item := response.Items[2]; item.Status != transformFailed || item.Transformed || item.Error != "Model returned no completion" {
+		t.Errorf("failed item = %+v", item)
+	}
+}
+
+func TestTransformAllBudgetExhausted(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	response := transformAll(ctx, numberedArticles(12), 0, nil, func(ctx context.Context, article Article) (string, error) {
+		if n, _ := strconv.Atoi(strings.TrimPrefix(article.Title, "Headline ")); n < 4 {
+			return "Glorious", nil
+		}
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+
+	if response.Status != "partial" || response.Summary != (TransformAllSummary{Total: 12, Succeeded: 4, Skipped: 8}) {
+		t.Errorf("status %s, summary %+v", response.Status, response.Summary)
+	}
+	for _, item := range response.Items[4:] {
+		if item.Status != transformSkipped || item.Error != "" {
+			t.Errorf("item after the budget = %+v, want skipped", item)
+		}
+	}
+}
+
+func TestTransformAllHeadlinesEndpoint(t *testing.T) {
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		if strings.Contains(req.Messages[1].Content, "Headline 1") {
+			http.Error(w, "overloaded", http.StatusInternalServerError)
+			return
+		}
+		writeCompletion(w, req.Model, "Glorious news", "stop")
+	}
+	setupTest(t, upstreams(openAI, newFakeNews(numberedArticles(3)...)))
+
+	if rec := do(newRequest(http.MethodGet, "/api/news/headlines/transform-all", "")); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want 405", rec.Code)
+	}
+
+	rec := do(newRequest(http.MethodPost, "/api/news/headlines/transform-all", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var response TransformAllResponse
+	decodeJSON(t, rec, &response)
+	if response.Status != "partial" || response.Summary != (TransformAllSummary{Total: 3, Succeeded: 2, Failed: 1}) {
+		t.Errorf("status %s, summary %+v", response.Status, response.Summary)
+	}
+	if item := response.Items[1]; item.Status != transformFailed || item.Error == "" {
+		t.Errorf("item 1 = %+v, want failed", item)
+	}
+	if item := response.Items[0]; item.TransformedContent != "Glorious news" {
+		t.Errorf("item 0 = %+v", item)
+	}
+}
+
+func TestTransformAllHeadlinesPostProcessing(t *testing.T) {
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		if strings.Contains(req.Messages[1].Content, "Headline 1") {
+			writeCompletion(w, req.Model, "Goldstein is a bastard", "stop")
+			return
+		}
+		writeCompletion(w, req.Model, "Glorious news from the front", "stop")
+	}
+	setupTest(t, upstreams(openAI, newFakeNews(numberedArticles(2)...)), "PROFANITY_MODE", "reject", "TRANSFORM_MAX_CHARS", "24")
+	before := usageTracker.Summary()
+
+	var response TransformAllResponse
+	decodeJSON(t, do(newRequest(http.MethodPost, "/api/news/headlines/transform-all", "")), &response)
+	if item := response.Items[0]; item.Status != transformSucceeded || item.TransformedContent != "Glorious news from the…" {
+		t.Errorf("item 0 = %+v, want truncated to TRANSFORM_MAX_CHARS", item)
+	}
+	if item := response.Items[1]; item.Status != transformFailed || strings.Contains(item.TransformedContent, "bastard") {
+		t.Errorf("item 1 = %+v, want rejected by the profanity filter", item)
+	}
+	if calls := usageTracker.Summary().Calls - before.Calls; calls != 2 {
+		t.Errorf("usage totals gained %d calls, want 2", calls)
+	}
+}
+
+func TestTransformAllHeadlinesBudget(t *testing.T) {
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		time.Sleep(300 * time.Millisecond)
+		writeCompletion(w, req.Model, "too late", "stop")
+	}
+	setupTest(t, upstreams(openAI, newFakeNews(numberedArticles(2)...)), "TRANSFORM_ALL_BUDGET", "50ms")
+
+	var response TransformAllResponse
+	decodeJSON(t, do(newRequest(http.MethodPost, "/api/news/headlines/transform-all", "")), &response)
+	if response.Status != "partial" || response.Summary != (TransformAllSummary{Total: 2, Skipped: 2}) {
+		t.Errorf("status %s, summary %+v", response.Status, response.Summary)
+	}
+}
+
+func TestLoadConfigRejectsBadTransformAllBudget(t *testing.T) {
+	t.Setenv("NEWS_API_KEY", "test-news-key")
+	t.Setenv("OPENAI_API_KEY", "test-openai-key")
+
+	for _, raw := range []string{"soon", "0s", "-1s"} {
+		t.Setenv("TRANSFORM_ALL_BUDGET", raw)
+		if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "TRANSFORM_ALL_BUDGET") {
+			t.Errorf("TRANSFORM_ALL_BUDGET=%s: error = %v", raw, err)
+		}
+	}
+}
+
+func TestTransformLimitParam(t *testing.T) {
+	tests := []struct {
+		raw     string
+		ceiling int
+		want    int
+	}{
+		{"", 0, 0},
+		{"", 5, 5},
+		{"3", 0, 3},
+		{"3", 5, 3},
+		{"9", 5, 5},
+	}
+	for _, test := range tests {
+		if got, err := transformLimitParam(test.raw, test.ceiling); err != nil || got != test.want {
+			t.Errorf("transformLimitParam(%q, %d) = %d, %v; want %d", test.raw, test.ceiling, got, err, test.want)
+		}
+	}
+	for _, raw := range []string{"0", "-1", "all", "2.5"} {
+		if _, err := transformLimitParam(raw, 0); err == nil {
+			t.Errorf("transformLimitParam(%q) accepted", raw)
+		}
+	}
+}
+
+func TestTransformAllLimit(t *testing.T) {
+	var calls atomic.Int32
+	response := transformAll(context.Background(), numberedArticles(5), 2, nil, func(ctx context.Context, article Article) (string, error) {
+		calls.Add(1)
+		return "Glorious " + article.Title, nil
+	})
+
+	if calls.Load() != 2 || response.Status != "completed" || response.Summary != (TransformAllSummary{Total: 5, Succeeded: 2, Limited: 3}) {
+		t.Errorf("%d calls, status %s, summary %+v", calls.Load(), response.Status, response.Summary)
+	}
+	for i, item := range response.Items {
+		if i < 2 && (!item.Transformed || item.TransformedContent != "Glorious Headline "+strconv.Itoa(i)) {
+			t.Errorf("item %d = %+v, want transformed", i, item)
+		}
+		if i >= 2 && (item.Transformed || item.Status != transformLimited || item.OriginalContent != "Story "+strconv.Itoa(i) || item.TransformedContent != "") {
+			t.Errorf("item %d = %+v, want limited with its original content", i, item)
+		}
+	}
+}
+
+func TestTransformAllHeadlinesLimit(t *testing.T) {
+	t.Run("limit parameter", func(t *testing.T) {
+		openAI := newFakeOpenAI("Glorious news")
+		setupTest(t, upstreams(openAI, newFakeNews(numberedArticles(4)...)))
+
+		var response TransformAllResponse
+		decodeJSON(t, do(newRequest(http.MethodPost, "/api/news/headlines/transform-all?limit=1", "")), &response)
+		if openAI.Calls() != 1 || response.Summary != (TransformAllSummary{Total: 4, Succeeded: 1, Limited: 3}) {
+			t.Errorf("%d model calls, summary %+v; want one transform", openAI.Calls(), response.Summary)
+		}
+		if item := response.Items[3]; item.Transformed || item.OriginalContent != "Story 3" {
+			t.Errorf("item 3 = %+v, want its original content untransformed", item)
+		}
+	})
+
+	t.Run("MAX_TRANSFORM_ARTICLES ceiling", func(t *testing.T) {
+		openAI := newFakeOpenAI("Glorious news")
+		setupTest(t, upstreams(openAI, newFakeNews(numberedArticles(4)...)), "MAX_TRANSFORM_ARTICLES", "2")
+
+		var response TransformAllResponse
+		decodeJSON(t, do(newRequest(http.MethodPost, "/api/news/headlines/transform-all?limit=10", "")), &response)
+		if openAI.Calls() != 2 || response.Summary.Limited != 2 {
+			t.Errorf("%d model calls, summary %+v; want the ceiling applied", openAI.Calls(), response.Summary)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		openAI := newFakeOpenAI("unused")
+		setupTest(t, upstreams(openAI, newFakeNews(numberedArticles(4)...)))
+
+		if rec := do(newRequest(http.MethodPost, "/api/news/headlines/transform-all?limit=0", "")); rec.Code != http.StatusBadRequest || openAI.Calls() != 0 {
+			t.Errorf("status = %d after %d model calls, want 400 before any transform", rec.Code, openAI.Calls())
+		}
+	})
+
+	t.Run("config", func(t *testing.T) {
+		setupTest(t, nil)
+		t.Setenv("MAX_TRANSFORM_ARTICLES", "-1")
+		if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "MAX_TRANSFORM_ARTICLES") {
+			t.Errorf("loadConfig error = %v", err)
+		}
+	})
+}
+
+// Decoded NDJSON lines of a transform-all stream
+func ndjsonLines(t *testing.T, body string) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		var v map[string]any
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			t.Fatalf("line %q isn't JSON: %v", line, err)
+		}
+		lines = append(lines, v)
+	}
+	return lines
+}
+
+func TestTransformAllNDJSON(t *testing.T) {
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		if strings.Contains(req.Messages[1].Content, "Headline 1") {
+			http.Error(w, "overloaded", http.StatusInternalServerError)
+			return
+		}
+		writeCompletion(w, req.Model, "Glorious news", "stop")
+	}
+	setupTest(t, upstreams(openAI, newFakeNews(numberedArticles(4)...)))
+
+	rec := do(newRequest(http.MethodPost, "/api/news/headlines/transform-all?limit=3", "", "Accept", "application/x-ndjson"))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("status = %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	lines := ndjsonLines(t, rec.Body.String())
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, want one per article and the summary: %q", len(lines), rec.Body.String())
+	}
+	statuses := map[string]string{}
+	for _, line := range lines[:4] {
+		statuses[line["title"].(string)] = line["status"].(string)
+	}
+	want := map[string]string{"Headline 0": transformSucceeded, "Headline 1": transformFailed, "Headline 2": transformSucceeded, "Headline 3": transformLimited}
+	if !maps.Equal(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	if lines[3]["status"] != transformLimited {
+		t.Errorf("line 3 = %v, want the limited article after the transformed ones", lines[3])
+	}
+
+	summary := lines[4]
+	counts, _ := summary["summary"].(map[string]any)
+	if summary["status"] != "partial" || counts["total"] != float64(4) || counts["succeeded"] != float64(2) || counts["failed"] != float64(1) || counts["limited"] != float64(1) {
+		t.Errorf("summary line = %v", summary)
+	}
+}
+
+func TestTransformAllNDJSONStreamsAsItGoes(t *testing.T) {
+	// Buffered so releasing never blocks, even once the handler has returned
+	release := make(chan struct{}, 1)
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		if strings.Contains(req.Messages[1].Content, "Headline 1") {
+			<-release
+		}
+		writeCompletion(w, req.Model, "Glorious news", "stop")
+	}
+	setupTest(t, upstreams(openAI, newFakeNews(numberedArticles(2)...)))
+	server := httptest.NewServer(newRouter())
+	defer server.Close()
+	defer close(release)
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/news/headlines/transform-all", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	// Not http.DefaultClient: setupTest points its transport at the fake upstream
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The quick article arrives while the slow one is still in the model
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	select {
+	case line := <-lines:
+		if !strings.Contains(line, `"title":"Headline 0"`) {
+			t.Errorf("first line = %q, want Headline 0", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no line streamed before the slow transform finished")
+	}
+
+	release <- struct{}{}
+	var rest []string
+	for line := range lines {
+		rest = append(rest, line)
+	}
+	if len(rest) != 2 || !strings.Contains(rest[0], "Headline 1") || !strings.Contains(rest[1], `"summary"`) {
+		t.Errorf("remaining lines = %q, want Headline 1 then the summary", rest)
+	}
+}
+
+// ResponseWriter whose writes fail, like one whose client has gone
+type brokenWriter struct {
+	httptest.ResponseRecorder
+	writes int
+}
+
+func (b *brokenWriter) Write(p []byte) (int, error) {
+	b.writes++
+	return 0, errors.New("broken pipe")
+}
+
+func TestNDJSONStreamStopsAfterFailedWrite(t *testing.T) {
+	w := &brokenWriter{ResponseRecorder: *httptest.NewRecorder()}
+	stream := newNDJSONStream(w)
+	stream.Send(TransformAllItem{Title: "Headline 0"})
+	stream.Send(TransformAllItem{Title: "Headline 1"})
+	if w.writes != 1 {
+		t.Errorf("%d writes, want the rest dropped after the first failure", w.writes)
+	}
+}
+
+func TestTransformAllStopsWhenClientLeaves(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	response := transformAll(ctx, numberedArticles(12), 0, nil, func(ctx context.Context, article Article) (string, error) {
+		if calls.Add(1) == 1 {
+			cancel()
+		}
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	if calls.Load() > transformAllWorkers || response.Summary.Skipped < 12-transformAllWorkers {
+		t.Errorf("%d transforms started, summary %+v; want the rest skipped once the request is cancelled", calls.Load(), response.Summary)
+	}
+}
@@ -0,0 +1,685 @@
+package ministry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Turns a news title/description into Ministry of Truth propaganda
+type Transformer interface {
+	Transform(ctx context.Context, title, description string) (string, error)
+}
+
+// Transform failures that callers can tell apart
+var (
+	ErrNoCompletion   = errors.New("model returned no completion")
+	ErrBadCompletion  = errors.New("model returned an unreadable completion")
+	ErrUpstreamStatus = errors.New("model API returned an error status")
+
+	ErrDryRunUnsupported = errors.New("dry run is not supported by this provider")
+
+	ErrTranslationUnsupported = errors.New("translation is not supported by this provider")
+)
+
+// Returned when the model API is still rate limiting once the retry budget is spent
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("model API rate limited, retry after %s", e.RetryAfter)
+}
+
+// Most 429 retries made for one request, whatever the budget
+const maxRateLimitRetries = 3
+
+// Wait used when a 429 carries no usable Retry-After
+const defaultRetryAfter = time.Second
+
+// Parse a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// Write a failed transform as its mapped status, passing on the upstream
+// retry delay when the model API rate limited us
+func writeTransformError(w http.ResponseWriter, err error) {
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))))
+	}
+	status, message := transformErrorStatus(err)
+	http.Error(w, message, status)
+}
+
+// HTTP status and client message for a failed transform: 504 when the
+// upstream call timed out, 503 while the circuit breaker is open, when no
+// concurrency slot freed up in time or when the transform queue is full,
+// 429 when rate limited past the retry budget, 502 when the model API
+// misbehaved, else 500
+func transformErrorStatus(err error) (int, string) {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return http.StatusGatewayTimeout, "Timed out waiting for the model"
+	case errors.Is(err, ErrCircuitOpen):
+		return http.StatusServiceUnavailable, "Model temporarily unavailable"
+	case errors.Is(err, ErrConcurrencyLimit):
+		return http.StatusServiceUnavailable, "Too many concurrent model requests, retry later"
+	case errors.Is(err, ErrQueueFull):
+		return http.StatusServiceUnavailable, "Too many transforms queued, retry later"
+	case errors.As(err, new(*RateLimitError)):
+		return http.StatusTooManyRequests, "Model API rate limit reached, retry later"
+	case errors.Is(err, ErrNoCompletion):
+		return http.StatusBadGateway, "Model returned no completion"
+	case errors.Is(err, ErrBadCompletion), errors.Is(err, ErrUpstreamStatus):
+		return http.StatusBadGateway, "Model API error"
+	default:
+		return http.StatusInternalServerError, "Error transforming content"
+	}
+}
+
+// Active transformer, selected by LLM_PROVIDER and rebuilt on config reload
+var activeTransformer atomic.Pointer[Transformer]
+
+func currentTransformer() Transformer {
+	return *activeTransformer.Load()
+}
+
+func setTransformer(t Transformer) {
+	activeTransformer.Store(&t)
+}
+
+// Transformer for cfg, behind llmBreaker when the circuit breaker is enabled
+func buildTransformer(cfg *Config) Transformer {
+	t := newTransformer(cfg)
+	if llmBreaker != nil {
+		t = &BreakerTransformer{Transformer: t, Breaker: llmBreaker}
+	}
+	return t
+}
+
+// Intensity used when a request doesn't ask for one
+const defaultIntensity = "high"
+
+// Tone instruction appended to the persona prompt, and sampling temperature,
+// for a propaganda intensity level
+type intensityProfile struct {
+	Instruction string  `json:"instruction"`
+	Temperature float64 `json:"temperature"`
+}
+
+var intensityProfiles = map[string]intensityProfile{
+	"low": {
+		Instruction: "Keep the tone gentle: light, tongue-in-cheek satire with only the occasional nod to the Party.",
+		Temperature: 0.5,
+	},
+	"medium": {
+		Instruction: "Keep the tone measured: Party-approved doublespeak without going over the top.",
+		Temperature: 0.7,
+	},
+	defaultIntensity: {Temperature: 0.9},
+}
+
+// Per-request transform settings carried on the context
+type TransformOptions struct {
+	Intensity        string `json:"intensity,omitempty"`
+	Persona          string `json:"persona,omitempty"`
+	PreserveEntities bool   `json:"preserveEntities,omitempty"`
+	Seed             *int64 `json:"seed,omitempty"`
+	Language         string `json:"language,omitempty"`
+}
+
+type transformOptionsKey struct{}
+
+func withTransformOptions(ctx context.Context, options TransformOptions) context.Context {
+	return context.WithValue(ctx, transformOptionsKey{}, options)
+}
+
+// Options attached to ctx, or the zero value for defaults
+func transformOptionsFrom(ctx context.Context) TransformOptions {
+	options, _ := ctx.Value(transformOptionsKey{}).(TransformOptions)
+	return options
+}
+
+// System prompt and temperature for the persona and intensity requested on ctx
+func promptFor(ctx context.Context) (string, float64) {
+	options := transformOptionsFrom(ctx)
+	templates := currentPrompts()
+
+	persona := options.Persona
+	prompt, ok := templates.Personas[persona]
+	if !ok {
+		persona = defaultPersona
+		prompt = templates.Personas[persona]
+	}
+	if localized, ok := localizedPersonas[options.Language][persona]; ok {
+		prompt = localized
+	}
+
+	profile, ok := templates.Intensities[options.Intensity]
+	if !ok {
+		profile = templates.Intensities[defaultIntensity]
+	}
+	if profile.Instruction != "" {
+		prompt += " " + profile.Instruction
+	}
+	if options.PreserveEntities {
+		prompt += " " + preserveEntitiesInstruction
+	}
+	prompt += " " + untrustedInputInstruction
+
+	return prompt, profile.Temperature
+}
+
+// User message sent to the model for a piece of news: sanitized and fenced
+// off from the instructions in <news> tags
+func transformPrompt(title, description string) string {
+	return fmt.Sprintf("Transform this news.\n<news>\nTitle: %s\nDescription: %s\n</news>",
+		strings.TrimSpace(sanitizeNewsText(title)), strings.TrimSpace(sanitizeNewsText(description)))
+}
+
+// Cut text to at most max characters on a word boundary, ending with an
+// ellipsis; reports whether anything was removed. max <= 0 disables the limit.
+func truncateText(text string, max int) (string, bool) {
+	runes := []rune(strings.TrimSpace(text))
+	if max <= 0 || len(runes) <= max {
+		return string(runes), false
+	}
+	if max == 1 {
+		return "…", true
+	}
+
+	// Leave room for the ellipsis, then back up to the last space when that splits a word
+	cut := runes[:max-1]
+	if !wordEndsAt(runes, max-1) {
+		if i := strings.LastIndexFunc(string(cut), unicode.IsSpace); i > 0 {
+			cut = []rune(string(cut)[:i])
+		}
+	}
+	return strings.TrimRightFunc(string(cut), isSpaceOrPunct) + "…", true
+}
+
+func isSpaceOrPunct(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r)
+}
+
+// Whether a word ends before runes[i], i.e. only trailing punctuation sits between there and
+// the next space
+func wordEndsAt(runes []rune, i int) bool {
+	for ; i < len(runes); i++ {
+		if unicode.IsSpace(runes[i]) {
+			return true
+		}
+		if !unicode.IsPunct(runes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Pick the transformer implementation for the configured provider
+func newTransformer(cfg *Config) Transformer {
+	switch cfg.LLMProvider {
+	case "anthropic":
+		return &AnthropicTransformer{APIKey: cfg.AnthropicAPIKey, Client: &http.Client{Transport: upstreamTransport}, UserAgent: cfg.UserAgent}
+	default:
+		return &OpenAITransformer{APIKey: cfg.OpenAIAPIKey, BaseURL: cfg.OpenAIBaseURL, Client: &http.Client{Transport: upstreamTransport}, RetryBudget: cfg.OpenAIRetryBudget, UserAgent: cfg.UserAgent, Limiter: openAILimiter, LogBodies: cfg.DebugLogBodies, Redact: []string{cfg.OpenAIAPIKey, cfg.NewsAPIKey}, OrgID: cfg.OpenAIOrgID, ProjectID: cfg.OpenAIProjectID, Models: cfg.OpenAIModelChain}
+	}
+}
+
+// Transformer backed by the OpenAI chat completions API
+type OpenAITransformer struct {
+	APIKey  string
+	BaseURL string // e.g. https://api.openai.com/v1, or a proxy/Azure endpoint
+	Client  *http.Client
+
+	// Total time to spend waiting out 429s before giving up (0 disables retries)
+	RetryBudget time.Duration
+
+	// Caps in-flight requests across every caller (nil means unlimited)
+	Limiter *Semaphore
+
+	UserAgent string
+
+	// Log request and response bodies at debug level with Redact masked out
+	LogBodies bool
+	Redact    []string
+
+	// Sent as OpenAI-Organization and OpenAI-Project when set
+	OrgID     string
+	ProjectID string
+
+	// Models tried in order until one returns a completion (default gpt-3.5-turbo)
+	Models []string
+}
+
+// Model used when OPENAI_MODEL_CHAIN is unset
+const defaultOpenAIModel = "gpt-3.5-turbo"
+
+// Models to try, in order
+func (t *OpenAITransformer) models() []string {
+	if len(t.Models) == 0 {
+		return []string{defaultOpenAIModel}
+	}
+	return t.Models
+}
+
+// Upstream request a transform would send, returned instead of calling the model
+type DryRunResult struct {
+	DryRun                bool   `json:"dryRun"`
+	Provider              string `json:"provider"`
+	Request               any    `json:"request"`
+	EstimatedPromptTokens int    `json:"estimatedPromptTokens"`
+}
+
+// Transformers that can describe their upstream request without sending it
+type DryRunner interface {
+	DryRun(ctx context.Context, title, description string) (DryRunResult, error)
+}
+
+// Rough prompt token count (about four characters per token, plus a few
+// tokens of overhead per message)
+func estimatePromptTokens(system string, messages []Message) int {
+	chars := utf8.RuneCountInString(system)
+	overhead := 0
+	if system != "" {
+		overhead += 4
+	}
+	for _, message := range messages {
+		chars += utf8.RuneCountInString(message.Content)
+		overhead += 4
+	}
+	return (chars+3)/4 + overhead
+}
+
+// Transformers that can emit output progressively as it is generated
+type StreamTransformer interface {
+	TransformStream(ctx context.Context, title, description string, onToken func(token string) error) error
+}
+
+// Chat completion request for a piece of news
+func (t *OpenAITransformer) buildRequest(ctx context.Context, title, description string) OpenAIRequest {
+	prompt, temperature := promptFor(ctx)
+	openAIRequest := OpenAIRequest{
+		Model: t.models()[0],
+		Messages: []Message{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: transformPrompt(title, description)},
+		},
+		MaxTokens:   200,
+		Temperature: temperature,
+	}
+
+	// OpenAI seeds its sampling, so the intensity's temperature (and any
+	// PROMPTS_FILE override) still applies with a seed set
+	openAIRequest.Seed = transformOptionsFrom(ctx).Seed
+	return openAIRequest
+}
+
+func (t *OpenAITransformer) DryRun(ctx context.Context, title, description string) (DryRunResult, error) {
+	openAIRequest := t.buildRequest(ctx, title, description)
+	return DryRunResult{
+		Provider:              "openai",
+		Request:               openAIRequest,
+		EstimatedPromptTokens: estimatePromptTokens("", openAIRequest.Messages),
+	}, nil
+}
+
+// POST a chat completion request, returning the response only when it
+// succeeded. 429s are retried after their Retry-After while RetryBudget lasts.
+func (t *OpenAITransformer) send(ctx context.Context, openAIRequest OpenAIRequest) (*http.Response, error) {
+	jsonData, err := json.Marshal(openAIRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	if err := t.Limiter.Acquire(ctx); err != nil {
+		slog.WarnContext(ctx, "OpenAI concurrency limit reached", "error", err)
+		return nil, err
+	}
+	resp, err := t.post(ctx, jsonData)
+	llmHealth.Record(err)
+	if err != nil {
+		t.Limiter.Release()
+		return nil, err
+	}
+	resp.Body = t.Limiter.releaseOnClose(resp.Body)
+	return resp, nil
+}
+
+// POST a chat completion, waiting out 429s within RetryBudget
+func (t *OpenAITransformer) post(ctx context.Context, jsonData []byte) (*http.Response, error) {
+	var waited time.Duration
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", t.BaseURL+"/chat/completions", bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %v", err)
+		}
+
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", t.APIKey))
+		req.Header.Set("Content-Type", "application/json")
+		if t.OrgID != "" {
+			req.Header.Set("OpenAI-Organization", t.OrgID)
+		}
+		if t.ProjectID != "" {
+			req.Header.Set("OpenAI-Project", t.ProjectID)
+		}
+		setUserAgent(req, t.UserAgent)
+
+		if t.LogBodies {
+			slog.DebugContext(ctx, "OpenAI request body", "body", redactSecrets(string(jsonData), t.Redact))
+		}
+
+		resp, err := t.Client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to make request to OpenAI: %w", err)
+		}
+		if t.LogBodies {
+			resp.Body = &loggedBody{ReadCloser: resp.Body, ctx: ctx, message: "OpenAI response body", redact: t.Redact}
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests {
+			return t.checkStatus(ctx, resp)
+		}
+		resp.Body.Close()
+
+		retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			retryAfter = defaultRetryAfter
+		}
+		if attempt >= maxRateLimitRetries || waited+retryAfter > t.RetryBudget {
+			slog.WarnContext(ctx, "OpenAI rate limit retry budget exhausted", "retryAfter", retryAfter, "waited", waited)
+			return nil, &RateLimitError{RetryAfter: retryAfter}
+		}
+
+		slog.InfoContext(ctx, "OpenAI rate limited, retrying", "retryAfter", retryAfter, "attempt", attempt+1)
+		timer := time.NewTimer(retryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("failed to make request to OpenAI: %w", ctx.Err())
+		case <-timer.C:
+		}
+		waited += retryAfter
+	}
+}
+
+// Pass through a successful response, closing and reporting any other status
+func (t *OpenAITransformer) checkStatus(ctx context.Context, resp *http.Response) (*http.Response, error) {
+	if resp.StatusCode != http.StatusOK {
+		if t.LogBodies {
+			// Read the error body so it gets logged
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		}
+		resp.Body.Close()
+		slog.WarnContext(ctx, "OpenAI API error", "status", resp.StatusCode)
+		return nil, fmt.Errorf("OpenAI API returned status %d: %w", resp.StatusCode, ErrUpstreamStatus)
+	}
+
+	return resp, nil
+}
+
+// Try each model in the chain until one returns a non-empty completion
+func (t *OpenAITransformer) Transform(ctx context.Context, title, description string) (string, error) {
+	openAIRequest := t.buildRequest(ctx, title, description)
+	models := t.models()
+
+	var err error
+	for i, model := range models {
+		openAIRequest.Model = model
+		var choice Choice
+		choice, err = t.complete(ctx, openAIRequest)
+		if err == nil && strings.TrimSpace(choice.Message.Content) == "" {
+			err = fmt.Errorf("OpenAI: %w", ErrNoCompletion)
+		}
+		if err == nil {
+			recordServedModel(ctx, model, choice.FinishReason)
+			return choice.Message.Content, nil
+		}
+
+		// A cancelled request or a full concurrency limit won't fare better on another model
+		if ctx.Err() != nil || errors.Is(err, ErrConcurrencyLimit) {
+			return "", err
+		}
+		if i < len(models)-1 {
+			slog.WarnContext(ctx, "OpenAI model failed, trying next in chain", "model", model, "next", models[i+1], "error", err)
+		}
+	}
+	return "", err
+}
+
+func (t *OpenAITransformer) Translate(ctx context.Context, text, language string) (string, error) {
+	choice, err := t.complete(ctx, OpenAIRequest{
+		Model: t.models()[0],
+		Messages: []Message{
+			{Role: "system", Content: translationPrompt(language)},
+			{Role: "user", Content: text},
+		},
+		MaxTokens:   400,
+		Temperature: translationTemperature,
+	})
+	return choice.Message.Content, err
+}
+
+// Send a chat completion request and return the first choice
+func (t *OpenAITransformer) complete(ctx context.Context, openAIRequest OpenAIRequest) (Choice, error) {
+	resp, err := t.send(ctx, openAIRequest)
+	if err != nil {
+		return Choice{}, err
+	}
+	defer resp.Body.Close()
+
+	var openAIResponse OpenAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&openAIResponse); err != nil {
+		return Choice{}, fmt.Errorf("failed to parse OpenAI response: %w: %v", ErrBadCompletion, err)
+	}
+
+	recordUsage(ctx, TokenUsage{
+		Model:            openAIRequest.Model,
+		PromptTokens:     openAIResponse.Usage.PromptTokens,
+		CompletionTokens: openAIResponse.Usage.CompletionTokens,
+	})
+
+	if len(openAIResponse.Choices) == 0 {
+		return Choice{}, fmt.Errorf("OpenAI: %w", ErrNoCompletion)
+	}
+
+	return openAIResponse.Choices[0], nil
+}
+
+// Stream the completion, calling onToken for each content delta
+func (t *OpenAITransformer) TransformStream(ctx context.Context, title, description string, onToken func(token string) error) error {
+	openAIRequest := t.buildRequest(ctx, title, description)
+	openAIRequest.Stream = true
+
+	resp, err := t.send(ctx, openAIRequest)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			return nil
+		}
+
+		var chunk OpenAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse OpenAI stream chunk: %w: %v", ErrBadCompletion, err)
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			if err := onToken(choice.Delta.Content); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read OpenAI stream: %w", err)
+	}
+	return nil
+}
+
+// Anthropic Messages API request/response structures
+type AnthropicRequest struct {
+	Model       string    `json:"model"`
+	System      string    `json:"system"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature float64   `json:"temperature"`
+}
+
+type AnthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// Transformer backed by the Anthropic Messages API
+type AnthropicTransformer struct {
+	APIKey    string
+	Client    *http.Client
+	UserAgent string
+}
+
+// Messages API request for a piece of news
+func (t *AnthropicTransformer) buildRequest(ctx context.Context, title, description string) AnthropicRequest {
+	prompt, temperature := promptFor(ctx)
+	return AnthropicRequest{
+		Model:  "claude-3-5-haiku-latest",
+		System: prompt,
+		Messages: []Message{
+			{Role: "user", Content: transformPrompt(title, description)},
+		},
+		MaxTokens:   200,
+		Temperature: temperature,
+	}
+}
+
+func (t *AnthropicTransformer) DryRun(ctx context.Context, title, description string) (DryRunResult, error) {
+	anthropicRequest := t.buildRequest(ctx, title, description)
+	return DryRunResult{
+		Provider:              "anthropic",
+		Request:               anthropicRequest,
+		EstimatedPromptTokens: estimatePromptTokens(anthropicRequest.System, anthropicRequest.Messages),
+	}, nil
+}
+
+func (t *AnthropicTransformer) Transform(ctx context.Context, title, description string) (string, error) {
+	anthropicRequest := t.buildRequest(ctx, title, description)
+	content, err := t.complete(ctx, anthropicRequest)
+	if err == nil {
+		recordServedModel(ctx, anthropicRequest.Model, "")
+	}
+	return content, err
+}
+
+func (t *AnthropicTransformer) Translate(ctx context.Context, text, language string) (string, error) {
+	return t.complete(ctx, AnthropicRequest{
+		Model:  "claude-3-5-haiku-latest",
+		System: translationPrompt(language),
+		Messages: []Message{
+			{Role: "user", Content: text},
+		},
+		MaxTokens:   400,
+		Temperature: translationTemperature,
+	})
+}
+
+// Send a Messages API request and return the first text block
+func (t *AnthropicTransformer) complete(ctx context.Context, anthropicRequest AnthropicRequest) (string, error) {
+	jsonData, err := json.Marshal(anthropicRequest)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewReader(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("x-api-key", t.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	req.Header.Set("Content-Type", "application/json")
+	setUserAgent(req, t.UserAgent)
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		err = fmt.Errorf("failed to make request to Anthropic: %w", err)
+		llmHealth.Record(err)
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		slog.WarnContext(ctx, "Anthropic API error", "status", resp.StatusCode)
+		err := fmt.Errorf("Anthropic API returned status %d: %w", resp.StatusCode, ErrUpstreamStatus)
+		llmHealth.Record(err)
+		return "", err
+	}
+	llmHealth.Record(nil)
+
+	var anthropicResponse AnthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&anthropicResponse); err != nil {
+		return "", fmt.Errorf("failed to parse Anthropic response: %w: %v", ErrBadCompletion, err)
+	}
+
+	recordUsage(ctx, TokenUsage{
+		Model:            anthropicRequest.Model,
+		PromptTokens:     anthropicResponse.Usage.InputTokens,
+		CompletionTokens: anthropicResponse.Usage.OutputTokens,
+	})
+
+	for _, block := range anthropicResponse.Content {
+		if block.Type == "text" && block.Text != "" {
+			return block.Text, nil
+		}
+	}
+
+	return "", fmt.Errorf("Anthropic: %w", ErrNoCompletion)
+}
@@ -0,0 +1,570 @@
+package ministry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewTransformerPicksProvider(t *testing.T) {
+	if _, ok := newTransformer(&Config{LLMProvider: "openai"}).(*OpenAITransformer); !ok {
+		t.Error("LLM_PROVIDER=openai didn't build an OpenAITransformer")
+	}
+	if _, ok := newTransformer(&Config{LLMProvider: "anthropic"}).(*AnthropicTransformer); !ok {
+		t.Error("LLM_PROVIDER=anthropic didn't build an AnthropicTransformer")
+	}
+}
+
+func TestLoadConfigRequiresProviderKey(t *testing.T) {
+	t.Setenv("NEWS_API_KEY", "test-news-key")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+
+	for provider, want := range map[string]string{
+		"openai":    "OPENAI_API_KEY",
+		"anthropic": "ANTHROPIC_API_KEY",
+		"llama":     "LLM_PROVIDER must be one of",
+	} {
+		t.Setenv("LLM_PROVIDER", provider)
+		if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LLM_PROVIDER=%s: error = %v, want it to mention %s", provider, err, want)
+		}
+	}
+}
+
+func TestAnthropicTransform(t *testing.T) {
+	var got AnthropicRequest
+	var header http.Header
+	upstream := http.NewServeMux()
+	upstream.HandleFunc("api.anthropic.com/v1/messages", func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content": [{"type": "text", "text": "Victory Mansions rejoice"}], "usage": {"input_tokens": 12, "output_tokens": 5}}`))
+	})
+	setupTest(t, upstream, "LLM_PROVIDER", "anthropic", "ANTHROPIC_API_KEY", "test-anthropic-key")
+
+	rec := postTransform(`{"title": "Lift broken again"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	var response TransformResponse
+	decodeJSON(t, rec, &response)
+	if response.TransformedContent != "Victory Mansions rejoice" || response.Source != "anthropic" {
+		t.Errorf("response = %+v", response)
+	}
+	if response.TokensUsed != 17 {
+		t.Errorf("tokensUsed = %d, want 17", response.TokensUsed)
+	}
+
+	if header.Get("x-api-key") != "test-anthropic-key" || header.Get("anthropic-version") == "" {
+		t.Errorf("request headers = %v", header)
+	}
+	if got.System == "" || len(got.Messages) != 1 || !strings.Contains(got.Messages[0].Content, "Lift broken again") {
+		t.Errorf("request = %+v", got)
+	}
+}
+
+func TestAnthropicTransformWithoutText(t *testing.T) {
+	upstream := http.NewServeMux()
+	upstream.HandleFunc("api.anthropic.com/v1/messages", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"content": []}`))
+	})
+	setupTest(t, upstream, "LLM_PROVIDER", "anthropic", "ANTHROPIC_API_KEY", "test-anthropic-key")
+
+	rec := postTransform(`{"title": "Lift broken again"}`)
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", rec.Code)
+	}
+}
+
+func TestOpenAIBaseURLOverride(t *testing.T) {
+	openAI := newFakeOpenAI("Proxied propaganda")
+	upstream := http.NewServeMux()
+	upstream.Handle("proxy.example.com/openai/chat/completions", openAI)
+	cfg := setupTest(t, upstream, "OPENAI_BASE_URL", "https://proxy.example.com/openai/")
+
+	if cfg.OpenAIBaseURL != "https://proxy.example.com/openai" {
+		t.Errorf("OpenAIBaseURL = %q, want the trailing slash trimmed", cfg.OpenAIBaseURL)
+	}
+
+	rec := postTransform(`{"title": "Lift broken again"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if openAI.Calls() != 1 {
+		t.Errorf("proxy got %d calls, want 1", openAI.Calls())
+	}
+}
+
+func TestLoadConfigRejectsBadOpenAIBaseURL(t *testing.T) {
+	t.Setenv("NEWS_API_KEY", "test-news-key")
+	t.Setenv("OPENAI_API_KEY", "test-openai-key")
+
+	for _, raw := range []string{"proxy.example.com", "ftp://proxy.example.com", "https://"} {
+		t.Setenv("OPENAI_BASE_URL", raw)
+		if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "OPENAI_BASE_URL") {
+			t.Errorf("OPENAI_BASE_URL=%s: error = %v", raw, err)
+		}
+	}
+}
+
+func TestTransformIntensityLevels(t *testing.T) {
+	openAI := newFakeOpenAI("Lift repaired ahead of schedule")
+	setupTest(t, upstreams(openAI, nil))
+
+	prompts := map[string]bool{}
+	temperatures := map[float64]bool{}
+	for _, intensity := range []string{"low", "medium", "high"} {
+		rec := postTransform(`{"title": "Lift broken again", "intensity": "` + intensity + `"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("intensity %s: status = %d, body %q", intensity, rec.Code, rec.Body.String())
+		}
+		request := openAI.Requests()[openAI.Calls()-1]
+		prompts[request.Messages[0].Content] = true
+		temperatures[request.Temperature] = true
+	}
+	if len(prompts) != 3 || len(temperatures) != 3 {
+		t.Errorf("got %d system prompts and %d temperatures for 3 intensities, want each distinct", len(prompts), len(temperatures))
+	}
+
+	// No intensity means high
+	postTransform(`{"title": "Rations cut", "intensity": "HIGH"}`)
+	postTransform(`{"title": "Rations cut again"}`)
+	requests := openAI.Requests()
+	explicit, implicit := requests[len(requests)-2], requests[len(requests)-1]
+	if explicit.Messages[0].Content != implicit.Messages[0].Content || explicit.Temperature != implicit.Temperature {
+		t.Error("a request without intensity didn't use the high level")
+	}
+}
+
+func TestTransformRejectsUnknownIntensity(t *testing.T) {
+	openAI := newFakeOpenAI("unused")
+	setupTest(t, upstreams(openAI, nil))
+
+	rec := postTransform(`{"title": "Lift broken again", "intensity": "extreme"}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "low") {
+		t.Errorf("status = %d, body %q; want 400 listing the levels", rec.Code, rec.Body.String())
+	}
+	if openAI.Calls() != 0 {
+		t.Error("unknown intensity reached the model")
+	}
+}
+
+func TestTransformErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{fmt.Errorf("OpenAI: %w", ErrNoCompletion), http.StatusBadGateway},
+		{fmt.Errorf("parse: %w", ErrBadCompletion), http.StatusBadGateway},
+		{fmt.Errorf("status 500: %w", ErrUpstreamStatus), http.StatusBadGateway},
+		{ErrCircuitOpen, http.StatusServiceUnavailable},
+		{&RateLimitError{}, http.StatusTooManyRequests},
+		{errors.New("something else"), http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		if got, _ := transformErrorStatus(test.err); got != test.want {
+			t.Errorf("transformErrorStatus(%v) = %d, want %d", test.err, got, test.want)
+		}
+	}
+}
+
+func TestTransformUpstreamFailureStatuses(t *testing.T) {
+	replies := map[string]func(w http.ResponseWriter, req OpenAIRequest){
+		"no choices": func(w http.ResponseWriter, req OpenAIRequest) {
+			w.Write([]byte(`{"choices": []}`))
+		},
+		"unreadable body": func(w http.ResponseWriter, req OpenAIRequest) {
+			w.Write([]byte(`not json`))
+		},
+		"error status": func(w http.ResponseWriter, req OpenAIRequest) {
+			http.Error(w, "bad request", http.StatusBadRequest)
+		},
+	}
+	for name, reply := range replies {
+		t.Run(name, func(t *testing.T) {
+			openAI := newFakeOpenAI()
+			openAI.Reply = reply
+			setupTest(t, upstreams(openAI, nil))
+
+			rec := postTransform(`{"title": "Lift broken again"}`)
+			if rec.Code != http.StatusBadGateway {
+				t.Errorf("status = %d, want 502", rec.Code)
+			}
+		})
+	}
+}
+
+func TestEstimatePromptTokens(t *testing.T) {
+	messages := []Message{{Content: "abcdefgh"}, {Content: "abcd"}}
+	if got := estimatePromptTokens("", messages); got != 3+8 {
+		t.Errorf("estimatePromptTokens = %d, want 11", got)
+	}
+	if got := estimatePromptTokens("abcd", nil); got != 1+4 {
+		t.Errorf("estimatePromptTokens with only a system prompt = %d, want 5", got)
+	}
+}
+
+func TestTransformDryRun(t *testing.T) {
+	openAI := newFakeOpenAI("unused")
+	setupTest(t, upstreams(openAI, nil))
+
+	postTransform(`{"title": "Lift broken again", "intensity": "low"}`)
+	rec := do(newRequest(http.MethodPost, "/api/transform?dryRun=true", `{"title": "Rations cut", "intensity": "low"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if openAI.Calls() != 1 {
+		t.Errorf("model got %d calls, want only the real transform's", openAI.Calls())
+	}
+
+	var result struct {
+		DryRun                bool          `json:"dryRun"`
+		Provider              string        `json:"provider"`
+		Request               OpenAIRequest `json:"request"`
+		EstimatedPromptTokens int           `json:"estimatedPromptTokens"`
+	}
+	decodeJSON(t, rec, &result)
+	if !result.DryRun || result.Provider != "openai" || result.EstimatedPromptTokens != estimatePromptTokens("", result.Request.Messages) {
+		t.Errorf("result = %+v", result)
+	}
+
+	// The dry run shows what the real transform sent, apart from the input
+	sent := openAI.Requests()[0]
+	built := result.Request
+	if built.Model != sent.Model || built.Temperature != sent.Temperature || built.MaxTokens != sent.MaxTokens || built.Messages[0] != sent.Messages[0] {
+		t.Errorf("dry run request = %+v, want it to match the sent %+v", built, sent)
+	}
+	if !strings.Contains(built.Messages[1].Content, "Rations cut") {
+		t.Errorf("user message = %q", built.Messages[1].Content)
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		text      string
+		max       int
+		want      string
+		truncated bool
+	}{
+		{"War is peace", 20, "War is peace", false},
+		{"War is peace", 12, "War is peace", false},
+		{"War is peace", 0, "War is peace", false},
+		{"War is peace, freedom is slavery", 16, "War is peace…", true},
+		{"War is peace", 7, "War is…", true},
+		{"War is peace, freedom", 13, "War is peace…", true},
+		{"Doubleplusungood", 8, "Doublep…", true},
+		{"War is peace", 1, "…", true},
+	}
+	for _, test := range tests {
+		got, truncated := truncateText(test.text, test.max)
+		if got != test.want || truncated != test.truncated {
+			t.Errorf("truncateText(%q, %d) = %q, %v; want %q, %v", test.text, test.max, got, truncated, test.want, test.truncated)
+		}
+	}
+}
+
+func TestTransformOutputLimit(t *testing.T) {
+	openAI := newFakeOpenAI("War is peace, freedom is slavery, ignorance is strength", "War is peace")
+	setupTest(t, upstreams(openAI, nil), "TRANSFORM_MAX_CHARS", "16")
+
+	var response TransformResponse
+	decodeJSON(t, postTransform(`{"title": "Lift broken again"}`), &response)
+	if response.TransformedContent != "War is peace…" || !response.Truncated {
+		t.Errorf("over the limit: %q, truncated %v", response.TransformedContent, response.Truncated)
+	}
+
+	response = TransformResponse{}
+	decodeJSON(t, postTransform(`{"title": "Rations cut"}`), &response)
+	if response.TransformedContent != "War is peace" || response.Truncated {
+		t.Errorf("under the limit: %q, truncated %v", response.TransformedContent, response.Truncated)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(1984, time.April, 4, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"7", 7 * time.Second, true},
+		{" 0 ", 0, true},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"", 0, false},
+		{"-3", 0, false},
+		{"soon", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := parseRetryAfter(test.value, now)
+		if got != test.want || ok != test.ok {
+			t.Errorf("parseRetryAfter(%q) = %s, %v; want %s, %v", test.value, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+// Fake OpenAI answering 429 with each Retry-After in turn, then completing
+func rateLimitedOpenAI(retryAfters ...string) *fakeOpenAI {
+	openAI := newFakeOpenAI()
+	var mu sync.Mutex
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(retryAfters) == 0 {
+			writeCompletion(w, req.Model, "Rations are plentiful", "stop")
+			return
+		}
+		w.Header().Set("Retry-After", retryAfters[0])
+		retryAfters = retryAfters[1:]
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}
+	return openAI
+}
+
+func TestTransformRetriesAfterRateLimit(t *testing.T) {
+	past := time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)
+	openAI := rateLimitedOpenAI("0", past)
+	setupTest(t, upstreams(openAI, nil))
+
+	rec := postTransform(`{"title": "Lift broken again"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if openAI.Calls() != 3 {
+		t.Errorf("model got %d calls, want 2 rate limited and 1 completed", openAI.Calls())
+	}
+}
+
+func TestTransformRateLimitBudgetExhausted(t *testing.T) {
+	t.Run("budget", func(t *testing.T) {
+		openAI := rateLimitedOpenAI("30")
+		setupTest(t, upstreams(openAI, nil), "OPENAI_RETRY_BUDGET", "1s")
+
+		rec := postTransform(`{"title": "Lift broken again"}`)
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "30" {
+			t.Errorf("status = %d, Retry-After %q; want 429 passing on 30", rec.Code, rec.Header().Get("Retry-After"))
+		}
+		if openAI.Calls() != 1 {
+			t.Errorf("model got %d calls, want no retry past the budget", openAI.Calls())
+		}
+	})
+
+	t.Run("attempts", func(t *testing.T) {
+		openAI := rateLimitedOpenAI("0", "0", "0", "0", "0")
+		setupTest(t, upstreams(openAI, nil))
+
+		rec := postTransform(`{"title": "Lift broken again"}`)
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "0" {
+			t.Errorf("status = %d, Retry-After %q; want 429", rec.Code, rec.Header().Get("Retry-After"))
+		}
+		if openAI.Calls() != maxRateLimitRetries+1 {
+			t.Errorf("model got %d calls, want %d", openAI.Calls(), maxRateLimitRetries+1)
+		}
+	})
+}
+
+func TestOpenAIOrgAndProjectHeaders(t *testing.T) {
+	t.Run("configured", func(t *testing.T) {
+		openAI := streamingOpenAI("Rations", " are", " plentiful")
+		setupTest(t, upstreams(openAI, nil), "OPENAI_ORG_ID", " org-minitrue ", "OPENAI_PROJECT_ID", "proj-records")
+
+		postTransform(`{"title": "Lift broken again"}`)
+		do(newRequest(http.MethodGet, "/api/transform/stream?title=Rations+cut", ""))
+
+		headers := openAI.Headers()
+		if len(headers) != 2 {
+			t.Fatalf("model got %d calls, want 2", len(headers))
+		}
+		for _, header := range headers {
+			if header.Get("OpenAI-Organization") != "org-minitrue" || header.Get("OpenAI-Project") != "proj-records" {
+				t.Errorf("headers = %v", header)
+			}
+		}
+	})
+
+	t.Run("with a lower intensity", func(t *testing.T) {
+		openAI := newFakeOpenAI("Rations are plentiful")
+		setupTest(t, upstreams(openAI, nil))
+
+		postTransform(`{"title": "Lift broken again", "seed": 42, "intensity": "low"}`)
+		if request := openAI.Requests()[0]; request.Seed == nil || request.Temperature != 0.5 {
+			t.Errorf("seed %v, temperature %v; want the low intensity's temperature kept", request.Seed, request.Temperature)
+		}
+	})
+
+	t.Run("unset", func(t *testing.T) {
+		openAI := newFakeOpenAI("Rations are plentiful")
+		setupTest(t, upstreams(openAI, nil), "OPENAI_ORG_ID", "", "OPENAI_PROJECT_ID", "")
+
+		postTransform(`{"title": "Lift broken again"}`)
+		header := openAI.Headers()[0]
+		if _, ok := header["Openai-Organization"]; ok {
+			t.Errorf("OpenAI-Organization sent without OPENAI_ORG_ID: %v", header)
+		}
+		if _, ok := header["Openai-Project"]; ok {
+			t.Errorf("OpenAI-Project sent without OPENAI_PROJECT_ID: %v", header)
+		}
+	})
+}
+
+// Fake OpenAI failing for the given models and answering the rest with their name
+func modelChainOpenAI(failing map[string]string) *fakeOpenAI {
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		switch failing[req.Model] {
+		case "error":
+			http.Error(w, "model overloaded", http.StatusInternalServerError)
+		case "empty":
+			writeCompletion(w, req.Model, "  ", "stop")
+		default:
+			writeCompletion(w, req.Model, "Served by "+req.Model, "stop")
+		}
+	}
+	return openAI
+}
+
+func TestOpenAIModelChain(t *testing.T) {
+	tests := map[string]struct {
+		failing map[string]string
+		want    string
+	}{
+		"first errors":           {map[string]string{"gpt-4o-mini": "error"}, "gpt-4o"},
+		"first comes back empty": {map[string]string{"gpt-4o-mini": "empty"}, "gpt-4o"},
+		"first succeeds":         {nil, "gpt-4o-mini"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			openAI := modelChainOpenAI(test.failing)
+			setupTest(t, upstreams(openAI, nil), "OPENAI_MODEL_CHAIN", "gpt-4o-mini, gpt-4o")
+
+			var response TransformResponse
+			decodeJSON(t, postTransform(`{"title": "Lift broken again"}`), &response)
+			if response.Model != test.want || response.TransformedContent != "Served by "+test.want {
+				t.Errorf("model %q, content %q; want %s", response.Model, response.TransformedContent, test.want)
+			}
+			if calls := openAI.Calls(); calls != len(test.failing)+1 {
+				t.Errorf("model got %d calls, want %d", calls, len(test.failing)+1)
+			}
+		})
+	}
+}
+
+func TestOpenAIModelChainExhausted(t *testing.T) {
+	openAI := modelChainOpenAI(map[string]string{"gpt-4o-mini": "error", "gpt-4o": "error"})
+	setupTest(t, upstreams(openAI, nil), "OPENAI_MODEL_CHAIN", "gpt-4o-mini,gpt-4o")
+
+	if rec := postTransform(`{"title": "Lift broken again"}`); rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", rec.Code)
+	}
+	var models []string
+	for _, request := range openAI.Requests() {
+		models = append(models, request.Model)
+	}
+	if strings.Join(models, ",") != "gpt-4o-mini,gpt-4o" {
+		t.Errorf("models tried = %q, want each in order", models)
+	}
+}
+
+func TestOpenAIDefaultModel(t *testing.T) {
+	openAI := modelChainOpenAI(nil)
+	setupTest(t, upstreams(openAI, nil))
+
+	var response TransformResponse
+	decodeJSON(t, postTransform(`{"title": "Lift broken again"}`), &response)
+	if response.Model != defaultOpenAIModel || openAI.Requests()[0].Model != defaultOpenAIModel {
+		t.Errorf("model = %q, want %s", response.Model, defaultOpenAIModel)
+	}
+}
+
+func TestTransformFinishReason(t *testing.T) {
+	openAI := newFakeOpenAI()
+	openAI.Reply = func(w http.ResponseWriter, req OpenAIRequest) {
+		writeCompletion(w, req.Model, "Rations are plentiful and", "length")
+	}
+	setupTest(t, upstreams(openAI, nil))
+
+	var response TransformResponse
+	decodeJSON(t, postTransform(`{"title": "Lift broken again"}`), &response)
+	if response.FinishReason != "length" {
+		t.Errorf("finishReason = %q, want length", response.FinishReason)
+	}
+
+	// A cache hit reports the finish reason of the call that filled it
+	decodeJSON(t, postTransform(`{"title": "Lift broken again"}`), &response)
+	if openAI.Calls() != 1 || response.FinishReason != "length" {
+		t.Errorf("%d calls, cached finishReason = %q; want 1 and length", openAI.Calls(), response.FinishReason)
+	}
+}
+
+func TestTransformSeed(t *testing.T) {
+	t.Run("from the request", func(t *testing.T) {
+		openAI := newFakeOpenAI("Rations are plentiful")
+		setupTest(t, upstreams(openAI, nil), "TRANSFORM_SEED", "7")
+
+		postTransform(`{"title": "Lift broken again", "seed": 42}`)
+		request := openAI.Requests()[0]
+		if request.Seed == nil || *request.Seed != 42 || request.Temperature != 0.9 {
+			t.Errorf("seed %v, temperature %v; want the request's seed at the intensity's temperature", request.Seed, request.Temperature)
+		}
+	})
+
+	t.Run("from TRANSFORM_SEED", func(t *testing.T) {
+		openAI := newFakeOpenAI("Rations are plentiful")
+		setupTest(t, upstreams(openAI, nil), "TRANSFORM_SEED", "7")
+
+		postTransform(`{"title": "Lift broken again"}`)
+		request := openAI.Requests()[0]
+		if request.Seed == nil || *request.Seed != 7 || request.Temperature != 0.9 {
+			t.Errorf("seed %v, temperature %v; want TRANSFORM_SEED at the intensity's temperature", request.Seed, request.Temperature)
+		}
+	})
+
+	t.Run("with a lower intensity", func(t *testing.T) {
+		openAI := newFakeOpenAI("Rations are plentiful")
+		setupTest(t, upstreams(openAI, nil))
+
+		postTransform(`{"title": "Lift broken again", "seed": 42, "intensity": "low"}`)
+		if request := openAI.Requests()[0]; request.Seed == nil || request.Temperature != 0.5 {
+			t.Errorf("seed %v, temperature %v; want the low intensity's temperature kept", request.Seed, request.Temperature)
+		}
+	})
+
+	t.Run("unset", func(t *testing.T) {
+		openAI := newFakeOpenAI("Rations are plentiful")
+		setupTest(t, upstreams(openAI, nil))
+
+		postTransform(`{"title": "Lift broken again"}`)
+		if request := openAI.Requests()[0]; request.Seed != nil || request.Temperature == 0 {
+			t.Errorf("seed %v, temperature %v; want no seed and the intensity's temperature", request.Seed, request.Temperature)
+		}
+	})
+
+	t.Run("different seeds are cached apart", func(t *testing.T) {
+		openAI := newFakeOpenAI("Rations are plentiful")
+		setupTest(t, upstreams(openAI, nil))
+
+		postTransform(`{"title": "Lift broken again", "seed": 1}`)
+		postTransform(`{"title": "Lift broken again", "seed": 1}`)
+		postTransform(`{"title": "Lift broken again", "seed": 2}`)
+		if openAI.Calls() != 2 {
+			t.Errorf("model got %d calls, want one per seed", openAI.Calls())
+		}
+	})
+}
+
+func TestTransformSeedConfig(t *testing.T) {
+	t.Setenv("TRANSFORM_SEED", "lucky")
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "TRANSFORM_SEED") {
+		t.Errorf("loadConfig error = %v, want it to name TRANSFORM_SEED", err)
+	}
+}
@@ -0,0 +1,92 @@
+package ministry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// Longest paragraph POST /api/transform/text accepts, the same as a description
+const maxTransformTextLength = maxTransformDescriptionLength
+
+// Check the text of a free-form transform
+func validateTransformText(text string) error {
+	if text == "" {
+		return fmt.Errorf("Field 'text' is required")
+	}
+	if n := utf8.RuneCountInString(text); n > maxTransformTextLength {
+		return fmt.Errorf("'text' must be at most %d characters, got %d", maxTransformTextLength, n)
+	}
+	return nil
+}
+
+// Transform a free-form paragraph. It goes through the same transformer,
+// cache and output limits as /api/transform, as a description without a title.
+func transformText(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var requestData struct {
+		Text      string `json:"text"`
+		Intensity string `json:"intensity"`
+		Persona   string `json:"persona"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("Request body exceeds %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	text := strings.TrimSpace(requestData.Text)
+	if err := validateTransformText(text); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	options, err := resolveTransformOptions(requestData.Intensity, requestData.Persona)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	options.Seed = currentConfig().TransformSeed
+	options.Language = promptLanguage(r.Header.Get("Accept-Language"))
+	w.Header().Add("Vary", "Accept-Language")
+
+	recorder := &usageRecorder{}
+	ctx := withUsageRecorder(withTransformOptions(r.Context(), options), recorder)
+
+	cacheKey := transformCacheKey(currentConfig().LLMProvider, options, "", text)
+	cached, hit := getCachedTransform(r.Context(), cacheKey)
+	response := TransformResponse{TransformedContent: cached.Content, Source: cached.Source, Model: cached.Model}
+	if !hit {
+		response.Source = currentConfig().LLMProvider
+		response.TransformedContent, err = transformOnce(ctx, cacheKey, "", text)
+		if err != nil && currentConfig().TransformFallback {
+			slog.WarnContext(r.Context(), "text transform failed, using local fallback", "error", err)
+			response.Source = "fallback"
+			response.TransformedContent, err = LocalTransformer{Dictionary: currentConfig().Newspeak}.Transform(r.Context(), "", text)
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "text transform error", "error", err)
+			writeTransformError(w, err)
+			return
+		}
+		response.Model = servedModel(ctx)
+	}
+
+	response.TransformedContent, response.Truncated = truncateText(response.TransformedContent, currentConfig().TransformMaxChars)
+	if response.TransformedContent, err = currentConfig().Profanity.Apply(response.TransformedContent); err != nil {
+		slog.WarnContext(r.Context(), "text transform output rejected by profanity filter")
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	response.TokensUsed, response.EstimatedCostUSD = trackUsage(recorder, currentConfig().ModelPricing)
+	json.NewEncoder(w).Encode(enveloped(r.Context(), response))
+}
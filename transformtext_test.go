@@ -0,0 +1,58 @@
+package ministry
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTransformText(t *testing.T) {
+	openAI := newFakeOpenAI("The lift has never worked better")
+	setupTest(t, upstreams(openAI, nil))
+
+	rec := do(newRequest(http.MethodPost, "/api/transform/text", `{"text": "  The lift in Victory Mansions is broken again.  ", "intensity": "low"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var response TransformResponse
+	decodeJSON(t, rec, &response)
+	if response.TransformedContent != "The lift has never worked better" || response.Source != "openai" {
+		t.Errorf("response = %+v", response)
+	}
+	if user := openAI.Requests()[0].Messages[1].Content; !strings.Contains(user, "The lift in Victory Mansions is broken again.") {
+		t.Errorf("user message = %q, want the trimmed text", user)
+	}
+
+	// Cached like any other transform
+	do(newRequest(http.MethodPost, "/api/transform/text", `{"text": "The lift in Victory Mansions is broken again.", "intensity": "low"}`))
+	if openAI.Calls() != 1 {
+		t.Errorf("model got %d calls, want the repeat served from the cache", openAI.Calls())
+	}
+}
+
+func TestTransformTextValidation(t *testing.T) {
+	openAI := newFakeOpenAI("unused")
+	setupTest(t, upstreams(openAI, nil))
+
+	tooLong := strings.Repeat("a", maxTransformTextLength+1)
+	for body, want := range map[string]string{
+		`not json`:                              "Invalid JSON",
+		`{"text": "   "}`:                       "Field 'text' is required",
+		`{"text": "` + tooLong + `"}`:           fmt.Sprintf("at most %d characters", maxTransformTextLength),
+		`{"text": "lift", "persona": "nobody"}`: "persona",
+	} {
+		rec := do(newRequest(http.MethodPost, "/api/transform/text", body))
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("status = %d, body %q; want 400 mentioning %q", rec.Code, rec.Body.String(), want)
+		}
+	}
+
+	// Exactly at the limit is fine, counted in characters rather than bytes
+	if rec := do(newRequest(http.MethodPost, "/api/transform/text", `{"text": "`+strings.Repeat("é", maxTransformTextLength)+`"}`)); rec.Code != http.StatusOK {
+		t.Errorf("status = %d at the limit, want 200", rec.Code)
+	}
+	if openAI.Calls() != 1 {
+		t.Errorf("model got %d calls, want only the valid text sent", openAI.Calls())
+	}
+}
@@ -0,0 +1,39 @@
+package ministry
+
+import (
+	"context"
+	"fmt"
+)
+
+// Sampling temperature for translations; low to stay close to the source
+const translationTemperature = 0.3
+
+// Languages transforms can be translated into, keyed by ISO 639-1 code
+var translationLanguages = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"es": "Spanish",
+	"fr": "French",
+	"hi": "Hindi",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pl": "Polish",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"sv": "Swedish",
+	"tr": "Turkish",
+	"uk": "Ukrainian",
+	"zh": "Chinese",
+}
+
+// Transformers that can also translate text with the same model
+type Translator interface {
+	Translate(ctx context.Context, text, language string) (string, error)
+}
+
+// System prompt asking the model to translate into language, keeping the tone
+func translationPrompt(language string) string {
+	return fmt.Sprintf("Translate the user's text into %s. Preserve its tone, names and formatting, and reply with the translation only.", language)
+}
@@ -0,0 +1,56 @@
+package ministry
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTransformTranslation(t *testing.T) {
+	openAI := newFakeOpenAI("Lift repaired ahead of schedule", "Ascenseur réparé en avance")
+	setupTest(t, upstreams(openAI, nil))
+
+	rec := postTransform(`{"title": "Lift broken again", "targetLang": "FR"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var response TransformResponse
+	decodeJSON(t, rec, &response)
+	if response.TransformedContent != "Lift repaired ahead of schedule" || response.TranslatedContent != "Ascenseur réparé en avance" || response.TargetLang != "fr" {
+		t.Errorf("response = %+v, want both versions", response)
+	}
+
+	requests := openAI.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("model got %d calls, want transform then translation", len(requests))
+	}
+	translation := requests[1]
+	if translation.Messages[0].Content != translationPrompt("French") || translation.Messages[1].Content != "Lift repaired ahead of schedule" || translation.Temperature != translationTemperature {
+		t.Errorf("translation request = %+v", translation)
+	}
+}
+
+func TestTransformTruncatesTranslation(t *testing.T) {
+	openAI := newFakeOpenAI("Lift repaired", "Ascenseur réparé en avance sur le calendrier")
+	setupTest(t, upstreams(openAI, nil), "TRANSFORM_MAX_CHARS", "20")
+
+	rec := postTransform(`{"title": "Lift broken again", "targetLang": "fr"}`)
+	var response TransformResponse
+	decodeJSON(t, rec, &response)
+	if response.TransformedContent != "Lift repaired" || response.TranslatedContent != "Ascenseur réparé en…" || !response.Truncated {
+		t.Errorf("response = %+v, want the translation trimmed to TRANSFORM_MAX_CHARS", response)
+	}
+}
+
+func TestTransformRejectsUnknownTargetLang(t *testing.T) {
+	openAI := newFakeOpenAI("unused")
+	setupTest(t, upstreams(openAI, nil))
+
+	rec := postTransform(`{"title": "Lift broken again", "targetLang": "newspeak"}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "fr") {
+		t.Errorf("status = %d, body %q; want 400 listing the languages", rec.Code, rec.Body.String())
+	}
+	if openAI.Calls() != 0 {
+		t.Error("unknown language reached the model")
+	}
+}
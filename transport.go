@@ -0,0 +1,32 @@
+package ministry
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// Transport shared by the NewsAPI, GNews and model API clients; nil means
+// http.DefaultTransport. setup builds it from the UPSTREAM_*_TIMEOUT settings.
+var upstreamTransport http.RoundTripper
+
+// Copy of base with separate limits on connecting, the TLS handshake and
+// waiting for response headers. The dial timeout is applied as a deadline
+// around base's own dialer.
+func newUpstreamTransport(base *http.Transport, cfg *Config) *http.Transport {
+	transport := base.Clone()
+	transport.TLSHandshakeTimeout = cfg.UpstreamTLSHandshakeTimeout
+	transport.ResponseHeaderTimeout = cfg.UpstreamResponseHeaderTimeout
+
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	timeout := cfg.UpstreamDialTimeout
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return dial(ctx, network, addr)
+	}
+	return transport
+}
@@ -0,0 +1,40 @@
+package ministry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewUpstreamTransport(t *testing.T) {
+	cfg := setupTest(t, nil, "UPSTREAM_DIAL_TIMEOUT", "3s", "UPSTREAM_TLS_HANDSHAKE_TIMEOUT", "4s", "UPSTREAM_RESPONSE_HEADER_TIMEOUT", "45s")
+
+	var dialDeadline time.Duration
+	base := &http.Transport{DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		deadline, _ := ctx.Deadline()
+		dialDeadline = time.Until(deadline)
+		return nil, errors.New("no network in tests")
+	}}
+
+	transport := newUpstreamTransport(base, cfg)
+	if transport.TLSHandshakeTimeout != 4*time.Second || transport.ResponseHeaderTimeout != 45*time.Second {
+		t.Errorf("TLSHandshakeTimeout = %v, ResponseHeaderTimeout = %v; want 4s and 45s", transport.TLSHandshakeTimeout, transport.ResponseHeaderTimeout)
+	}
+	transport.DialContext(context.Background(), "tcp", "newsapi.org:443")
+	if dialDeadline <= 2*time.Second || dialDeadline > 3*time.Second {
+		t.Errorf("dial deadline %v away, want 3s", dialDeadline)
+	}
+	if base.TLSHandshakeTimeout != 0 {
+		t.Error("base transport was modified")
+	}
+}
+
+func TestUpstreamTimeoutDefaults(t *testing.T) {
+	cfg := setupTest(t, nil)
+	if cfg.UpstreamDialTimeout != 10*time.Second || cfg.UpstreamTLSHandshakeTimeout != 10*time.Second || cfg.UpstreamResponseHeaderTimeout != 2*time.Minute {
+		t.Errorf("timeouts = %v/%v/%v, want 10s/10s/2m", cfg.UpstreamDialTimeout, cfg.UpstreamTLSHandshakeTimeout, cfg.UpstreamResponseHeaderTimeout)
+	}
+}
@@ -0,0 +1,211 @@
+package ministry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Tokens consumed by a single LLM call
+type TokenUsage struct {
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Price in USD per 1K tokens for a model
+type ModelPrice struct {
+	PromptPer1K     float64 `json:"prompt"`
+	CompletionPer1K float64 `json:"completion"`
+}
+
+// Built-in price table, overridable via MODEL_PRICING
+func defaultModelPricing() map[string]ModelPrice {
+	return map[string]ModelPrice{
+		"gpt-3.5-turbo":           {PromptPer1K: 0.0005, CompletionPer1K: 0.0015},
+		"gpt-4o-mini":             {PromptPer1K: 0.00015, CompletionPer1K: 0.0006},
+		"claude-3-5-haiku-latest": {PromptPer1K: 0.0008, CompletionPer1K: 0.004},
+	}
+}
+
+// Parse MODEL_PRICING JSON on top of the built-in prices, e.g.
+// {"gpt-3.5-turbo": {"prompt": 0.0005, "completion": 0.0015}}
+func parseModelPricing(raw string) (map[string]ModelPrice, error) {
+	pricing := defaultModelPricing()
+	if raw == "" {
+		return pricing, nil
+	}
+
+	var overrides map[string]ModelPrice
+	if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+		return nil, fmt.Errorf("MODEL_PRICING must be a JSON object: %v", err)
+	}
+
+	for model, price := range overrides {
+		if price.PromptPer1K < 0 || price.CompletionPer1K < 0 {
+			return nil, fmt.Errorf("MODEL_PRICING %s: prices must not be negative", model)
+		}
+		pricing[model] = price
+	}
+	return pricing, nil
+}
+
+// Estimated cost of a call in USD; unknown models cost 0
+func estimateCost(pricing map[string]ModelPrice, usage TokenUsage) float64 {
+	price := pricing[usage.Model]
+	return float64(usage.PromptTokens)/1000*price.PromptPer1K +
+		float64(usage.CompletionTokens)/1000*price.CompletionPer1K
+}
+
+// Collects usage from every LLM call made on behalf of one request
+type usageRecorder struct {
+	mu           sync.Mutex
+	calls        []TokenUsage
+	shared       []TokenUsage // calls made for a transform shared with other requests
+	model        string       // model behind the latest successful transform
+	finishReason string       // why that model stopped, when the provider says
+}
+
+type usageRecorderKey struct{}
+
+func withUsageRecorder(ctx context.Context, recorder *usageRecorder) context.Context {
+	return context.WithValue(ctx, usageRecorderKey{}, recorder)
+}
+
+// Record usage on the request's recorder, if it has one
+func recordUsage(ctx context.Context, usage TokenUsage) {
+	recorder, ok := ctx.Value(usageRecorderKey{}).(*usageRecorder)
+	if !ok {
+		return
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.calls = append(recorder.calls, usage)
+}
+
+// Record usage of a call shared with other requests on the request's
+// recorder, if it has one. It counts towards the request's tokens but not
+// the process totals, which the shared call already added to.
+func recordSharedUsage(ctx context.Context, usage TokenUsage) {
+	recorder, ok := ctx.Value(usageRecorderKey{}).(*usageRecorder)
+	if !ok {
+		return
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.shared = append(recorder.shared, usage)
+}
+
+// Note the model that produced a transform, and why it stopped, on the
+// request's recorder, if it has one
+func recordServedModel(ctx context.Context, model, finishReason string) {
+	recorder, ok := ctx.Value(usageRecorderKey{}).(*usageRecorder)
+	if !ok {
+		return
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.model = model
+	recorder.finishReason = finishReason
+}
+
+// Model recorded by the latest successful transform on ctx, if any
+func servedModel(ctx context.Context) string {
+	recorder, ok := ctx.Value(usageRecorderKey{}).(*usageRecorder)
+	if !ok {
+		return ""
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return recorder.model
+}
+
+// Finish reason recorded by the latest successful transform on ctx, if any
+func servedFinishReason(ctx context.Context) string {
+	recorder, ok := ctx.Value(usageRecorderKey{}).(*usageRecorder)
+	if !ok {
+		return ""
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return recorder.finishReason
+}
+
+// Recorded calls so far
+func (rec *usageRecorder) Calls() []TokenUsage {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]TokenUsage(nil), rec.calls...)
+}
+
+// Running totals across all transforms served by this process
+type UsageTracker struct {
+	mu               sync.Mutex
+	calls            int64
+	promptTokens     int64
+	completionTokens int64
+	estimatedCostUSD float64
+}
+
+type UsageSummary struct {
+	Calls            int64   `json:"calls"`
+	PromptTokens     int64   `json:"promptTokens"`
+	CompletionTokens int64   `json:"completionTokens"`
+	TotalTokens      int64   `json:"totalTokens"`
+	EstimatedCostUSD float64 `json:"estimatedCostUSD"`
+}
+
+var usageTracker = &UsageTracker{}
+
+func (t *UsageTracker) Add(usage TokenUsage, cost float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls++
+	t.promptTokens += int64(usage.PromptTokens)
+	t.completionTokens += int64(usage.CompletionTokens)
+	t.estimatedCostUSD += cost
+}
+
+func (t *UsageTracker) Summary() UsageSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return UsageSummary{
+		Calls:            t.calls,
+		PromptTokens:     t.promptTokens,
+		CompletionTokens: t.completionTokens,
+		TotalTokens:      t.promptTokens + t.completionTokens,
+		EstimatedCostUSD: t.estimatedCostUSD,
+	}
+}
+
+// Add a request's recorded calls to the totals; returns tokens used and cost
+func trackUsage(recorder *usageRecorder, pricing map[string]ModelPrice) (int, float64) {
+	tokens := 0
+	cost := 0.0
+	for _, usage := range recorder.Calls() {
+		callCost := estimateCost(pricing, usage)
+		usageTracker.Add(usage, callCost)
+		tokens += usage.PromptTokens + usage.CompletionTokens
+		cost += callCost
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	for _, usage := range recorder.shared {
+		tokens += usage.PromptTokens + usage.CompletionTokens
+		cost += estimateCost(pricing, usage)
+	}
+	return tokens, cost
+}
+
+// Aggregate usage endpoint
+func getUsage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usageTracker.Summary())
+}
@@ -0,0 +1,62 @@
+package ministry
+
+import (
+	"math"
+	"net/http"
+	"testing"
+)
+
+func TestParseModelPricing(t *testing.T) {
+	pricing, err := parseModelPricing(`{"gpt-3.5-turbo": {"prompt": 0.001, "completion": 0.002}, "custom": {"prompt": 0.01, "completion": 0.03}}`)
+	if err != nil {
+		t.Fatalf("parseModelPricing: %v", err)
+	}
+	if got := pricing["gpt-3.5-turbo"]; got != (ModelPrice{PromptPer1K: 0.001, CompletionPer1K: 0.002}) {
+		t.Errorf("gpt-3.5-turbo = %+v, want the override", got)
+	}
+	if _, ok := pricing["gpt-4o-mini"]; !ok {
+		t.Error("built-in prices dropped by an override")
+	}
+	if _, ok := pricing["custom"]; !ok {
+		t.Error("new model missing")
+	}
+
+	for _, raw := range []string{`[1, 2]`, `{"gpt-4o-mini": {"prompt": -1}}`} {
+		if _, err := parseModelPricing(raw); err == nil {
+			t.Errorf("parseModelPricing(%s) succeeded, want an error", raw)
+		}
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	pricing := map[string]ModelPrice{"m": {PromptPer1K: 1, CompletionPer1K: 2}}
+
+	if got := estimateCost(pricing, TokenUsage{Model: "m", PromptTokens: 500, CompletionTokens: 250}); got != 1 {
+		t.Errorf("estimateCost = %v, want 1", got)
+	}
+	if got := estimateCost(pricing, TokenUsage{Model: "unknown", PromptTokens: 500}); got != 0 {
+		t.Errorf("estimateCost for an unknown model = %v, want 0", got)
+	}
+}
+
+func TestTransformReportsUsage(t *testing.T) {
+	setupTest(t, upstreams(newFakeOpenAI("Plenty announces a glorious increase"), nil))
+	before := usageTracker.Summary()
+
+	rec := postTransform(`{"title": "Chocolate ration raised"}`)
+	var response TransformResponse
+	decodeJSON(t, rec, &response)
+
+	// writeCompletion reports 10 prompt and 20 completion tokens
+	wantCost := 10.0/1000*0.0005 + 20.0/1000*0.0015
+	if response.TokensUsed != 30 || math.Abs(response.EstimatedCostUSD-wantCost) > 1e-12 {
+		t.Errorf("tokensUsed = %d, estimatedCostUSD = %v; want 30, %v", response.TokensUsed, response.EstimatedCostUSD, wantCost)
+	}
+
+	rec = do(newRequest(http.MethodGet, "/api/usage", ""))
+	var after UsageSummary
+	decodeJSON(t, rec, &after)
+	if after.Calls-before.Calls != 1 || after.TotalTokens-before.TotalTokens != 30 {
+		t.Errorf("usage went from %+v to %+v, want one more call and 30 more tokens", before, after)
+	}
+}
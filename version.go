@@ -0,0 +1,45 @@
+package ministry
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+)
+
+// Build metadata, injected at build time with e.g.
+// go build -ldflags "-X ministry-of-truth.version=1.2.0 -X ministry-of-truth.commit=$(git rev-parse --short HEAD) -X ministry-of-truth.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/ministry-of-truth
+var (
+	version   = "dev"
+	commit    = "dev"
+	buildTime = "dev"
+)
+
+type VersionResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Report which build is running
+func getVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(VersionResponse{
+		Version:   version,
+		Commit:    commit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+	})
+}
+
+// User-Agent sent on outbound requests unless HTTP_USER_AGENT overrides it
+func defaultUserAgent() string {
+	return "ministry-of-truth/" + version + " (+https://github.com/MitchellLeybaCale/ministry-of-truth)"
+}
+
+// Identify the service on an outbound request; empty keeps Go's default
+func setUserAgent(req *http.Request, userAgent string) {
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+}
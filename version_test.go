@@ -0,0 +1,94 @@
+package ministry
+
+import (
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestVersionEndpoint(t *testing.T) {
+	setupTest(t, nil)
+
+	rec := do(newRequest(http.MethodGet, "/version", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var response VersionResponse
+	decodeJSON(t, rec, &response)
+	want := VersionResponse{Version: "dev", Commit: "dev", BuildTime: "dev", GoVersion: runtime.Version()}
+	if response != want {
+		t.Errorf("response = %+v, want %+v", response, want)
+	}
+}
+
+func TestVersionEndpointReportsInjectedBuild(t *testing.T) {
+	setupTest(t, nil)
+	previous := [3]string{version, commit, buildTime}
+	version, commit, buildTime = "1.2.0", "abc1234", "2024-03-01T12:00:00Z"
+	t.Cleanup(func() { version, commit, buildTime = previous[0], previous[1], previous[2] })
+
+	var response VersionResponse
+	decodeJSON(t, do(newRequest(http.MethodGet, "/version", "")), &response)
+	if response.Version != "1.2.0" || response.Commit != "abc1234" || response.BuildTime != "2024-03-01T12:00:00Z" {
+		t.Errorf("response = %+v", response)
+	}
+}
+
+func TestDefaultUserAgent(t *testing.T) {
+	previous := version
+	version = "1.2.0"
+	t.Cleanup(func() { version = previous })
+
+	if got := defaultUserAgent(); !strings.HasPrefix(got, "ministry-of-truth/1.2.0 ") {
+		t.Errorf("defaultUserAgent() = %q, want the service name and version", got)
+	}
+}
+
+func TestOutboundUserAgent(t *testing.T) {
+	for name, env := range map[string][]string{
+		"default":    nil,
+		"configured": {"HTTP_USER_AGENT", "minitrue-bot/1.0"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			agents := map[string]string{}
+			capture := func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					mu.Lock()
+					agents[r.Host] = r.UserAgent()
+					mu.Unlock()
+					next.ServeHTTP(w, r)
+				})
+			}
+			cfg := setupTest(t, upstreams(capture(newFakeOpenAI("Rations are plentiful")), capture(newFakeNews(Article{Title: "Lift broken again"}))), env...)
+
+			postTransform(`{"title": "Lift broken again"}`)
+			do(newRequest(http.MethodGet, "/api/news/headlines", ""))
+
+			want := defaultUserAgent()
+			if len(env) > 0 {
+				want = env[1]
+			}
+			if cfg.UserAgent != want {
+				t.Errorf("UserAgent = %q, want %q", cfg.UserAgent, want)
+			}
+			for _, host := range []string{"api.openai.com", "newsapi.org"} {
+				if agents[host] != want {
+					t.Errorf("User-Agent sent to %s = %q, want %q", host, agents[host], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfigRejectsUserAgentLineBreaks(t *testing.T) {
+	t.Setenv("NEWS_API_KEY", "test-news-key")
+	t.Setenv("OPENAI_API_KEY", "test-openai-key")
+	t.Setenv("HTTP_USER_AGENT", "minitrue-bot/1.0\r\nX-Injected: yes")
+
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "HTTP_USER_AGENT") {
+		t.Errorf("error = %v", err)
+	}
+}
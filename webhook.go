@@ -0,0 +1,169 @@
+package ministry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Webhook delivery limits
+const (
+	webhookQueueSize   = 100
+	webhookMaxAttempts = 3
+	webhookBackoff     = time.Second
+	webhookTimeout     = 10 * time.Second
+)
+
+// A newly transformed headline; text and content make the payload usable
+// as-is by Slack and Discord incoming webhooks
+type WebhookPayload struct {
+	Title              string    `json:"title"`
+	Description        string    `json:"description,omitempty"`
+	URL                string    `json:"url,omitempty"`
+	TransformedContent string    `json:"transformedContent"`
+	Source             string    `json:"source"`
+	TransformedAt      time.Time `json:"transformedAt"`
+	Text               string    `json:"text"`
+	Content            string    `json:"content"`
+}
+
+func newWebhookPayload(title, description, articleURL, transformed, source string) WebhookPayload {
+	return WebhookPayload{
+		Title:              title,
+		Description:        description,
+		URL:                articleURL,
+		TransformedContent: transformed,
+		Source:             source,
+		TransformedAt:      time.Now().UTC(),
+		Text:               transformed,
+		Content:            transformed,
+	}
+}
+
+// Delivers payloads to a webhook from a bounded queue so slow receivers
+// never block request handlers
+type WebhookSender struct {
+	URL         string
+	UserAgent   string
+	Client      *http.Client
+	MaxAttempts int
+	Backoff     time.Duration
+	queue       chan WebhookPayload
+}
+
+func NewWebhookSender(webhookURL, userAgent string, queueSize int) *WebhookSender {
+	return &WebhookSender{
+		URL:         webhookURL,
+		UserAgent:   userAgent,
+		Client:      &http.Client{Timeout: webhookTimeout},
+		MaxAttempts: webhookMaxAttempts,
+		Backoff:     webhookBackoff,
+		queue:       make(chan WebhookPayload, queueSize),
+	}
+}
+
+// Queue a payload without blocking; false when the queue is full
+func (s *WebhookSender) Enqueue(payload WebhookPayload) bool {
+	select {
+	case s.queue <- payload:
+		return true
+	default:
+		return false
+	}
+}
+
+// Deliver queued payloads until ctx is cancelled
+func (s *WebhookSender) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload := <-s.queue:
+			if err := s.deliver(ctx, payload); err != nil && ctx.Err() == nil {
+				slog.WarnContext(ctx, "webhook delivery failed", "error", err)
+			}
+		}
+	}
+}
+
+// POST a payload, retrying failures with exponential backoff
+func (s *WebhookSender) deliver(ctx context.Context, payload WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %v", err)
+	}
+
+	backoff := s.Backoff
+	for attempt := 1; ; attempt++ {
+		err = s.post(ctx, body)
+		if err == nil || attempt >= s.MaxAttempts {
+			return err
+		}
+
+		slog.DebugContext(ctx, "retrying webhook delivery", "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (s *WebhookSender) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setUserAgent(req, s.UserAgent)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		// Name only the host: the rest of WEBHOOK_URL may hold a secret token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to send webhook to %s: %v", req.URL.Host, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Webhook sender, or nil when WEBHOOK_URL is unset
+var webhooks *WebhookSender
+
+// Queue a newly transformed headline for the webhook, if one is configured
+func notifyWebhook(ctx context.Context, payload WebhookPayload) {
+	if webhooks == nil {
+		return
+	}
+	if !webhooks.Enqueue(payload) {
+		slog.WarnContext(ctx, "webhook queue full, dropping headline", "title", payload.Title)
+	}
+}
+
+// Validate WEBHOOK_URL as an absolute http(s) URL
+func parseWebhookURL(raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		// The value isn't quoted back: webhook URLs often carry a secret token
+		return "", fmt.Errorf("WEBHOOK_URL must be an absolute http or https URL")
+	}
+	return raw, nil
+}
@@ -0,0 +1,134 @@
+package ministry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseWebhookURL(t *testing.T) {
+	for _, raw := range []string{"", "https://hooks.example.com/abc", "http://localhost:9000/hook"} {
+		if _, err := parseWebhookURL(raw); err != nil {
+			t.Errorf("parseWebhookURL(%q) = %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"hooks.example.com", "ftp://hooks.example.com", "https://"} {
+		if _, err := parseWebhookURL(raw); err == nil {
+			t.Errorf("parseWebhookURL(%q) succeeded, want an error", raw)
+		}
+	}
+}
+
+func TestWebhookSenderRetriesDelivery(t *testing.T) {
+	var mu sync.Mutex
+	var attempts int
+	var received WebhookPayload
+	var header http.Header
+	delivered := make(chan struct{})
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		header = r.Header.Clone()
+		json.NewDecoder(r.Body).Decode(&received)
+		close(delivered)
+	}))
+	defer receiver.Close()
+
+	sender := NewWebhookSender(receiver.URL, "ministry-test", 1)
+	sender.Backoff = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sender.Run(ctx)
+
+	if !sender.Enqueue(newWebhookPayload("Lift broken again", "Third time", "https://example.com/lift", "Lift repaired", "openai")) {
+		t.Fatal("Enqueue on an empty queue failed")
+	}
+	select {
+	case <-delivered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("payload wasn't delivered")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want a retry after the 503", attempts)
+	}
+	if received.Title != "Lift broken again" || received.URL != "https://example.com/lift" || received.TransformedContent != "Lift repaired" || received.Source != "openai" {
+		t.Errorf("payload = %+v", received)
+	}
+	// Slack and Discord read text and content
+	if received.Text != "Lift repaired" || received.Content != "Lift repaired" || received.TransformedAt.IsZero() {
+		t.Errorf("payload = %+v", received)
+	}
+	if header.Get("Content-Type") != "application/json" || header.Get("User-Agent") != "ministry-test" {
+		t.Errorf("headers = %v", header)
+	}
+}
+
+func TestWebhookSenderGivesUp(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		http.Error(w, "gone", http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	sender := NewWebhookSender(receiver.URL, "", 1)
+	sender.Backoff = time.Millisecond
+	if err := sender.deliver(context.Background(), WebhookPayload{Title: "Lift broken again"}); err == nil {
+		t.Error("deliver succeeded against a failing receiver")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != webhookMaxAttempts {
+		t.Errorf("attempts = %d, want %d", attempts, webhookMaxAttempts)
+	}
+}
+
+func TestWebhookErrorHidesURL(t *testing.T) {
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	receiver.Close()
+
+	sender := NewWebhookSender(receiver.URL+"/services/secret-hook-token", "", 1)
+	err := sender.post(context.Background(), []byte("{}"))
+	if err == nil || strings.Contains(err.Error(), "secret-hook-token") {
+		t.Errorf("post = %v, want an error without the webhook path", err)
+	}
+}
+
+func TestWebhookQueueDropsWhenFull(t *testing.T) {
+	sender := NewWebhookSender("http://localhost", "", 1)
+	if !sender.Enqueue(WebhookPayload{}) || sender.Enqueue(WebhookPayload{}) {
+		t.Error("want the first payload queued and the second dropped")
+	}
+}
+
+func TestTransformNotifiesWebhook(t *testing.T) {
+	setupTest(t, upstreams(newFakeOpenAI("Lift repaired ahead of schedule"), nil))
+	webhooks = NewWebhookSender("http://localhost", "", 1)
+
+	postTransform(`{"title": "Lift broken again"}`)
+	select {
+	case payload := <-webhooks.queue:
+		if payload.Title != "Lift broken again" || payload.TransformedContent != "Lift repaired ahead of schedule" {
+			t.Errorf("payload = %+v", payload)
+		}
+	default:
+		t.Error("transform didn't queue a webhook payload")
+	}
+}